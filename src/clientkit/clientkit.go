@@ -1,6 +1,7 @@
 package clientkit
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,7 +14,10 @@ import (
 	"github.com/quasilyte/roboden-game/session"
 )
 
-func GetLeaderboard(state *session.State, season int, gameMode string) (*serverapi.LeaderboardResp, error) {
+// GetLeaderboard fetches the player-centered board.
+// If cached is not nil, its ETag is used to avoid downloading the same data again;
+// cached is returned as is if the board was not changed.
+func GetLeaderboard(state *session.State, season int, gameMode string, cached *serverapi.LeaderboardResp) (*serverapi.LeaderboardResp, error) {
 	var u url.URL
 	u.Host = state.ServerHost
 	u.Scheme = state.ServerProtocol
@@ -24,11 +28,90 @@ func GetLeaderboard(state *session.State, season int, gameMode string) (*servera
 	q.Add("name", state.Persistent.PlayerName)
 	u.RawQuery = q.Encode()
 
-	data, err := httpfetch.GetBytes(u.String())
+	etag := ""
+	if cached != nil && cached.NumSeasons != 0 {
+		etag = cached.ETag
+	}
+	httpResp, err := httpfetch.GetIfNoneMatch(u.String(), etag)
 	if err != nil {
 		return nil, err
 	}
+	switch httpResp.Code {
+	case http.StatusOK:
+		// OK
+	case http.StatusNotModified:
+		if etag != "" {
+			return cached, nil
+		}
+		fallthrough
+	default:
+		return nil, fmt.Errorf("unexpected get-player-board status code %d", httpResp.Code)
+	}
 	var resp serverapi.LeaderboardResp
+	if err := json.Unmarshal(httpResp.Data, &resp); err != nil {
+		return nil, err
+	}
+	resp.ETag = httpResp.ETag
+	return &resp, nil
+}
+
+func GetPlayerProfile(state *session.State, playerName string) (*serverapi.PlayerProfileResp, error) {
+	var u url.URL
+	u.Host = state.ServerHost
+	u.Scheme = state.ServerProtocol
+	u.Path = path.Join(state.ServerPath, "get-player-profile")
+	q := u.Query()
+	q.Add("name", playerName)
+	u.RawQuery = q.Encode()
+
+	data, err := httpfetch.GetBytes(u.String())
+	if err != nil {
+		return nil, err
+	}
+	var resp serverapi.PlayerProfileResp
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func GetReplay(state *session.State, season int, gameMode, playerName string) (*serverapi.GameReplay, error) {
+	var u url.URL
+	u.Host = state.ServerHost
+	u.Scheme = state.ServerProtocol
+	u.Path = path.Join(state.ServerPath, "get-replay")
+	q := u.Query()
+	q.Add("season", strconv.Itoa(season))
+	q.Add("mode", gameMode)
+	q.Add("name", playerName)
+	u.RawQuery = q.Encode()
+
+	// The older servers ignore the Accept header and send JSON.
+	data, err := httpfetch.GetBytesAccept(u.String(), serverapi.ReplayBinaryContentType)
+	if err != nil {
+		return nil, err
+	}
+	var replay serverapi.GameReplay
+	if err := serverapi.UnmarshalReplay(data, &replay); err != nil {
+		return nil, err
+	}
+	return &replay, nil
+}
+
+func getScoreChallenge(state *session.State, replayChecksum string) (*serverapi.ScoreChallengeResp, error) {
+	var u url.URL
+	u.Host = state.ServerHost
+	u.Scheme = state.ServerProtocol
+	u.Path = path.Join(state.ServerPath, "get-score-challenge")
+	q := u.Query()
+	q.Add("checksum", hex.EncodeToString([]byte(replayChecksum)))
+	u.RawQuery = q.Encode()
+
+	data, err := httpfetch.GetBytes(u.String())
+	if err != nil {
+		return nil, err
+	}
+	var resp serverapi.ScoreChallengeResp
 	if err := json.Unmarshal(data, &resp); err != nil {
 		return nil, err
 	}
@@ -67,6 +150,8 @@ type SendScoreResult struct {
 }
 
 func SendScore(state *session.State, season int, replay serverapi.GameReplay) (SendScoreResult, error) {
+	replay = serverapi.SubmissionReplay(&replay)
+
 	var u url.URL
 	u.Host = state.ServerHost
 	u.Scheme = state.ServerProtocol
@@ -79,12 +164,37 @@ func SendScore(state *session.State, season int, replay serverapi.GameReplay) (S
 
 	var result SendScoreResult
 
-	replayData, err := json.Marshal(replay)
+	// The server requires a proof-of-work stamp for every submission.
+	replayChecksum := serverapi.ReplayChecksum(&replay)
+	challenge, err := getScoreChallenge(state, replayChecksum)
+	if err != nil {
+		result.TryAgain = true
+		return result, err
+	}
+	if challenge.Difficulty > serverapi.MaxHashcashDifficulty {
+		// It would take too long; maybe the server is less busy later.
+		result.TryAgain = true
+		return result, fmt.Errorf("challenge difficulty %d is too high", challenge.Difficulty)
+	}
+	counter := serverapi.SolveHashcash(replayChecksum, challenge.Nonce, challenge.Difficulty)
+	q.Add("pow_nonce", challenge.Nonce)
+	q.Add("pow_counter", strconv.FormatUint(counter, 10))
+	u.RawQuery = q.Encode()
+
+	// The binary replays are much smaller, but the older servers can't decode them.
+	contentType := "application/json"
+	var replayData []byte
+	if challenge.BinaryReplays {
+		contentType = serverapi.ReplayBinaryContentType
+		replayData, err = serverapi.MarshalReplayBinary(&replay)
+	} else {
+		replayData, err = json.Marshal(replay)
+	}
 	if err != nil {
 		return result, err
 	}
 
-	resp, err := httpfetch.PostJSON(u.String(), replayData)
+	resp, err := httpfetch.Post(u.String(), contentType, replayData)
 	if err != nil {
 		// Probably a network issue; or a server is down.
 		// It's worth trying again.
@@ -97,6 +207,11 @@ func SendScore(state *session.State, season int, replay serverapi.GameReplay) (S
 		// Server asks to try this again.
 		result.TryAgain = true
 		return result, nil
+	case http.StatusForbidden:
+		// The challenge has expired (or the server was restarted).
+		// The next attempt will request a new one.
+		result.TryAgain = true
+		return result, nil
 	case http.StatusOK:
 		var responseInfo serverapi.SavePlayerScoreResp
 		if err := json.Unmarshal(resp.Data, &responseInfo); err != nil {
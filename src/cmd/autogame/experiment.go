@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/serverapi"
+	"gopkg.in/yaml.v3"
+)
+
+// experiment describes a simulation matrix.
+//
+// The level config fields are referred to by their ReplayLevelConfig JSON names.
+// Every field is either fixed, swept or randomized:
+//
+//	samples: 50
+//	workers: 8
+//	fixed:
+//	  mode: classic
+//	  drones_power: 1
+//	sweep:
+//	  world_size: [1, 2, 3]
+//	  bot_profile: [0, 3, 4]
+//	random:
+//	  environment: [0, 1, 2, 3]
+//	  creep_difficulty: {min: 2, max: 5}
+//
+// Every sweep values combination is a cell that runs the specified number of samples.
+// The random fields are picked for every sample: either a list element
+// or an integer from the {min, max} range.
+//
+// The fields that are not mentioned keep the default autogame settings.
+// The seed and the colony build (tier2_recipes, core_design and turret_design)
+// are randomized unless they're fixed or swept.
+// Every resulting config is checked against the replay option ranges.
+type experiment struct {
+	Samples int `json:"samples" yaml:"samples"`
+	Workers int `json:"workers" yaml:"workers"`
+
+	// Timeout is a single game simulation timeout in seconds.
+	Timeout int `json:"timeout" yaml:"timeout"`
+
+	Fixed  map[string]any   `json:"fixed" yaml:"fixed"`
+	Sweep  map[string][]any `json:"sweep" yaml:"sweep"`
+	Random map[string]any   `json:"random" yaml:"random"`
+}
+
+// defaultExperiment is the original autogame setup:
+// 1000 classic mode games with random builds and environments.
+func defaultExperiment() *experiment {
+	return &experiment{
+		Samples: 1000,
+		Fixed:   map[string]any{"mode": "classic"},
+		Random:  map[string]any{"environment": []any{0, 1, 2, 3}},
+	}
+}
+
+func loadExperiment(filename string) (*experiment, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var e experiment
+	if filepath.Ext(filename) == ".json" {
+		err = json.Unmarshal(data, &e)
+	} else {
+		err = yaml.Unmarshal(data, &e)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %w", filename, err)
+	}
+	if e.Samples <= 0 {
+		return nil, fmt.Errorf("samples should be positive")
+	}
+	if e.Workers < 0 {
+		return nil, fmt.Errorf("workers can't be negative")
+	}
+	return &e, nil
+}
+
+// simulationRun is a single game of the experiment.
+type simulationRun struct {
+	cell   int
+	sample int
+	config serverapi.ReplayLevelConfig
+}
+
+// defaultLevelFields are the settings used for the fields
+// that are not mentioned by the experiment.
+var defaultLevelFields = map[string]any{
+	"players_mode":     serverapi.PmodeSingleBot,
+	"drones_power":     1,
+	"teleporters":      1,
+	"oil_regen_rage":   2,
+	"terrain":          1,
+	"resources":        2,
+	"world_size":       2,
+	"creep_difficulty": 3,
+	"initial_creeps":   1,
+	"num_creep_bases":  2,
+	"creep_spawn_rate": 1,
+	"boss_difficulty":  1,
+}
+
+// createRuns expands the experiment into the list of the game configs.
+func (e *experiment) createRuns(rng *gmath.Rand) ([]simulationRun, error) {
+	for field := range e.Sweep {
+		if _, ok := e.Fixed[field]; ok {
+			return nil, fmt.Errorf("%s can't be both fixed and swept", field)
+		}
+		if len(e.Sweep[field]) == 0 {
+			return nil, fmt.Errorf("%s sweep has no values", field)
+		}
+	}
+	for field := range e.Random {
+		if _, ok := e.Fixed[field]; ok {
+			return nil, fmt.Errorf("%s can't be both fixed and random", field)
+		}
+		if _, ok := e.Sweep[field]; ok {
+			return nil, fmt.Errorf("%s can't be both swept and random", field)
+		}
+	}
+
+	// The map iteration order is random, so the sweep fields are sorted
+	// to get the same cell numbers for the same experiment.
+	sweepFields := make([]string, 0, len(e.Sweep))
+	for field := range e.Sweep {
+		sweepFields = append(sweepFields, field)
+	}
+	sort.Strings(sweepFields)
+	randomFields := make([]string, 0, len(e.Random))
+	for field := range e.Random {
+		randomFields = append(randomFields, field)
+	}
+	sort.Strings(randomFields)
+
+	numCells := 1
+	for _, field := range sweepFields {
+		numCells *= len(e.Sweep[field])
+	}
+
+	var runs []simulationRun
+	for cell := 0; cell < numCells; cell++ {
+		for sample := 0; sample < e.Samples; sample++ {
+			fields := make(map[string]any, len(defaultLevelFields)+len(e.Fixed)+len(e.Sweep)+len(e.Random))
+			for field, v := range defaultLevelFields {
+				fields[field] = v
+			}
+			for field, v := range e.Fixed {
+				fields[field] = v
+			}
+			// The cell index is a mixed radix number:
+			// every digit selects a sweep field value.
+			cellIndex := cell
+			for _, field := range sweepFields {
+				values := e.Sweep[field]
+				fields[field] = values[cellIndex%len(values)]
+				cellIndex /= len(values)
+			}
+			for _, field := range randomFields {
+				v, err := pickRandomValue(rng, e.Random[field])
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", field, err)
+				}
+				fields[field] = v
+			}
+
+			config, err := makeLevelConfig(rng, fields)
+			if err != nil {
+				return nil, err
+			}
+			runs = append(runs, simulationRun{cell: cell, sample: sample, config: config})
+		}
+	}
+
+	return runs, nil
+}
+
+func pickRandomValue(rng *gmath.Rand, spec any) (any, error) {
+	switch spec := spec.(type) {
+	case []any:
+		if len(spec) == 0 {
+			return nil, fmt.Errorf("no values to pick from")
+		}
+		return gmath.RandElem(rng, spec), nil
+	case map[string]any:
+		var r struct {
+			Min *int `json:"min"`
+			Max *int `json:"max"`
+		}
+		if err := decodeStrict(spec, &r); err != nil {
+			return nil, err
+		}
+		if r.Min == nil || r.Max == nil || *r.Min > *r.Max {
+			return nil, fmt.Errorf("invalid {min, max} range")
+		}
+		return rng.IntRange(*r.Min, *r.Max), nil
+	default:
+		return nil, fmt.Errorf("expected a list or a {min, max} range, found %v", spec)
+	}
+}
+
+func makeLevelConfig(rng *gmath.Rand, fields map[string]any) (serverapi.ReplayLevelConfig, error) {
+	var config serverapi.ReplayLevelConfig
+	if err := decodeStrict(fields, &config); err != nil {
+		return config, err
+	}
+
+	switch config.RawGameMode {
+	case "classic", "arena", "inf_arena", "blitz":
+		// OK.
+	default:
+		return config, fmt.Errorf("unsupported game mode: %q", config.RawGameMode)
+	}
+	switch config.PlayersMode {
+	case serverapi.PmodeSingleBot, serverapi.PmodeTwoBots:
+		// OK.
+	default:
+		return config, fmt.Errorf("players_mode should be %d (single bot) or %d (two bots)",
+			serverapi.PmodeSingleBot, serverapi.PmodeTwoBots)
+	}
+	if err := gamedata.ValidateLevelOptions(&config); err != nil {
+		return config, err
+	}
+
+	if _, ok := fields["seed"]; !ok {
+		config.Seed = rng.PositiveInt64()
+	}
+
+	// Create a random bot build.
+	if _, ok := fields["tier2_recipes"]; !ok {
+		config.Tier2Recipes = gamedata.CreateDroneBuild(rng)
+	}
+	if _, ok := fields["core_design"]; !ok {
+		var coreDesigns []string
+		for _, core := range gamedata.CoreStatsList {
+			coreDesigns = append(coreDesigns, core.Name)
+		}
+		config.CoreDesign = gamedata.PickColonyDesign(coreDesigns, rng)
+	}
+	if _, ok := fields["turret_design"]; !ok {
+		var turretDesigns []string
+		for _, turret := range gamedata.TurretStatsList {
+			turretDesigns = append(turretDesigns, turret.Kind.String())
+		}
+		config.TurretDesign = gamedata.PickTurretDesign(config.CoreDesign, turretDesigns, rng)
+	}
+
+	return config, nil
+}
+
+// decodeStrict converts the generic YAML/JSON value into the typed one.
+// The unknown fields are reported, so the typos are not silently ignored.
+func decodeStrict(v any, dst any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(dst)
+}
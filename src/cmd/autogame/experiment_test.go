@@ -0,0 +1,200 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+func TestCreateRunsSweep(t *testing.T) {
+	e := &experiment{
+		Samples: 2,
+		Fixed:   map[string]any{"mode": "classic"},
+		Sweep: map[string][]any{
+			"world_size":  {1, 2, 3},
+			"bot_profile": {0, 4},
+		},
+	}
+	var rng gmath.Rand
+	rng.SetSeed(1)
+	runs, err := e.createRuns(&rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 12 {
+		t.Fatalf("have %d runs, want 12", len(runs))
+	}
+
+	// The sweep fields are sorted, so bot_profile is the lowest cell index digit.
+	for i, run := range runs {
+		cell := i / e.Samples
+		if run.cell != cell || run.sample != i%e.Samples {
+			t.Fatalf("run #%d: have cell %d sample %d", i, run.cell, run.sample)
+		}
+		wantProfile := []int{0, 4}[cell%2]
+		wantWorldSize := []int{1, 2, 3}[cell/2]
+		if run.config.BotProfile != wantProfile || run.config.WorldSize != wantWorldSize {
+			t.Fatalf("cell %d: have bot_profile=%d world_size=%d, want %d and %d",
+				cell, run.config.BotProfile, run.config.WorldSize, wantProfile, wantWorldSize)
+		}
+		if run.config.RawGameMode != "classic" || run.config.PlayersMode != serverapi.PmodeSingleBot {
+			t.Fatalf("cell %d: unexpected fixed or default fields: %+v", cell, run.config)
+		}
+	}
+}
+
+func TestCreateRunsRandomRange(t *testing.T) {
+	e := &experiment{
+		Samples: 50,
+		Fixed:   map[string]any{"mode": "arena"},
+		Random: map[string]any{
+			// The JSON experiment numbers are decoded as float64.
+			"creep_difficulty": map[string]any{"min": 2.0, "max": 5.0},
+		},
+	}
+	var rng gmath.Rand
+	rng.SetSeed(1)
+	runs, err := e.createRuns(&rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[int]bool)
+	for _, run := range runs {
+		v := run.config.CreepDifficulty
+		if v < 2 || v > 5 {
+			t.Fatalf("creep_difficulty %d is out of the [2, 5] range", v)
+		}
+		seen[v] = true
+	}
+	if len(seen) != 4 {
+		t.Fatalf("not all range values were picked: %v", seen)
+	}
+}
+
+func TestCreateRunsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		fixed  map[string]any
+		sweep  map[string][]any
+		random map[string]any
+		err    string
+	}{
+		{
+			name:  "fixed and swept",
+			fixed: map[string]any{"mode": "classic", "world_size": 1},
+			sweep: map[string][]any{"world_size": {1, 2}},
+			err:   "world_size can't be both fixed and swept",
+		},
+		{
+			name:   "fixed and random",
+			fixed:  map[string]any{"mode": "classic", "terrain": 1},
+			random: map[string]any{"terrain": []any{0, 1}},
+			err:    "terrain can't be both fixed and random",
+		},
+		{
+			name:   "swept and random",
+			fixed:  map[string]any{"mode": "classic"},
+			sweep:  map[string][]any{"terrain": {0, 1}},
+			random: map[string]any{"terrain": []any{0, 1}},
+			err:    "terrain can't be both swept and random",
+		},
+		{
+			name:  "empty sweep",
+			fixed: map[string]any{"mode": "classic"},
+			sweep: map[string][]any{"terrain": {}},
+			err:   "terrain sweep has no values",
+		},
+		{
+			name:   "inverted range",
+			fixed:  map[string]any{"mode": "classic"},
+			random: map[string]any{"terrain": map[string]any{"min": 2, "max": 1}},
+			err:    "terrain: invalid {min, max} range",
+		},
+		{
+			name:   "incomplete range",
+			fixed:  map[string]any{"mode": "classic"},
+			random: map[string]any{"terrain": map[string]any{"min": 1}},
+			err:    "terrain: invalid {min, max} range",
+		},
+		{
+			name:   "unknown range key",
+			fixed:  map[string]any{"mode": "classic"},
+			random: map[string]any{"terrain": map[string]any{"min": 0, "max": 1, "step": 1}},
+			err:    `unknown field "step"`,
+		},
+		{
+			name:   "not a range",
+			fixed:  map[string]any{"mode": "classic"},
+			random: map[string]any{"terrain": 1},
+			err:    "terrain: expected a list or a {min, max} range",
+		},
+		{
+			name:  "unknown field",
+			fixed: map[string]any{"mode": "classic", "wrold_size": 1},
+			err:   `unknown field "wrold_size"`,
+		},
+		{
+			name:  "unsupported mode",
+			fixed: map[string]any{"mode": "reverse"},
+			err:   `unsupported game mode: "reverse"`,
+		},
+		{
+			name:  "out of range option",
+			fixed: map[string]any{"mode": "classic", "world_size": 4},
+			err:   "world_size: 4 is out of the [0, 3] range",
+		},
+		{
+			name:  "out of range sweep value",
+			fixed: map[string]any{"mode": "classic"},
+			sweep: map[string][]any{"bot_profile": {0, 100}},
+			err:   "bot_profile: 100 is out of the",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := &experiment{
+				Samples: 1,
+				Fixed:   test.fixed,
+				Sweep:   test.sweep,
+				Random:  test.random,
+			}
+			var rng gmath.Rand
+			rng.SetSeed(1)
+			_, err := e.createRuns(&rng)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("unexpected error:\nhave: %v\nwant: %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestLoadExperimentErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"no samples", `{"samples": 0}`, "samples should be positive"},
+		{"negative workers", `{"samples": 1, "workers": -2}`, "workers can't be negative"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "experiment.json")
+			if err := os.WriteFile(filename, []byte(test.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadExperiment(filename)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected %q error, got %v", test.err, err)
+			}
+		})
+	}
+}
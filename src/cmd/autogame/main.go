@@ -6,15 +6,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"time"
 
-	"github.com/quasilyte/ge"
-	"github.com/quasilyte/ge/langs"
 	"github.com/quasilyte/gmath"
-	"github.com/quasilyte/roboden-game/assets"
-	"github.com/quasilyte/roboden-game/gamedata"
 	"github.com/quasilyte/roboden-game/runsim"
-	"github.com/quasilyte/roboden-game/scenes/staging"
 	"github.com/quasilyte/roboden-game/serverapi"
 	"github.com/quasilyte/roboden-game/session"
 )
@@ -22,59 +18,85 @@ import (
 func main() {
 	outputDir := flag.String("o", "",
 		"an output directory")
+	experimentFile := flag.String("experiment", "",
+		"a YAML or JSON experiment file; see the experiment type for the format")
+	jobs := flag.Int("j", 0,
+		"how many games to run in parallel; overrides the experiment workers")
 	flag.Parse()
 
 	if *outputDir == "" {
 		panic("the output directory should be specified")
 	}
 
-	ctx := ge.NewContext(ge.ContextConfig{
-		Mute:          true,
-		TimeDeltaMode: ge.TimeDeltaFixed60,
-	})
-	ctx.Loader.OpenAssetFunc = assets.MakeOpenAssetFunc(ctx, "")
-	ctx.Dict = langs.NewDictionary("en", 2)
+	e := defaultExperiment()
+	if *experimentFile != "" {
+		var err error
+		e, err = loadExperiment(*experimentFile)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if *jobs != 0 {
+		e.Workers = *jobs
+	}
+	if e.Workers == 0 {
+		e.Workers = runtime.NumCPU()
+	}
+	if e.Timeout == 0 {
+		e.Timeout = 35
+	}
 
 	var rng gmath.Rand
 	rng.SetSeed(time.Now().UnixNano())
 
-	runsim.PrepareAssets(ctx)
+	runs, err := e.createRuns(&rng)
+	if err != nil {
+		panic(err)
+	}
 
 	generation := int(time.Now().Unix())
 
-	rstate := &runnerState{
-		ctx:     ctx,
-		session: runsim.NewState(ctx),
-		rng:     &rng,
+	runJobs := make([]func(*session.State) error, len(runs))
+	for i := range runs {
+		i := i
+		run := runs[i]
+		runJobs[i] = func(state *session.State) error {
+			fmt.Printf("Running simulation #%d (cell %d, sample %d)\n", i, run.cell, run.sample)
+			results, err := runsim.RunMatch(state, runsim.Match{
+				Config:         run.config,
+				TimeoutSeconds: e.Timeout,
+			})
+			if err != nil {
+				return err
+			}
+			filename := filepath.Join(*outputDir, fmt.Sprintf("%s_%d_%d.json", run.config.RawGameMode, generation, i))
+			data, err := json.Marshal(runResults{
+				Seed:    int(run.config.Seed),
+				Env:     run.config.Environment,
+				Victory: results.Results.Victory,
+				Score:   results.Results.Score,
+				Time:    results.Results.Time,
+				Mode:    run.config.RawGameMode,
+				Drones:  run.config.Tier2Recipes,
+				Turret:  run.config.TurretDesign,
+				Core:    run.config.CoreDesign,
+				Cell:    run.cell,
+				Config:  run.config,
+			})
+			if err != nil {
+				panic(err)
+			}
+			if err := os.WriteFile(filename, data, os.ModePerm); err != nil {
+				panic(err)
+			}
+			return nil
+		}
 	}
-	for i := 0; i < 1000; i++ {
-		fmt.Printf("Running simulation #%d\n", i)
-		results, config := runSimulation(rstate, "classic")
-		filename := filepath.Join(*outputDir, fmt.Sprintf("%s_%d_%d.json", config.RawGameMode, generation, i))
-		data, err := json.Marshal(runResults{
-			Seed:    int(config.Seed),
-			Env:     config.Environment,
-			Victory: results.Victory,
-			Score:   results.Score,
-			Time:    results.Time,
-			Mode:    config.RawGameMode,
-			Drones:  config.Tier2Recipes,
-			Turret:  config.TurretDesign,
-			Core:    config.CoreDesign,
-		})
+	runsim.RunJobs(runsim.PoolConfig{NumWorkers: e.Workers}, runJobs, func(i int, err error) {
 		if err != nil {
-			panic(err)
-		}
-		if err := os.WriteFile(filename, data, os.ModePerm); err != nil {
-			panic(err)
+			fmt.Fprintf(os.Stderr, "simulation #%d failed: %v\n", i, err)
 		}
-	}
-}
-
-type runnerState struct {
-	ctx     *ge.Context
-	session *session.State
-	rng     *gmath.Rand
+	})
 }
 
 type runResults struct {
@@ -88,58 +110,10 @@ type runResults struct {
 	Drones []string
 	Turret string
 	Core   string
-}
-
-func runSimulation(rstate *runnerState, mode string) (serverapi.GameResults, gamedata.LevelConfig) {
-	var replayConfig serverapi.ReplayLevelConfig
-	replayConfig.RawGameMode = mode
-	replayConfig.Seed = rstate.rng.PositiveInt64()
-
-	var turretDesigns []string
-	for _, turret := range gamedata.TurretStatsList {
-		turretDesigns = append(turretDesigns, turret.Kind.String())
-	}
-	var coreDesigns []string
-	for _, core := range gamedata.CoreStatsList {
-		coreDesigns = append(coreDesigns, core.Name)
-	}
 
-	// Create a random bot build.
-	replayConfig.Tier2Recipes = gamedata.CreateDroneBuild(rstate.rng)
-	replayConfig.CoreDesign = gamedata.PickColonyDesign(coreDesigns, rstate.rng)
-	replayConfig.TurretDesign = gamedata.PickTurretDesign(replayConfig.CoreDesign, turretDesigns, rstate.rng)
-	replayConfig.PlayersMode = serverapi.PmodeSingleBot
-	replayConfig.Environment = rstate.rng.IntRange(0, 3)
-
-	// Some default settings.
-	replayConfig.DronesPower = 1
-	replayConfig.SuperCreeps = false
-	replayConfig.Teleporters = 1
-	replayConfig.OilRegenRate = 2
-	replayConfig.Terrain = 1
-	replayConfig.Resources = 2
-	replayConfig.WorldSize = 2
-	replayConfig.CreepDifficulty = 3
-
-	// Mode-specific settings.
-	switch mode {
-	case "classic":
-		replayConfig.InitialCreeps = 1
-		replayConfig.NumCreepBases = 2
-		replayConfig.CreepSpawnRate = 1
-		replayConfig.BossDifficulty = 1
-	default:
-		panic("unexpected game mode")
-	}
-
-	config := gamedata.MakeLevelConfig(gamedata.ExecuteSimulation, replayConfig)
-	config.Finalize()
-
-	controller := staging.NewController(rstate.session, config, nil)
-	simResult, err := runsim.Run(rstate.session, 0, 35, controller)
-	if err != nil {
-		panic(err)
-	}
+	// Cell is the experiment sweep values combination index.
+	Cell int
 
-	return simResult, config
+	// Config is the complete level config used for this run.
+	Config serverapi.ReplayLevelConfig
 }
@@ -14,6 +14,8 @@ import (
 func main() {
 	dir := flag.String("dir", "",
 		"path to a folder that contains simulation results")
+	groupBy := flag.String("group", "",
+		"a level config field JSON name to group the results by, like world_size")
 	flag.Parse()
 
 	if *dir == "" {
@@ -29,6 +31,7 @@ func main() {
 	numSamples := 0
 	statsByDrone := map[string]*droneStats{}
 	statsByBuild := map[string]*buildStats{}
+	statsByGroup := map[string]*groupStats{}
 	for _, f := range files {
 		data, err := os.ReadFile(filepath.Join(*dir, f.Name()))
 		if err != nil {
@@ -39,6 +42,23 @@ func main() {
 			panic(err)
 		}
 		numSamples++
+		if *groupBy != "" {
+			value, ok := results.Config[*groupBy]
+			if !ok {
+				panic(fmt.Sprintf("%s: no %q config field", f.Name(), *groupBy))
+			}
+			key := string(value)
+			stats := statsByGroup[key]
+			if stats == nil {
+				stats = &groupStats{value: key}
+				statsByGroup[key] = stats
+			}
+			stats.picks++
+			stats.totalScore += results.Score
+			if results.Victory {
+				stats.wins++
+			}
+		}
 		keyParts := make([]string, 0, len(results.Drones))
 		for _, drone := range results.Drones {
 			keyParts = append(keyParts, drone)
@@ -100,6 +120,30 @@ func main() {
 		}
 		fmt.Printf("%v => %d%% (%d picks)\n", stats.drones, int(math.Round(100*stats.winRate)), stats.picks)
 	}
+
+	if *groupBy != "" {
+		var groupStatsList []*groupStats
+		for _, stats := range statsByGroup {
+			groupStatsList = append(groupStatsList, stats)
+		}
+		sort.Slice(groupStatsList, func(i, j int) bool {
+			return groupStatsList[i].value < groupStatsList[j].value
+		})
+		fmt.Println("----")
+		for _, stats := range groupStatsList {
+			winRate := float64(stats.wins) / float64(stats.picks)
+			avgScore := float64(stats.totalScore) / float64(stats.picks)
+			fmt.Printf("%s=%s => %d%%, avg score %.1f (%d samples)\n", *groupBy, stats.value, int(math.Round(100*winRate)), avgScore, stats.picks)
+		}
+	}
+}
+
+type groupStats struct {
+	value string
+
+	picks      int
+	wins       int
+	totalScore int
 }
 
 type buildStats struct {
@@ -131,4 +175,8 @@ type runResults struct {
 	Drones []string
 	Turret string
 	Core   string
+
+	// Config is a full level config in the ReplayLevelConfig JSON format.
+	// It's only recorded by the newer autogame versions.
+	Config map[string]json.RawMessage
 }
@@ -36,6 +36,10 @@ func Main() {
 			state.Logf("failed to initialize game data storage: %v", err)
 		} else {
 			state.GameData = m
+			if runtime.GOARCH != "wasm" {
+				// Keep the exported replays near the save data, so they're easy to find.
+				state.ReplayExchangeDir = filepath.Join(filepath.Dir(m.ItemPath("save.json")), "replays")
+			}
 			if runtime.GOARCH == "wasm" {
 				// We were using "save" key before, now it's "save.json";
 				// migrate the old save item to a new key if localStorage
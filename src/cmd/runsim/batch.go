@@ -0,0 +1,253 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quasilyte/roboden-game/runsim"
+	"github.com/quasilyte/roboden-game/scenes/staging"
+	"github.com/quasilyte/roboden-game/serverapi"
+	"github.com/quasilyte/roboden-game/session"
+)
+
+// Batch report statuses.
+// The failed replays have a serverapi.SimulationFailure kind as a status.
+const (
+	statusOK       = "ok"
+	statusMismatch = "mismatch"
+)
+
+type batchConfig struct {
+	path           string
+	numWorkers     int
+	timeoutSeconds int
+	trust          bool
+	debug          bool
+	output         io.Writer
+}
+
+type batchReport struct {
+	Name     string                       `json:"name"`
+	Status   string                       `json:"status"`
+	Failure  *serverapi.SimulationFailure `json:"failure,omitempty"`
+	Expected *serverapi.GameResults       `json:"expected,omitempty"`
+	Actual   *serverapi.GameResults       `json:"actual,omitempty"`
+	Desync   *staging.StateDesync         `json:"desync,omitempty"`
+	Seconds  float64                      `json:"seconds"`
+}
+
+// batchItem is a replay file to verify.
+// The data is loaded lazily by the worker.
+type batchItem struct {
+	name string
+	load func() ([]byte, error)
+}
+
+// runBatch verifies every replay from the directory or zip archive.
+// It writes a JSON line report for every replay and
+// returns the number of replays that didn't pass the verification.
+func runBatch(config batchConfig) (int, error) {
+	items, closer, err := collectBatchItems(config.path)
+	if err != nil {
+		return 0, err
+	}
+	defer closer()
+
+	reports := make([]batchReport, len(items))
+	jobs := make([]func(*session.State) error, len(items))
+	for i := range items {
+		i := i
+		jobs[i] = func(state *session.State) error {
+			reports[i] = verifyBatchItem(state, config, items[i])
+			if failure := reports[i].Failure; failure != nil {
+				return errors.New(failure.Message)
+			}
+			return nil
+		}
+	}
+
+	w := bufio.NewWriter(config.output)
+	defer w.Flush()
+	numFailed := 0
+	var writeErr error
+	poolConfig := runsim.PoolConfig{
+		NumWorkers: config.numWorkers,
+		DebugLogs:  config.debug,
+	}
+	runsim.RunJobs(poolConfig, jobs, func(i int, err error) {
+		report := reports[i]
+		if err != nil && report.Failure == nil {
+			// A panic outside of the replay simulation.
+			report = batchReport{Name: items[i].name}
+			report.Status = serverapi.FailureCrash
+			report.Failure = &serverapi.SimulationFailure{
+				Kind:    serverapi.FailureCrash,
+				Message: err.Error(),
+			}
+		}
+		if report.Status != statusOK {
+			numFailed++
+		}
+		if writeErr != nil {
+			return
+		}
+		data, err := json.Marshal(report)
+		if err != nil {
+			writeErr = err
+			return
+		}
+		w.Write(data)
+		w.WriteByte('\n')
+	})
+	if writeErr != nil {
+		return numFailed, writeErr
+	}
+
+	fmt.Fprintf(os.Stderr, "verified %d replays, %d failed\n", len(items), numFailed)
+	return numFailed, nil
+}
+
+func verifyBatchItem(state *session.State, config batchConfig, item batchItem) batchReport {
+	report := batchReport{Name: item.name}
+
+	fail := func(failure *serverapi.SimulationFailure) batchReport {
+		report.Status = failure.Kind
+		report.Failure = failure
+		return report
+	}
+
+	data, err := item.load()
+	if err != nil {
+		return fail(&serverapi.SimulationFailure{
+			Kind:    serverapi.FailureBadReplay,
+			Message: err.Error(),
+		})
+	}
+	var replay serverapi.GameReplay
+	if err := serverapi.UnmarshalReplay(data, &replay); err != nil {
+		return fail(&serverapi.SimulationFailure{
+			Kind:    serverapi.FailureBadReplay,
+			Message: fmt.Sprintf("unmarshal replay: %v", err),
+		})
+	}
+	if replay.LevelGenChecksum == 0 && !config.trust {
+		return fail(&serverapi.SimulationFailure{
+			Kind:    serverapi.FailureBadReplay,
+			Message: "replay has a zero levelgen checksum",
+		})
+	}
+
+	start := time.Now()
+	result, err := runsim.RunReplay(state, replay, config.timeoutSeconds)
+	report.Seconds = time.Since(start).Seconds()
+	report.Desync = result.Desync
+	report.Expected = &replay.Results
+	if err != nil {
+		return fail(runsim.DescribeFailure(err))
+	}
+
+	report.Actual = &result.Results
+	if result.Results != replay.Results {
+		report.Status = statusMismatch
+	} else {
+		report.Status = statusOK
+	}
+	return report
+}
+
+func collectBatchItems(path string) ([]batchItem, func(), error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !info.IsDir() {
+		if !strings.HasSuffix(path, ".zip") {
+			return nil, nil, fmt.Errorf("%s: expected a directory or a zip archive", path)
+		}
+		archive, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		var items []batchItem
+		for _, f := range archive.File {
+			if !isReplayFilename(f.Name) {
+				continue
+			}
+			f := f
+			items = append(items, batchItem{
+				name: f.Name,
+				load: func() ([]byte, error) {
+					r, err := f.Open()
+					if err != nil {
+						return nil, err
+					}
+					defer r.Close()
+					return decodeReplayFile(f.Name, r)
+				},
+			})
+		}
+		return items, func() { archive.Close() }, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []batchItem
+	for _, e := range entries {
+		if e.IsDir() || !isReplayFilename(e.Name()) {
+			continue
+		}
+		filename := filepath.Join(path, e.Name())
+		items = append(items, batchItem{
+			name: e.Name(),
+			load: func() ([]byte, error) {
+				f, err := os.Open(filename)
+				if err != nil {
+					return nil, err
+				}
+				defer f.Close()
+				return decodeReplayFile(filename, f)
+			},
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].name < items[j].name
+	})
+	return items, func() {}, nil
+}
+
+func isReplayFilename(name string) bool {
+	return strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".json.gz") ||
+		strings.HasSuffix(name, serverapi.ReplayBinaryFileExt)
+}
+
+// decodeReplayFile reads the replay data; it can be either JSON or binary.
+// The gzipped files are supported as the server stores them in that way.
+func decodeReplayFile(name string, r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(name, ".gz") {
+		return data, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
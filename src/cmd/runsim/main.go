@@ -2,18 +2,14 @@ package main
 
 import (
 	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"runtime"
 
-	"github.com/quasilyte/ge"
-	"github.com/quasilyte/ge/langs"
-	"github.com/quasilyte/roboden-game/assets"
-	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/gmath"
 	"github.com/quasilyte/roboden-game/runsim"
-	"github.com/quasilyte/roboden-game/scenes/staging"
 	"github.com/quasilyte/roboden-game/serverapi"
 )
 
@@ -21,46 +17,105 @@ func main() {
 	timeoutFlag := flag.Int("timeout", 30, "simulation timeout in seconds")
 	debugFlag := flag.Bool("debug", false, "whether to enable debug logs")
 	trustFlag := flag.Bool("trust", false, "whether to allow 0 levelgen checksums")
+	batchFlag := flag.String("batch", "", "verify all replays from this directory or zip archive instead of stdin")
+	jobsFlag := flag.Int("j", runtime.NumCPU(), "how many replays to verify in parallel in batch mode")
+	outputFlag := flag.String("o", "", "where to write the batch mode JSON lines report or the play mode results; stdout if empty")
+	playFlag := flag.String("play", "", "play a game described by this level config JSON file with the external bots")
+	botFlag := flag.String("bot", "stdio", "a comma-separated list of the play mode bot addresses: stdio, tcp:ADDR or unix:PATH")
 	flag.Parse()
 
+	if *playFlag != "" {
+		err := runPlay(playConfig{
+			levelConfigPath: *playFlag,
+			bots:            *botFlag,
+			debug:           *debugFlag,
+			outputPath:      *outputFlag,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "play: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *batchFlag != "" {
+		output := os.Stdout
+		if *outputFlag != "" {
+			f, err := os.Create(*outputFlag)
+			if err != nil {
+				panic(err)
+			}
+			defer f.Close()
+			output = f
+		}
+		numFailed, err := runBatch(batchConfig{
+			path:           *batchFlag,
+			numWorkers:     gmath.ClampMin(*jobsFlag, 1),
+			timeoutSeconds: *timeoutFlag,
+			trust:          *trustFlag,
+			debug:          *debugFlag,
+			output:         output,
+		})
+		if err != nil {
+			panic(err)
+		}
+		if numFailed != 0 {
+			output.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
 	replayDataBytes, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		panic(err)
 	}
+	// The encoding is detected by the data header,
+	// so both JSON and binary replays are accepted.
 	var replayData serverapi.GameReplay
-	if err := json.Unmarshal(replayDataBytes, &replayData); err != nil {
-		panic(err)
+	if err := serverapi.UnmarshalReplay(replayDataBytes, &replayData); err != nil {
+		exitWithFailure(&serverapi.SimulationFailure{
+			Kind:    serverapi.FailureBadReplay,
+			Message: fmt.Sprintf("unmarshal replay: %v", err),
+		})
 	}
 
 	if replayData.LevelGenChecksum == 0 && !*trustFlag {
-		panic(errors.New("replay has a zero levelgen checksum"))
+		exitWithFailure(&serverapi.SimulationFailure{
+			Kind:    serverapi.FailureBadReplay,
+			Message: "replay has a zero levelgen checksum",
+		})
 	}
 
-	config := gamedata.MakeLevelConfig(gamedata.ExecuteSimulation, replayData.Config)
-	ctx := ge.NewContext(ge.ContextConfig{
-		Mute:          true,
-		TimeDeltaMode: ge.TimeDeltaFixed60,
-	})
-	ctx.Loader.OpenAssetFunc = assets.MakeOpenAssetFunc(ctx, "")
-	ctx.Dict = langs.NewDictionary("en", 2)
-
-	runsim.PrepareAssets(ctx)
-
+	ctx := runsim.NewContext()
 	state := runsim.NewState(ctx)
 	state.Persistent.Settings.DebugLogs = *debugFlag
 
-	config.Finalize()
+	simResult, err := runsim.RunReplay(state, replayData, *timeoutFlag)
+	if simResult.Desync != nil {
+		// The stdout is reserved for the results.
+		fmt.Fprintf(os.Stderr, "state desync at tick %d: %s hash mismatch (expected %d, got %d)\n",
+			simResult.Desync.Tick, simResult.Desync.Subsystem, simResult.Desync.Expected, simResult.Desync.Actual)
+	}
+	if err != nil {
+		exitWithFailure(runsim.DescribeFailure(err))
+	}
 
-	controller := staging.NewController(state, config, nil)
-	controller.SetReplayActions(replayData)
-	simResult, err := runsim.Run(state, replayData.LevelGenChecksum, *timeoutFlag, controller)
+	encodedResult, err := json.Marshal(simResult.Results)
 	if err != nil {
 		panic(err)
 	}
+	fmt.Println(string(encodedResult))
+}
 
-	encodedResult, err := json.Marshal(simResult)
+// exitWithFailure prints the failure JSON instead of the results and exits with a non-zero code.
+// The caller can tell the failure from the results by the exit code.
+func exitWithFailure(failure *serverapi.SimulationFailure) {
+	encodedFailure, err := json.Marshal(failure)
 	if err != nil {
 		panic(err)
 	}
-	fmt.Println(string(encodedResult))
+	fmt.Println(string(encodedFailure))
+	fmt.Fprintf(os.Stderr, "%s: %s\n", failure.Kind, failure.Message)
+	os.Exit(1)
 }
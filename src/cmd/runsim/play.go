@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/quasilyte/roboden-game/runsim"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+type playConfig struct {
+	levelConfigPath string
+	bots            string
+	debug           bool
+	outputPath      string
+}
+
+// runPlay plays a game with the external bots and prints its results.
+//
+// The bot addresses are assigned to the players in order.
+// The address is one of:
+//
+//	stdio      - use the runsim stdin and stdout
+//	tcp:ADDR   - connect to the TCP socket, like tcp:127.0.0.1:4000
+//	unix:PATH  - connect to the unix domain socket
+func runPlay(config playConfig) error {
+	data, err := os.ReadFile(config.levelConfigPath)
+	if err != nil {
+		return err
+	}
+	var levelConfig serverapi.ReplayLevelConfig
+	if err := json.Unmarshal(data, &levelConfig); err != nil {
+		return fmt.Errorf("unmarshal level config: %w", err)
+	}
+
+	var bots []runsim.ExternalBot
+	usesStdio := false
+	for i, addr := range strings.Split(config.bots, ",") {
+		addr = strings.TrimSpace(addr)
+		bot := runsim.ExternalBot{PlayerID: i}
+		switch {
+		case addr == "stdio":
+			if usesStdio {
+				return fmt.Errorf("only one bot can use stdio")
+			}
+			usesStdio = true
+			bot.R = bufio.NewReader(os.Stdin)
+			bot.W = os.Stdout
+		case strings.HasPrefix(addr, "tcp:"), strings.HasPrefix(addr, "unix:"):
+			network, address, _ := strings.Cut(addr, ":")
+			conn, err := net.Dial(network, address)
+			if err != nil {
+				return fmt.Errorf("connect to bot %d: %w", i, err)
+			}
+			defer conn.Close()
+			bot.R = bufio.NewReader(conn)
+			bot.W = conn
+		default:
+			return fmt.Errorf("unexpected bot %d address: %q", i, addr)
+		}
+		bots = append(bots, bot)
+	}
+
+	ctx := runsim.NewContext()
+	state := runsim.NewState(ctx)
+	state.Persistent.Settings.DebugLogs = config.debug
+	// The stdout could be reserved for the bot protocol.
+	state.LogWriter = os.Stderr
+
+	// The bots response time is not limited,
+	// so there is no simulation timeout either.
+	results, err := runsim.RunExternalBots(state, levelConfig, bots, 0)
+	if err != nil {
+		return err
+	}
+
+	// The stdout could be reserved for the bot protocol.
+	var output io.Writer = os.Stdout
+	if usesStdio {
+		output = os.Stderr
+	}
+	if config.outputPath != "" {
+		f, err := os.Create(config.outputPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		output = f
+	}
+	return json.NewEncoder(output).Encode(results)
+}
@@ -0,0 +1,18 @@
+-- All game modes share this table, the mode is a part of the key.
+-- The legacy per-mode tables (see season*.sql) are imported
+-- into it by the server migrations (see migrations.go).
+-- The server embeds this file, it's the only scores table definition.
+CREATE TABLE scores (
+    mode TEXT NOT NULL,
+    player_name TEXT NOT NULL,
+    replay_id INTEGER,
+    score INTEGER NOT NULL,
+    difficulty INTEGER NOT NULL,
+    time_seconds INTEGER NOT NULL DEFAULT 0,
+    drones TEXT,
+    platform TEXT,
+    PRIMARY KEY (mode, player_name)
+);
+
+CREATE INDEX scores_player_name_index
+ON scores(player_name);
@@ -7,29 +7,19 @@ import (
 	"github.com/quasilyte/roboden-game/serverapi"
 )
 
+// seasonDB stores the verified player scores of a single season.
+//
+// All game modes share the same scores table; the mode name
+// is a part of the primary key. See _schema/scores.sql.
 type seasonDB struct {
 	id   int
 	conn *sql.DB
 
-	classicPlayerScore *sql.Stmt
-	classicFetchAll    *sql.Stmt
-	classicUpsert      *sql.Stmt
-
-	blitzPlayerScore *sql.Stmt
-	blitzFetchAll    *sql.Stmt
-	blitzUpsert      *sql.Stmt
-
-	arenaPlayerScore *sql.Stmt
-	arenaFetchAll    *sql.Stmt
-	arenaUpsert      *sql.Stmt
-
-	infArenaPlayerScore *sql.Stmt
-	infArenaFetchAll    *sql.Stmt
-	infArenaUpsert      *sql.Stmt
-
-	reversePlayerScore *sql.Stmt
-	reverseFetchAll    *sql.Stmt
-	reverseUpsert      *sql.Stmt
+	playerScore    *sql.Stmt
+	playerReplayID *sql.Stmt
+	playerEntries  *sql.Stmt
+	fetchAll       *sql.Stmt
+	upsert         *sql.Stmt
 }
 
 func withTransaction(conn *sql.DB, f func(tx *sql.Tx) error) (err error) {
@@ -56,241 +46,135 @@ func withTransaction(conn *sql.DB, f func(tx *sql.Tx) error) (err error) {
 
 func (db *seasonDB) PrepareQueries() error {
 	{
-		q := "SELECT score FROM classic_scores WHERE player_name = ?"
+		q := "SELECT score FROM scores WHERE mode = ? AND player_name = ?"
 		stmt, err := db.conn.Prepare(q)
 		if err != nil {
 			return err
 		}
-		db.classicPlayerScore = stmt
-	}
-
-	if db.id == currentSeason {
-		q := `
-			SELECT player_name, score, difficulty, drones, time_seconds, platform
-			FROM classic_scores
-			ORDER BY score DESC
-		`
-		stmt, err := db.conn.Prepare(q)
-		if err != nil {
-			return err
-		}
-		db.classicFetchAll = stmt
+		db.playerScore = stmt
 	}
 
 	{
-		q := `
-		INSERT OR REPLACE INTO classic_scores
-			('player_name', 'replay_id', 'score', 'difficulty', 'drones', 'time_seconds', 'platform')
-		VALUES
-			(?, ?, ?, ?, ?, ?, ?)
-		`
+		q := "SELECT COALESCE(replay_id, 0) FROM scores WHERE mode = ? AND player_name = ?"
 		stmt, err := db.conn.Prepare(q)
 		if err != nil {
 			return err
 		}
-		db.classicUpsert = stmt
+		db.playerReplayID = stmt
 	}
 
 	{
-		q := "SELECT score FROM blitz_scores WHERE player_name = ?"
-		stmt, err := db.conn.Prepare(q)
-		if err != nil {
-			return err
-		}
-		db.blitzPlayerScore = stmt
-	}
-
-	if db.id == currentSeason {
+		// The rank is calculated in the same way as for the cached boards:
+		// players with equal scores share the same rank.
 		q := `
-			SELECT player_name, score, difficulty, drones, time_seconds, platform
-			FROM blitz_scores
-			ORDER BY score DESC
+			SELECT
+				s.mode, s.score, s.difficulty, s.time_seconds,
+				COALESCE(s.drones, ''), COALESCE(s.platform, ''), COALESCE(s.replay_id, 0),
+				(SELECT COUNT(DISTINCT r.score) FROM scores r WHERE r.mode = s.mode AND r.score > s.score) + 1
+			FROM scores s
+			WHERE s.player_name = ?
+			ORDER BY s.mode
 		`
 		stmt, err := db.conn.Prepare(q)
 		if err != nil {
 			return err
 		}
-		db.blitzFetchAll = stmt
+		db.playerEntries = stmt
 	}
 
 	{
 		q := `
-		INSERT OR REPLACE INTO blitz_scores
-			('player_name', 'replay_id', 'score', 'difficulty', 'drones', 'time_seconds', 'platform')
-		VALUES
-			(?, ?, ?, ?, ?, ?, ?)
-		`
-		stmt, err := db.conn.Prepare(q)
-		if err != nil {
-			return err
-		}
-		db.blitzUpsert = stmt
-	}
-
-	{
-		q := "SELECT score FROM arena_scores WHERE player_name = ?"
-		stmt, err := db.conn.Prepare(q)
-		if err != nil {
-			return err
-		}
-		db.arenaPlayerScore = stmt
-	}
-
-	if db.id == currentSeason {
-		q := `
-			SELECT player_name, score, difficulty, drones, platform
-			FROM arena_scores
+			SELECT
+				player_name, score, difficulty, time_seconds,
+				COALESCE(drones, ''), COALESCE(platform, ''), COALESCE(replay_id, 0)
+			FROM scores
+			WHERE mode = ?
 			ORDER BY score DESC
 		`
 		stmt, err := db.conn.Prepare(q)
 		if err != nil {
 			return err
 		}
-		db.arenaFetchAll = stmt
+		db.fetchAll = stmt
 	}
 
 	{
+		// Replays are verified concurrently and can finish in any order.
+		// The conflict clause makes sure that a worse result never
+		// overwrites the better one.
 		q := `
-		INSERT OR REPLACE INTO arena_scores
-			('player_name', 'replay_id', 'score', 'difficulty', 'drones', 'platform')
+		INSERT INTO scores
+			('mode', 'player_name', 'replay_id', 'score', 'difficulty', 'drones', 'time_seconds', 'platform')
 		VALUES
-			(?, ?, ?, ?, ?, ?)
-		`
-		stmt, err := db.conn.Prepare(q)
-		if err != nil {
-			return err
-		}
-		db.arenaUpsert = stmt
-	}
-
-	{
-		q := "SELECT score FROM inf_arena_scores WHERE player_name = ?"
-		stmt, err := db.conn.Prepare(q)
-		if err != nil {
-			return err
-		}
-		db.infArenaPlayerScore = stmt
-	}
-
-	if db.id == currentSeason {
-		q := `
-			SELECT player_name, score, difficulty, drones, time_seconds, platform
-			FROM inf_arena_scores
-			ORDER BY score DESC
+			(?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (mode, player_name) DO UPDATE SET
+			replay_id = excluded.replay_id,
+			score = excluded.score,
+			difficulty = excluded.difficulty,
+			drones = excluded.drones,
+			time_seconds = excluded.time_seconds,
+			platform = excluded.platform
+		WHERE excluded.score > scores.score
 		`
 		stmt, err := db.conn.Prepare(q)
 		if err != nil {
 			return err
 		}
-		db.infArenaFetchAll = stmt
-	}
-
-	{
-		q := `
-		INSERT OR REPLACE INTO inf_arena_scores
-			('player_name', 'replay_id', 'score', 'difficulty', 'drones', 'time_seconds', 'platform')
-		VALUES
-			(?, ?, ?, ?, ?, ?, ?)
-		`
-		stmt, err := db.conn.Prepare(q)
-		if err != nil {
-			return err
-		}
-		db.infArenaUpsert = stmt
-	}
-
-	{
-		q := "SELECT score FROM reverse_scores WHERE player_name = ?"
-		stmt, err := db.conn.Prepare(q)
-		if err != nil {
-			return err
-		}
-		db.reversePlayerScore = stmt
-	}
-
-	if db.id == currentSeason {
-		q := `
-			SELECT player_name, score, difficulty, time_seconds, platform
-			FROM reverse_scores
-			ORDER BY score DESC
-		`
-		stmt, err := db.conn.Prepare(q)
-		if err != nil {
-			return err
-		}
-		db.reverseFetchAll = stmt
-	}
-
-	{
-		q := `
-		INSERT OR REPLACE INTO reverse_scores
-			('player_name', 'replay_id', 'score', 'difficulty', 'time_seconds', 'platform')
-		VALUES
-			(?, ?, ?, ?, ?, ?)
-		`
-		stmt, err := db.conn.Prepare(q)
-		if err != nil {
-			return err
-		}
-		db.reverseUpsert = stmt
+		db.upsert = stmt
 	}
 
 	return nil
 }
 
 func (db *seasonDB) UpdatePlayerScore(mode, name string, replayID int, drones string, score, difficulty, timeSeconds int, platform string) error {
-	var err error
-	switch mode {
-	case "classic":
-		_, err = db.classicUpsert.Exec(name, replayID, score, difficulty, drones, timeSeconds, platform)
-	case "blitz":
-		_, err = db.blitzUpsert.Exec(name, replayID, score, difficulty, drones, timeSeconds, platform)
-	case "arena":
-		_, err = db.arenaUpsert.Exec(name, replayID, score, difficulty, drones, platform)
-	case "inf_arena":
-		_, err = db.infArenaUpsert.Exec(name, replayID, score, difficulty, drones, timeSeconds, platform)
-	case "reverse":
-		_, err = db.reverseUpsert.Exec(name, replayID, score, difficulty, timeSeconds, platform)
-	}
+	_, err := db.upsert.Exec(mode, name, replayID, score, difficulty, drones, timeSeconds, platform)
 	return err
 }
 
 func (db *seasonDB) PlayerScore(mode, name string) int {
 	var result int
-	var err error
-	switch mode {
-	case "classic":
-		err = db.classicPlayerScore.QueryRow(name).Scan(&result)
-	case "blitz":
-		err = db.blitzPlayerScore.QueryRow(name).Scan(&result)
-	case "arena":
-		err = db.arenaPlayerScore.QueryRow(name).Scan(&result)
-	case "inf_arena":
-		err = db.infArenaPlayerScore.QueryRow(name).Scan(&result)
-	case "reverse":
-		err = db.reversePlayerScore.QueryRow(name).Scan(&result)
-	}
-	if err != nil {
+	if err := db.playerScore.QueryRow(mode, name).Scan(&result); err != nil {
 		return -1
 	}
 	return result
 }
 
-func (db *seasonDB) AllScores(mode string) ([]serverapi.LeaderboardEntry, error) {
-	var rows *sql.Rows
-	var err error
-	switch mode {
-	case "classic":
-		rows, err = db.classicFetchAll.Query()
-	case "blitz":
-		rows, err = db.blitzFetchAll.Query()
-	case "arena":
-		rows, err = db.arenaFetchAll.Query()
-	case "inf_arena":
-		rows, err = db.infArenaFetchAll.Query()
-	case "reverse":
-		rows, err = db.reverseFetchAll.Query()
+// PlayerReplayID returns the archived replay ID for the player's best score.
+// A zero ID means that this replay was not archived.
+func (db *seasonDB) PlayerReplayID(mode, name string) (int, error) {
+	var result int
+	err := db.playerReplayID.QueryRow(mode, name).Scan(&result)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return result, err
+}
+
+// PlayerEntries returns the player's results for every game mode of this season.
+func (db *seasonDB) PlayerEntries(name string) ([]serverapi.PlayerProfileEntry, error) {
+	rows, err := db.playerEntries.Query(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []serverapi.PlayerProfileEntry
+	for rows.Next() {
+		e := serverapi.PlayerProfileEntry{Season: db.id}
+		e.PlayerName = name
+		var replayID int
+		err := rows.Scan(&e.Mode, &e.Score, &e.Difficulty, &e.Time, &e.Drones, &e.Platform, &replayID, &e.Rank)
+		if err != nil {
+			return nil, err
+		}
+		e.HasReplay = replayID != 0
+		entries = append(entries, e)
 	}
+	return entries, rows.Err()
+}
+
+func (db *seasonDB) AllScores(mode string) ([]serverapi.LeaderboardEntry, error) {
+	rows, err := db.fetchAll.Query(mode)
 	if err != nil {
 		return nil, err
 	}
@@ -299,18 +183,12 @@ func (db *seasonDB) AllScores(mode string) ([]serverapi.LeaderboardEntry, error)
 	entries := make([]serverapi.LeaderboardEntry, 0, 512)
 	for rows.Next() {
 		var e serverapi.LeaderboardEntry
-		var err error
-		switch mode {
-		case "classic", "blitz", "inf_arena":
-			err = rows.Scan(&e.PlayerName, &e.Score, &e.Difficulty, &e.Drones, &e.Time, &e.Platform)
-		case "arena":
-			err = rows.Scan(&e.PlayerName, &e.Score, &e.Difficulty, &e.Drones, &e.Platform)
-		case "reverse":
-			err = rows.Scan(&e.PlayerName, &e.Score, &e.Difficulty, &e.Time, &e.Platform)
-		}
+		var replayID int
+		err := rows.Scan(&e.PlayerName, &e.Score, &e.Difficulty, &e.Time, &e.Drones, &e.Platform, &replayID)
 		if err != nil {
 			return nil, err
 		}
+		e.HasReplay = replayID != 0
 		entries = append(entries, e)
 	}
 	if err = rows.Err(); err != nil {
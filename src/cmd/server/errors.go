@@ -1,6 +1,10 @@
 package main
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/quasilyte/roboden-game/serverapi"
+)
 
 var (
 	errBadParams        = errors.New("bad params")
@@ -8,8 +12,14 @@ var (
 	errBadHTTPMethod    = errors.New("bad method")
 	errQueueIsFull      = errors.New("queue is full")
 	errUnsupportedBuild = errors.New("unsupported game build")
+	errBadStamp         = errors.New("missing or invalid proof-of-work stamp")
+
+	errZeroLevelGenChecksum = errors.New("replay has a zero levelgen checksum")
 )
 
+// archiveReason values are stored in the database.
+// Never reorder them; add the new reasons to the end.
+// The serverutil archive reason names should be in sync with this list.
 type archiveReason int
 
 const (
@@ -18,4 +28,39 @@ const (
 	archiveMismatchingResults
 	archiveInvalidSeason
 	archiveExecError
+	archiveIllegalAction
+	archiveBadCheckpoint
 )
+
+// metricsLabel returns a reason name suitable for the metrics labels.
+func (r archiveReason) metricsLabel() string {
+	switch r {
+	case archiveUnsupportedBuild:
+		return "unsupported_build"
+	case archiveMismatchingResults:
+		return "mismatching_results"
+	case archiveInvalidSeason:
+		return "invalid_season"
+	case archiveExecError:
+		return "exec_error"
+	case archiveIllegalAction:
+		return "illegal_action"
+	case archiveBadCheckpoint:
+		return "bad_checkpoint"
+	default:
+		return "unknown"
+	}
+}
+
+// archiveReasonForFailure maps the simulation failure kind to the archive reason.
+// The failure kind itself is stored alongside the reason.
+func archiveReasonForFailure(kind string) archiveReason {
+	switch kind {
+	case serverapi.FailureIllegalAction:
+		return archiveIllegalAction
+	case serverapi.FailureBadCheckpoint:
+		return archiveBadCheckpoint
+	default:
+		return archiveExecError
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+// The score challenges are stateless: the nonce carries its difficulty
+// and expiration time, signed by the server secret.
+// The secret is generated on every server start, so a restart
+// invalidates all challenges that were issued before it.
+//
+// A solved challenge can be submitted more than once,
+// but it's bound to the replay checksum and the duplicated
+// checksums are rejected by the queue anyway.
+
+const scoreChallengeTTL = 10 * 60 // In seconds
+
+// scoreChallengeDifficulty returns the number of zero bits required
+// from the new submissions. A busy queue makes the spamming more expensive.
+func (s *apiServer) scoreChallengeDifficulty(queueSize int) int {
+	if queueSize >= maxQueueSize {
+		return s.powMaxDifficulty
+	}
+	extra := (s.powMaxDifficulty - s.powMinDifficulty) * queueSize / maxQueueSize
+	return s.powMinDifficulty + extra
+}
+
+func (s *apiServer) NewScoreChallenge(checksum string, difficulty int, now int64) serverapi.ScoreChallengeResp {
+	payload := strconv.Itoa(difficulty) + "." + strconv.FormatInt(now+scoreChallengeTTL, 10)
+	return serverapi.ScoreChallengeResp{
+		Nonce:      payload + "." + s.scoreChallengeSignature(checksum, payload),
+		Difficulty: difficulty,
+
+		BinaryReplays: true,
+	}
+}
+
+func (s *apiServer) CheckScoreStamp(checksum, nonce string, counter uint64, now int64) bool {
+	lastDot := strings.LastIndexByte(nonce, '.')
+	if lastDot == -1 {
+		return false
+	}
+	payload := nonce[:lastDot]
+	signature := nonce[lastDot+1:]
+	if !hmac.Equal([]byte(signature), []byte(s.scoreChallengeSignature(checksum, payload))) {
+		return false
+	}
+
+	// The signature is valid, so the payload is known to be well-formed.
+	difficultyString, expiresString, _ := strings.Cut(payload, ".")
+	difficulty, err := strconv.Atoi(difficultyString)
+	if err != nil {
+		return false
+	}
+	expires, err := strconv.ParseInt(expiresString, 10, 64)
+	if err != nil {
+		return false
+	}
+	if now > expires {
+		return false
+	}
+
+	return serverapi.CheckHashcash(checksum, nonce, counter, difficulty)
+}
+
+func (s *apiServer) scoreChallengeSignature(checksum, payload string) string {
+	mac := hmac.New(sha256.New, s.powSecret)
+	mac.Write([]byte(checksum))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
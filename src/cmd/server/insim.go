@@ -0,0 +1,46 @@
+//go:build inprocsim
+
+package main
+
+import (
+	"github.com/quasilyte/roboden-game/runsim"
+	"github.com/quasilyte/roboden-game/serverapi"
+	"github.com/quasilyte/roboden-game/session"
+)
+
+// The in-process simulation links the whole game into the server binary,
+// so it's only available in the builds with the inprocsim tag:
+//
+//	go build -tags inprocsim ./cmd/server
+//
+// Such a build needs everything the game build needs (cgo, X11 and ALSA headers).
+const inProcessSimAvailable = true
+
+type replaySimulator struct {
+	// state is created on demand, so the workers that only run
+	// runsim binaries don't load the game assets.
+	state *session.State
+}
+
+func (w *replayWorker) simulate(replay serverapi.GameReplay) (serverapi.GameResults, *serverapi.SimulationFailure, error) {
+	if replay.LevelGenChecksum == 0 {
+		failure := &serverapi.SimulationFailure{
+			Kind:    serverapi.FailureBadReplay,
+			Message: errZeroLevelGenChecksum.Error(),
+		}
+		return serverapi.GameResults{}, failure, errZeroLevelGenChecksum
+	}
+	if w.sim.state == nil {
+		w.sim.state = runsim.NewState(runsim.NewContext())
+	}
+	result, err := runsim.RunReplay(w.sim.state, replay, replayTimeoutSeconds(&replay))
+	if result.Desync != nil {
+		w.server.logger.Info("worker %d: state desync at tick %d (%s)", w.id, result.Desync.Tick, result.Desync.Subsystem)
+	}
+	if err != nil {
+		// A failed simulation could leave the state in a weird condition.
+		w.sim.state = nil
+		return result.Results, runsim.DescribeFailure(err), err
+	}
+	return result.Results, nil, nil
+}
@@ -0,0 +1,19 @@
+//go:build !inprocsim
+
+package main
+
+import (
+	"errors"
+
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+// This build can only verify the replays by running the runsim binaries.
+// See insim.go.
+const inProcessSimAvailable = false
+
+type replaySimulator struct{}
+
+func (w *replayWorker) simulate(replay serverapi.GameReplay) (serverapi.GameResults, *serverapi.SimulationFailure, error) {
+	return serverapi.GameResults{}, nil, errors.New("the server is built without the inprocsim tag")
+}
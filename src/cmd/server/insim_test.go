@@ -0,0 +1,76 @@
+//go:build inprocsim
+
+package main
+
+import (
+	"testing"
+
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/runsim"
+	"github.com/quasilyte/roboden-game/scenes/staging"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+// recordTestReplay plays a game without the player actions
+// and returns its replay, just like the game client would do.
+func recordTestReplay(t *testing.T) serverapi.GameReplay {
+	t.Helper()
+
+	// An idle colony can't win an infinite arena,
+	// the hardest settings make the game end sooner.
+	replay := newTestReplay(0)
+	replay.Config.RawGameMode = "inf_arena"
+	replay.Config.CreepDifficulty = 13
+	replay.Config.ArenaProgression = 7
+	replay.Config.DifficultyScore = gamedata.CalcDifficultyScore(replay.Config, 0)
+
+	state := runsim.NewState(runsim.NewContext())
+	config := gamedata.MakeLevelConfig(gamedata.ExecuteSimulation, replay.Config)
+	config.Finalize()
+	controller := staging.NewController(state, config, nil)
+	controller.SetReplayActions(replay)
+	results, err := runsim.Run(state, 0, replayTimeoutSeconds(&replay), controller)
+	if err != nil {
+		t.Fatalf("record a replay: %v", err)
+	}
+	replay.Results = results
+	replay.LevelGenChecksum = controller.GetLevelGenChecksum()
+	return replay
+}
+
+func TestInProcessReplayVerification(t *testing.T) {
+	s := newTestServer(t)
+	s.inProcessSim = true
+	w := newReplayWorker(s, 0, 1)
+
+	replay := recordTestReplay(t)
+	if !gamedata.IsSendableReplay(replay) {
+		t.Fatalf("the recorded replay can't be sent: %+v", replay.Results)
+	}
+	resp := submitTestReplay(t, s, "alice", replay)
+	if !resp.Queued {
+		t.Fatal("the replay is not queued")
+	}
+
+	replayed, err := s.doRunReplay(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !replayed {
+		t.Fatal("expected a replay to be executed")
+	}
+	if w.sim.state == nil {
+		t.Fatal("the replay was not simulated in-process")
+	}
+
+	if err := s.reloadLeaderboard(s.leaderboards["inf_arena"]); err != nil {
+		t.Fatal(err)
+	}
+	entries, numEntries := s.BoardPage("inf_arena", &boardFilter{}, 0, 10)
+	if numEntries != 1 {
+		t.Fatalf("the board has %d entries, want 1", numEntries)
+	}
+	if e := entries[0]; e.PlayerName != "alice" || e.Rank != 1 || e.Score != replay.Results.Score {
+		t.Fatalf("unexpected board entry: %+v", e)
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"sync"
 	"syscall"
 	"time"
+
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/serverapi"
 )
 
 func main() {
@@ -30,11 +33,15 @@ func main() {
 
 	mux := http.NewServeMux()
 	config := serverConfig{
-		runsimFolder: args.simulatorsFolder,
-		httpHandler:  mux,
-		dataFolder:   args.dataFolder,
-		logger:       l,
-		metricsFile:  args.metricsFile,
+		runsimFolder:     args.simulatorsFolder,
+		numReplayWorkers: args.replayWorkers,
+		inProcessSim:     args.inProcessSim,
+		powMinDifficulty: args.powMinDifficulty,
+		powMaxDifficulty: args.powMaxDifficulty,
+		httpHandler:      mux,
+		dataFolder:       args.dataFolder,
+		logger:           l,
+		metricsFile:      args.metricsFile,
 	}
 	server := newAPIServer(config)
 
@@ -63,8 +70,12 @@ func main() {
 
 	mux.HandleFunc("/version", server.NewHandler(h.HandleVersion))
 	mux.HandleFunc("/get-player-board", server.NewHandler(h.HandleGetPlayerBoard))
+	mux.HandleFunc("/get-player-profile", server.NewHandler(h.HandleGetPlayerProfile))
 	mux.HandleFunc("/get-board", server.NewHandler(h.HandleGetBoard))
+	mux.HandleFunc("/get-score-challenge", server.NewHandler(h.HandleGetScoreChallenge))
 	mux.HandleFunc("/save-player-score", server.NewHandler(h.HandleSavePlayerScore))
+	mux.HandleFunc("/get-replay", server.NewHandler(h.HandleGetReplay))
+	mux.HandleFunc("/metrics", h.HandleMetrics)
 
 	l.Info("starting server, listenning to %s", args.listenAddr)
 
@@ -98,6 +109,10 @@ type cliArguments struct {
 	metricsFile      string
 	logFile          string
 	simulatorsFolder string
+	replayWorkers    int
+	inProcessSim     bool
+	powMinDifficulty int
+	powMaxDifficulty int
 }
 
 func parseCLIArgs() *cliArguments {
@@ -107,6 +122,14 @@ func parseCLIArgs() *cliArguments {
 		"net listen address")
 	flag.StringVar(&args.simulatorsFolder, "simulators-folder", "",
 		"where to find roboden game simulators for replay validation")
+	flag.IntVar(&args.replayWorkers, "replay-workers", 1,
+		"how many replays can be validated in parallel")
+	flag.BoolVar(&args.inProcessSim, "inprocess-sim", false,
+		"validate the current build replays without running the simulator binaries; requires the inprocsim build tag")
+	flag.IntVar(&args.powMinDifficulty, "pow-min-difficulty", 16,
+		"score submission proof-of-work difficulty (in bits) for the empty queue")
+	flag.IntVar(&args.powMaxDifficulty, "pow-max-difficulty", 22,
+		"score submission proof-of-work difficulty (in bits) for the full queue")
 	flag.StringVar(&args.dataFolder, "data-folder", "",
 		"path to a sqlite databases folder")
 	flag.StringVar(&args.metricsFile, "metrics", "metrics.json",
@@ -116,5 +139,15 @@ func parseCLIArgs() *cliArguments {
 
 	flag.Parse()
 
+	if args.inProcessSim && !inProcessSimAvailable {
+		panic("--inprocess-sim requires a server built with -tags inprocsim")
+	}
+	if args.replayWorkers < 1 {
+		args.replayWorkers = 1
+	}
+	// Clients refuse to solve the challenges that are too hard.
+	args.powMaxDifficulty = gmath.Clamp(args.powMaxDifficulty, 0, serverapi.MaxHashcashDifficulty)
+	args.powMinDifficulty = gmath.Clamp(args.powMinDifficulty, 0, args.powMaxDifficulty)
+
 	return &args
 }
@@ -1,11 +1,37 @@
 package main
 
 import (
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type serverMetrics struct {
 	data metricsData
+
+	startTime time.Time
+
+	// The metrics below are only available via the /metrics endpoint.
+	// They're protected by mu.
+	mu                 sync.Mutex
+	simulationDuration *histogram
+	queueDepth         *histogram
+	queueWait          *histogram
+	replaysArchived    map[archiveReason]int64
+	submissionsByMode  map[string]int64
+	submissionsByBuild map[int]int64
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{
+		startTime:          time.Now(),
+		simulationDuration: newHistogram([]float64{0.5, 1, 2, 5, 10, 20, 30, 60}),
+		queueDepth:         newHistogram([]float64{0, 1, 2, 5, 10, 25, 50, 100, 250, 512}),
+		queueWait:          newHistogram([]float64{5, 15, 30, 60, 5 * 60, 15 * 60, 60 * 60, 3 * 60 * 60, 12 * 60 * 60}),
+		replaysArchived:    make(map[archiveReason]int64),
+		submissionsByMode:  make(map[string]int64),
+		submissionsByBuild: make(map[int]int64),
+	}
 }
 
 type metricsData struct {
@@ -17,13 +43,19 @@ type metricsData struct {
 	NumReqErrors       int64
 	ReqGetPlayerBoard  int64
 	ReqGetBoard        int64
+	ReqGetProfile      int64
 	ReqSavePlayerScore int64
+	ReqGetChallenge    int64
+	ReqGetReplay       int64
+	ReqMetrics         int64
 	ReqVersion         int64
 
 	NumReplaysQueued    int64
 	NumReplaysCompleted int64
 	NumReplaysFailed    int64
 	NumReplaysRejected  int64
+	NumReplaysRequeued  int64
+	NumBadStamps        int64
 }
 
 func (m *serverMetrics) IncNumReplaysQueued() {
@@ -42,6 +74,45 @@ func (m *serverMetrics) IncNumReplaysRejected() {
 	atomic.AddInt64(&m.data.NumReplaysRejected, 1)
 }
 
+func (m *serverMetrics) IncNumReplaysRequeued() {
+	atomic.AddInt64(&m.data.NumReplaysRequeued, 1)
+}
+
+func (m *serverMetrics) IncNumBadStamps() {
+	atomic.AddInt64(&m.data.NumBadStamps, 1)
+}
+
+func (m *serverMetrics) IncNumReplaysArchived(reason archiveReason) {
+	m.mu.Lock()
+	m.replaysArchived[reason]++
+	m.mu.Unlock()
+}
+
+func (m *serverMetrics) IncNumSubmissions(mode string, build int) {
+	m.mu.Lock()
+	m.submissionsByMode[mode]++
+	m.submissionsByBuild[build]++
+	m.mu.Unlock()
+}
+
+func (m *serverMetrics) ObserveSimulationDuration(d time.Duration) {
+	m.mu.Lock()
+	m.simulationDuration.Observe(d.Seconds())
+	m.mu.Unlock()
+}
+
+func (m *serverMetrics) ObserveQueueDepth(size int) {
+	m.mu.Lock()
+	m.queueDepth.Observe(float64(size))
+	m.mu.Unlock()
+}
+
+func (m *serverMetrics) ObserveQueueWait(d time.Duration) {
+	m.mu.Lock()
+	m.queueWait.Observe(d.Seconds())
+	m.mu.Unlock()
+}
+
 func (m *serverMetrics) IncNumReqErrors() {
 	atomic.AddInt64(&m.data.NumReqErrors, 1)
 }
@@ -58,6 +129,22 @@ func (m *serverMetrics) IncReqSavePlayerScore() {
 	atomic.AddInt64(&m.data.ReqSavePlayerScore, 1)
 }
 
+func (m *serverMetrics) IncReqGetChallenge() {
+	atomic.AddInt64(&m.data.ReqGetChallenge, 1)
+}
+
+func (m *serverMetrics) IncReqGetPlayerProfile() {
+	atomic.AddInt64(&m.data.ReqGetProfile, 1)
+}
+
+func (m *serverMetrics) IncReqGetReplay() {
+	atomic.AddInt64(&m.data.ReqGetReplay, 1)
+}
+
+func (m *serverMetrics) IncReqMetrics() {
+	atomic.AddInt64(&m.data.ReqMetrics, 1)
+}
+
 func (m *serverMetrics) IncReqVersion() {
 	atomic.AddInt64(&m.data.ReqVersion, 1)
 }
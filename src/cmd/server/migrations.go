@@ -0,0 +1,236 @@
+package main
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/sqliteutil"
+)
+
+// The migrations are applied by the server on startup.
+// Only append new migrations to the end of these lists.
+//
+// The first migrations are written to handle the databases that
+// were created from the _schema files before the versioning was added.
+//
+// The queue database schema is only defined by these migrations;
+// the scores table is defined by the embedded _schema/scores.sql.
+
+var queueMigrations = []sqliteutil.Migration{
+	sqliteutil.Exec("initial schema", `
+		CREATE TABLE IF NOT EXISTS replay_checksums (
+			replay_hash TEXT NOT NULL PRIMARY KEY,
+			player_name TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS replay_queue (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			player_name TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			replay_json BLOB NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS replay_queue_player_name_index
+		ON replay_queue(player_name);
+
+		CREATE TABLE IF NOT EXISTS good_replay_archive (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			replay_id INTEGER NOT NULL,
+			player_name TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			replay_json BLOB NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS failed_replay_archive (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			replay_id INTEGER NOT NULL,
+			player_name TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			replay_json BLOB NOT NULL,
+			fail_reason INTEGER NOT NULL
+		);
+	`),
+
+	{
+		Name:  "replay_queue claimed_at column",
+		Apply: addColumnIfMissing("replay_queue", "claimed_at", "INTEGER NOT NULL DEFAULT 0"),
+	},
+
+	{
+		Name:  "failed_replay_archive fail_kind column",
+		Apply: addColumnIfMissing("failed_replay_archive", "fail_kind", "TEXT NOT NULL DEFAULT ''"),
+	},
+	{
+		Name:  "failed_replay_archive fail_details column",
+		Apply: addColumnIfMissing("failed_replay_archive", "fail_details", "TEXT"),
+	},
+	{
+		Name:  "failed_replay_archive fail_kind backfill",
+		Apply: backfillFailKind,
+	},
+
+	{
+		Name:  "failed_replay_archive game_version column",
+		Apply: addColumnIfMissing("failed_replay_archive", "game_version", "INTEGER NOT NULL DEFAULT 0"),
+	},
+	{
+		Name:  "failed_replay_archive game_version backfill",
+		Apply: backfillArchiveGameVersion,
+	},
+	sqliteutil.Exec("failed_replay_archive requeue index", `
+		CREATE INDEX IF NOT EXISTS failed_replay_archive_requeue_index
+		ON failed_replay_archive(fail_reason, game_version)
+	`),
+}
+
+var seasonMigrations = []sqliteutil.Migration{
+	{
+		Name:  "shared scores table",
+		Apply: createScoresTable,
+	},
+}
+
+func addColumnIfMissing(table, column, columnDef string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		columns, err := sqliteutil.TableColumns(tx, table)
+		if err != nil {
+			return err
+		}
+		if columns[column] {
+			return nil
+		}
+		_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, columnDef))
+		return err
+	}
+}
+
+// backfillFailKind sets the fail_kind of the replays archived
+// before the failure kinds were introduced; the archive reason name is used.
+//
+// The reason names are copied here as they were at the time of
+// this migration, so renaming a reason later doesn't change its result.
+func backfillFailKind(tx *sql.Tx) error {
+	kinds := [...]string{
+		0: "unknown",
+		1: "unsupported_build",
+		2: "mismatching_results",
+		3: "invalid_season",
+		4: "exec_error",
+		5: "illegal_action",
+		6: "bad_checkpoint",
+	}
+	for reason, kind := range kinds {
+		_, err := tx.Exec("UPDATE failed_replay_archive SET fail_kind = ? WHERE fail_reason = ? AND fail_kind = ''",
+			kind, reason)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillArchiveGameVersion sets the game_version of the replays
+// archived before the column was added.
+// The malformed replays keep the zero version.
+func backfillArchiveGameVersion(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, replay_json FROM failed_replay_archive")
+	if err != nil {
+		return err
+	}
+	versions := make(map[int]int)
+	for rows.Next() {
+		var id int
+		var compressedData []byte
+		if err := rows.Scan(&id, &compressedData); err != nil {
+			rows.Close()
+			return err
+		}
+		data, err := gzipUncompress(compressedData)
+		if err != nil {
+			continue
+		}
+		var replay struct {
+			GameVersion int `json:"game_version"`
+		}
+		if err := json.Unmarshal(data, &replay); err != nil {
+			continue
+		}
+		versions[id] = replay.GameVersion
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, version := range versions {
+		_, err := tx.Exec("UPDATE failed_replay_archive SET game_version = ? WHERE id = ?", version, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scoresSchema is the only definition of the shared scores table.
+//
+//go:embed _schema/scores.sql
+var scoresSchema string
+
+// createScoresTable creates the shared scores table if it doesn't exist yet.
+//
+// The older season databases used a separate "<mode>_scores" table per
+// game mode and their columns differ from season to season (see _schema/season*.sql).
+// These tables are imported into the new scores table;
+// the columns that don't exist in a legacy table get their defaults.
+func createScoresTable(tx *sql.Tx) error {
+	exists, err := sqliteutil.TableExists(tx, "scores")
+	if err != nil || exists {
+		return err
+	}
+
+	if _, err := tx.Exec(scoresSchema); err != nil {
+		return err
+	}
+
+	modes := make([]string, 0, len(gamedata.GameModeInfoMap))
+	for mode := range gamedata.GameModeInfoMap {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+
+	for _, mode := range modes {
+		legacyTable := mode + "_scores"
+		columns, err := sqliteutil.TableColumns(tx, legacyTable)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		selectList := []string{"player_name", "score", "difficulty"}
+		for _, optional := range []string{"replay_id", "time_seconds", "drones", "platform"} {
+			if columns[optional] {
+				selectList = append(selectList, optional)
+			} else if optional == "time_seconds" {
+				selectList = append(selectList, "0")
+			} else {
+				selectList = append(selectList, "NULL")
+			}
+		}
+		q := fmt.Sprintf(`
+			INSERT INTO scores
+				('mode', 'player_name', 'score', 'difficulty', 'replay_id', 'time_seconds', 'drones', 'platform')
+			SELECT ?, %s FROM %s
+		`, strings.Join(selectList, ", "), legacyTable)
+		if _, err := tx.Exec(q, mode); err != nil {
+			return fmt.Errorf("import %s: %w", legacyTable, err)
+		}
+	}
+
+	return nil
+}
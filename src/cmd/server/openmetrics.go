@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// histogram is a minimal Prometheus-style cumulative histogram.
+// It's not synchronized, the owner should protect it.
+type histogram struct {
+	bounds []float64
+	counts []uint64 // len(bounds)+1, the last one is +Inf
+	sum    float64
+	total  uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+func (h *histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i]++
+	h.sum += v
+	h.total++
+}
+
+// metricsWriter renders the metrics in the Prometheus text exposition format.
+// See https://prometheus.io/docs/instrumenting/exposition_formats/
+type metricsWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *metricsWriter) header(name, kind, help string) {
+	fmt.Fprintf(&w.buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(&w.buf, "# TYPE %s %s\n", name, kind)
+}
+
+func (w *metricsWriter) value(name, labels string, v float64) {
+	w.buf.WriteString(name)
+	if labels != "" {
+		w.buf.WriteByte('{')
+		w.buf.WriteString(labels)
+		w.buf.WriteByte('}')
+	}
+	w.buf.WriteByte(' ')
+	w.buf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	w.buf.WriteByte('\n')
+}
+
+func (w *metricsWriter) Counter(name, help string, v int64) {
+	w.header(name, "counter", help)
+	w.value(name, "", float64(v))
+}
+
+func (w *metricsWriter) Gauge(name, help string, v float64) {
+	w.header(name, "gauge", help)
+	w.value(name, "", v)
+}
+
+func (w *metricsWriter) Histogram(name, help string, h *histogram) {
+	w.header(name, "histogram", help)
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		le := strconv.FormatFloat(bound, 'g', -1, 64)
+		w.value(name+"_bucket", `le="`+le+`"`, float64(cumulative))
+	}
+	w.value(name+"_bucket", `le="+Inf"`, float64(h.total))
+	w.value(name+"_sum", "", h.sum)
+	w.value(name+"_count", "", float64(h.total))
+}
+
+func (m *serverMetrics) OpenMetrics() []byte {
+	var w metricsWriter
+
+	w.Gauge("roboden_uptime_seconds", "Time since the server start.",
+		time.Since(m.startTime).Seconds())
+
+	w.header("roboden_requests_total", "counter", "Handled API requests.")
+	requests := []struct {
+		endpoint string
+		counter  *int64
+	}{
+		{"get_player_board", &m.data.ReqGetPlayerBoard},
+		{"get_board", &m.data.ReqGetBoard},
+		{"get_player_profile", &m.data.ReqGetProfile},
+		{"save_player_score", &m.data.ReqSavePlayerScore},
+		{"get_score_challenge", &m.data.ReqGetChallenge},
+		{"get_replay", &m.data.ReqGetReplay},
+		{"metrics", &m.data.ReqMetrics},
+		{"version", &m.data.ReqVersion},
+	}
+	for _, r := range requests {
+		w.value("roboden_requests_total", `endpoint="`+r.endpoint+`"`, float64(atomic.LoadInt64(r.counter)))
+	}
+	w.Counter("roboden_request_errors_total", "API requests that ended up with an error.",
+		atomic.LoadInt64(&m.data.NumReqErrors))
+
+	w.Counter("roboden_replays_queued_total", "Replays added to the verification queue.",
+		atomic.LoadInt64(&m.data.NumReplaysQueued))
+	w.Counter("roboden_replays_completed_total", "Replays that were simulated successfully.",
+		atomic.LoadInt64(&m.data.NumReplaysCompleted))
+	w.Counter("roboden_replays_failed_total", "Replays that failed the verification.",
+		atomic.LoadInt64(&m.data.NumReplaysFailed))
+	w.Counter("roboden_replays_rejected_total", "Replays rejected due to the full queue.",
+		atomic.LoadInt64(&m.data.NumReplaysRejected))
+	w.Counter("roboden_bad_stamps_total", "Score submissions rejected due to the invalid proof-of-work.",
+		atomic.LoadInt64(&m.data.NumBadStamps))
+	w.Counter("roboden_replays_requeued_total", "Archived replays moved back to the queue.",
+		atomic.LoadInt64(&m.data.NumReplaysRequeued))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.header("roboden_replays_archived_total", "counter", "Failed replays moved to the archive, by reason.")
+	for reason := archiveUnknown; reason <= archiveBadCheckpoint; reason++ {
+		w.value("roboden_replays_archived_total", `reason="`+reason.metricsLabel()+`"`, float64(m.replaysArchived[reason]))
+	}
+
+	w.header("roboden_submissions_total", "counter", "Accepted score submissions, by game mode.")
+	modes := make([]string, 0, len(m.submissionsByMode))
+	for mode := range m.submissionsByMode {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	for _, mode := range modes {
+		w.value("roboden_submissions_total", `mode="`+mode+`"`, float64(m.submissionsByMode[mode]))
+	}
+
+	w.header("roboden_build_submissions_total", "counter", "Accepted score submissions, by game build.")
+	builds := make([]int, 0, len(m.submissionsByBuild))
+	for build := range m.submissionsByBuild {
+		builds = append(builds, build)
+	}
+	sort.Ints(builds)
+	for _, build := range builds {
+		w.value("roboden_build_submissions_total", `build="`+strconv.Itoa(build)+`"`, float64(m.submissionsByBuild[build]))
+	}
+
+	w.Histogram("roboden_simulation_duration_seconds", "Replay simulation time.", m.simulationDuration)
+	w.Histogram("roboden_queue_depth", "Replay queue size observed by the score submissions.", m.queueDepth)
+	w.Histogram("roboden_queue_wait_seconds", "Time replays spend in the queue before being simulated.", m.queueWait)
+
+	return w.buf.Bytes()
+}
@@ -7,6 +7,10 @@ import (
 	"github.com/quasilyte/roboden-game/serverapi"
 )
 
+// maxQueueSize is a soft limit for the replay queue size.
+// New submissions are rejected when the queue has more entries than that.
+const maxQueueSize = 512
+
 type replayQueue struct {
 	conn *sql.DB
 
@@ -15,10 +19,13 @@ type replayQueue struct {
 	countStmt            *sql.Stmt
 	countForPlayer       *sql.Stmt
 	pushStmt             *sql.Stmt
-	selectNextStmt       *sql.Stmt
+	claimNextStmt        *sql.Stmt
+	resetClaimsStmt      *sql.Stmt
+	releaseStmt          *sql.Stmt
 	deleteByIDStmt       *sql.Stmt
 	addToArchiveStmt     *sql.Stmt
 	addToGoodArchiveStmt *sql.Stmt
+	goodArchiveReplay    *sql.Stmt
 }
 
 func newReplayQueue(conn *sql.DB) *replayQueue {
@@ -81,16 +88,48 @@ func (q *replayQueue) PrepareQueries() error {
 	}
 
 	{
+		// A single UPDATE statement is atomic, so two workers
+		// can't claim the same replay.
 		stmt, err := q.conn.Prepare(`
-			SELECT id, player_name, replay_json
-			FROM replay_queue
-			ORDER BY id
-			LIMIT 1
+			UPDATE replay_queue
+			SET claimed_at = ?
+			WHERE id = (
+				SELECT id
+				FROM replay_queue
+				WHERE claimed_at = 0
+				ORDER BY id
+				LIMIT 1
+			)
+			RETURNING id, player_name, created_at, replay_json
 		`)
 		if err != nil {
 			return err
 		}
-		q.selectNextStmt = stmt
+		q.claimNextStmt = stmt
+	}
+
+	{
+		stmt, err := q.conn.Prepare(`
+			UPDATE replay_queue
+			SET claimed_at = 0
+			WHERE claimed_at != 0
+		`)
+		if err != nil {
+			return err
+		}
+		q.resetClaimsStmt = stmt
+	}
+
+	{
+		stmt, err := q.conn.Prepare(`
+			UPDATE replay_queue
+			SET claimed_at = 0
+			WHERE id = ?
+		`)
+		if err != nil {
+			return err
+		}
+		q.releaseStmt = stmt
 	}
 
 	{
@@ -107,8 +146,8 @@ func (q *replayQueue) PrepareQueries() error {
 	{
 		stmt, err := q.conn.Prepare(`
 			INSERT INTO failed_replay_archive
-			       ('replay_id', 'player_name', 'created_at', 'replay_json', 'fail_reason')
-			VALUES (?, ?, ?, ?, ?)
+			       ('replay_id', 'player_name', 'created_at', 'replay_json', 'game_version', 'fail_reason', 'fail_kind', 'fail_details')
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
@@ -130,6 +169,18 @@ func (q *replayQueue) PrepareQueries() error {
 		}
 	}
 
+	{
+		stmt, err := q.conn.Prepare(`
+			SELECT replay_json
+			FROM good_replay_archive
+			WHERE replay_id = ?
+		`)
+		if err != nil {
+			return err
+		}
+		q.goodArchiveReplay = stmt
+	}
+
 	return nil
 }
 
@@ -147,12 +198,33 @@ func (q *replayQueue) Delete(id int, playerName string) error {
 	return err
 }
 
-func (q *replayQueue) Get() (int, string, []byte, error) {
+// Claim marks the oldest unclaimed replay as taken and returns it.
+// If there are no replays to claim, sql.ErrNoRows is returned.
+func (q *replayQueue) Claim(claimedAt int64) (int, string, int64, []byte, error) {
 	var id int
 	var playerName string
+	var createdAt int64
 	var data []byte
-	err := q.selectNextStmt.QueryRow().Scan(&id, &playerName, &data)
-	return id, playerName, data, err
+	err := q.claimNextStmt.QueryRow(claimedAt).Scan(&id, &playerName, &createdAt, &data)
+	return id, playerName, createdAt, data, err
+}
+
+// Release makes the claimed replay available again.
+// It does nothing if the replay is not in the queue anymore.
+func (q *replayQueue) Release(id int) error {
+	_, err := q.releaseStmt.Exec(id)
+	return err
+}
+
+// ResetClaims makes all claimed replays available again.
+// It's only safe to call it when no workers are running.
+func (q *replayQueue) ResetClaims() (int, error) {
+	res, err := q.resetClaimsStmt.Exec()
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
 }
 
 func (q *replayQueue) Count() (int, error) {
@@ -167,9 +239,26 @@ func (q *replayQueue) CountForPlayer(name string) (int, error) {
 	return result, err
 }
 
-func (q *replayQueue) Archive(id int, playerName string, createdAt int64, compressedData []byte, reason archiveReason) error {
+// Archive moves the replay to the failed replays archive.
+// The game version is stored separately, so the archived replays
+// can be selected by their build without decoding them
+// (it's 0 if the replay can't be decoded).
+// The failure kind is stored as fail_kind and the failure itself
+// is stored as fail_details JSON; if failure is nil,
+// the reason name is used as a kind.
+func (q *replayQueue) Archive(id int, playerName string, createdAt int64, compressedData []byte, gameVersion int, reason archiveReason, failure *serverapi.SimulationFailure) error {
+	kind := reason.metricsLabel()
+	var details sql.NullString
+	if failure != nil {
+		kind = failure.Kind
+		data, err := json.Marshal(failure)
+		if err != nil {
+			return err
+		}
+		details = sql.NullString{String: string(data), Valid: true}
+	}
 	return withTransaction(q.conn, func(tx *sql.Tx) error {
-		_, err := tx.Stmt(q.addToArchiveStmt).Exec(id, playerName, createdAt, compressedData, int(reason))
+		_, err := tx.Stmt(q.addToArchiveStmt).Exec(id, playerName, createdAt, compressedData, gameVersion, int(reason), kind, details)
 		if err != nil {
 			return err
 		}
@@ -189,6 +278,14 @@ func (q *replayQueue) GoodArchive(id int, playerName string, createdAt int64, co
 	})
 }
 
+// GoodArchiveReplay returns the compressed replay data by its ID.
+// If there is no such replay, sql.ErrNoRows is returned.
+func (q *replayQueue) GoodArchiveReplay(id int) ([]byte, error) {
+	var data []byte
+	err := q.goodArchiveReplay.QueryRow(id).Scan(&data)
+	return data, err
+}
+
 func (q *replayQueue) PushRaw(checksum, playerName string, createdAt int64, replayData []byte, compressed bool) error {
 	if !compressed {
 		compressedReplayData, err := gzipCompress(replayData)
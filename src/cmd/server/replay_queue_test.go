@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/serverapi"
+	"github.com/quasilyte/roboden-game/sqliteutil"
+)
+
+func TestReplayQueueLegacySchema(t *testing.T) {
+	conn, err := sqliteutil.Connect(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// A queue database created before the claims were added.
+	_, err = conn.Exec(`
+		CREATE TABLE replay_queue (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			player_name TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			replay_json BLOB NOT NULL
+		);
+		INSERT INTO replay_queue (player_name, created_at, replay_json)
+		VALUES ('alice', 100, x'00');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sqliteutil.Migrate(conn, queueMigrations); err != nil {
+		t.Fatal(err)
+	}
+	q := newReplayQueue(conn)
+	if err := q.PrepareQueries(); err != nil {
+		t.Fatal(err)
+	}
+	id, playerName, createdAt, _, err := q.Claim(200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 || playerName != "alice" || createdAt != 100 {
+		t.Fatalf("claimed unexpected replay: id=%d player=%q created_at=%d", id, playerName, createdAt)
+	}
+}
+
+func TestReplayWorkerFailure(t *testing.T) {
+	s := newTestServer(t)
+	w := newReplayWorker(s, 0, 1)
+
+	submitTestReplay(t, s, "alice", newTestReplay(2000))
+
+	// Make the score update fail after a successful simulation.
+	if _, err := s.getSeasonDB(currentSeason).conn.Exec("DROP TABLE scores"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.doRunReplay(w); err == nil {
+		t.Fatal("expected the replay run to fail")
+	}
+
+	// The failed replay should be available for the next attempt.
+	_, playerName, _, _, err := s.queue.Claim(time.Now().Unix())
+	if err != nil {
+		t.Fatalf("claim after a failure: %v", err)
+	}
+	if playerName != "alice" {
+		t.Fatalf("claimed %q replay, want alice", playerName)
+	}
+}
+
+func TestReplayWorkerCorruptedReplay(t *testing.T) {
+	s := newTestServer(t)
+	w := newReplayWorker(s, 0, 1)
+
+	if err := s.queue.PushRaw("checksum", "alice", time.Now().Unix(), []byte("not gzip"), true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.doRunReplay(w); err == nil {
+		t.Fatal("expected the replay run to fail")
+	}
+
+	// Retrying a corrupted replay is pointless, so it should be archived.
+	queueSize, err := s.queue.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queueSize != 0 {
+		t.Fatalf("queue size is %d, want 0", queueSize)
+	}
+	var playerName string
+	var reason archiveReason
+	err = s.queue.conn.QueryRow("SELECT player_name, fail_reason FROM failed_replay_archive").Scan(&playerName, &reason)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if playerName != "alice" || reason != archiveUnknown {
+		t.Fatalf("unexpected archived replay: player=%q reason=%v", playerName, reason)
+	}
+}
+
+func TestRequeueArchived(t *testing.T) {
+	s := newTestServer(t)
+	w := newReplayWorker(s, 0, 1)
+
+	// Both replays are archived: there are no runsim binaries for their builds.
+	for _, gameVersion := range []int{gamedata.BuildNumber - 1, gamedata.BuildNumber - 2} {
+		replay := newTestReplay(2000)
+		replay.GameVersion = gameVersion
+		replay.Config.Seed += int64(gameVersion) // Every replay needs a unique checksum
+		if err := s.queue.Push(serverapi.ReplayChecksum(&replay), "alice", time.Now().Unix(), replay); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.doRunReplay(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := s.doRequeueArchived()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.scanned != 0 || stats.requeued != 0 {
+		t.Fatalf("unexpected stats before the build is supported: %+v", stats)
+	}
+
+	// Only the supported build replays should be decoded.
+	runsimPath := s.runsimBinaryPath(gamedata.BuildNumber - 1)
+	if err := os.WriteFile(runsimPath, []byte(fakeRunsimScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = s.doRequeueArchived()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.scanned != 1 || stats.requeued != 1 {
+		t.Fatalf("unexpected stats after the build is supported: %+v", stats)
+	}
+	queueSize, err := s.queue.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queueSize != 1 {
+		t.Fatalf("queue size is %d, want 1", queueSize)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// replayWorker pulls the replays from the queue and verifies them.
+// Several workers can run in parallel; the queue makes sure that
+// every replay is claimed by only one of them.
+type replayWorker struct {
+	id     int
+	server *apiServer
+
+	// The server rand is not synchronized, so every worker
+	// has its own generator.
+	rand *rand.Rand
+
+	// sim is used for the in-process simulations, see insim.go.
+	sim replaySimulator
+}
+
+func newReplayWorker(s *apiServer, id int, seed int64) *replayWorker {
+	return &replayWorker{
+		id:     id,
+		server: s,
+		rand:   rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (w *replayWorker) intervalRunReplay() float64 {
+	return floatRange(w.rand, 5, 10)
+}
+
+func (w *replayWorker) Run() {
+	// Don't let all workers hit the queue at the same moment.
+	untilRunReplay := floatRange(w.rand, 1, 5)
+
+	for {
+		if atomic.LoadInt64(&w.server.stop) != 0 {
+			w.server.logger.Info("stopping replay worker %d", w.id)
+			return
+		}
+		secondsToSleep := 1.0 * (w.rand.Float64() + 0.5)
+		sleepStart := time.Now()
+		time.Sleep(time.Duration(secondsToSleep * float64(time.Second)))
+		secondsSlept := time.Since(sleepStart).Seconds()
+
+		untilRunReplay -= secondsSlept
+		if untilRunReplay > 0 {
+			continue
+		}
+
+		delayMultiplier := 1.0
+		replayed, err := w.server.doRunReplay(w)
+		if err != nil {
+			delayMultiplier += floatRange(w.rand, 2.5, 4)
+			w.server.logger.Error("worker %d: run replay: %v", w.id, err)
+		} else if replayed {
+			// There could be more replays waiting in the queue,
+			// try to get the next one sooner.
+			delayMultiplier = 0.1
+			w.server.logger.Info("worker %d: executed a replay", w.id)
+		} else {
+			delayMultiplier += floatRange(w.rand, 1, 2)
+		}
+		untilRunReplay = w.intervalRunReplay() * delayMultiplier
+	}
+}
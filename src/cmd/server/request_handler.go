@@ -1,11 +1,13 @@
 package main
 
 import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -37,6 +39,15 @@ func (h *requestHandler) HandleVersion(r *http.Request) (any, error) {
 	return h.versionResponse, nil
 }
 
+// HandleMetrics is not wrapped by the apiServer.NewHandler
+// as it responds with a plain text instead of JSON.
+func (h *requestHandler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	h.server.metrics.IncReqMetrics()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(h.server.metrics.OpenMetrics())
+}
+
 func (h *requestHandler) HandleGetPlayerBoard(r *http.Request) (any, error) {
 	h.server.metrics.IncReqGetPlayerBoard()
 
@@ -45,10 +56,7 @@ func (h *requestHandler) HandleGetPlayerBoard(r *http.Request) (any, error) {
 		return nil, errBadParams
 	}
 	modeParam := r.URL.Query().Get("mode")
-	switch modeParam {
-	case "classic", "blitz", "arena", "inf_arena", "reverse":
-		// OK
-	default:
+	if !h.server.IsValidMode(modeParam) {
 		return nil, errBadParams
 	}
 	playerName := r.URL.Query().Get("name")
@@ -62,6 +70,10 @@ func (h *requestHandler) HandleGetPlayerBoard(r *http.Request) (any, error) {
 		return nil, errBadParams
 	}
 
+	// Read the tag before the board: if the board is reloaded in between,
+	// the client gets the new data with the outdated tag and
+	// the next request will not match it.
+	boardETag := h.server.BoardETag(modeParam)
 	resp := &serverapi.LeaderboardResp{
 		NumSeasons: h.server.NumSeasons(),
 		NumPlayers: h.server.NumBoardPlayers(modeParam),
@@ -69,18 +81,43 @@ func (h *requestHandler) HandleGetPlayerBoard(r *http.Request) (any, error) {
 	playerName = strings.TrimSpace(playerName)
 	if playerName == "" || !gamedata.IsValidUsername(playerName) {
 		resp.Entries = h.server.Top10(modeParam)
-		return resp, nil
+		return newTaggedResp(boardETag, r, resp, resp.NumSeasons), nil
 	}
+	// The player score is not a part of the board, it's read from the database.
 	playerScore := db.PlayerScore(modeParam, playerName)
 	if playerScore == -1 {
 		resp.Entries = h.server.Top10(modeParam)
-		return resp, nil
+		return newTaggedResp(boardETag, r, resp, resp.NumSeasons, playerScore), nil
 	}
 	leaderboardEntries, err := h.server.PlayerBoard(modeParam, playerName, playerScore)
 	if err != nil {
 		return nil, err
 	}
 	resp.Entries = leaderboardEntries
+	return newTaggedResp(boardETag, r, resp, resp.NumSeasons, playerScore), nil
+}
+
+func (h *requestHandler) HandleGetPlayerProfile(r *http.Request) (any, error) {
+	h.server.metrics.IncReqGetPlayerProfile()
+
+	playerName := r.URL.Query().Get("name")
+	playerName = strings.TrimSpace(playerName)
+	if playerName == "" || !gamedata.IsValidUsername(playerName) {
+		return nil, errBadParams
+	}
+
+	resp := &serverapi.PlayerProfileResp{
+		PlayerName: playerName,
+		NumSeasons: h.server.NumSeasons(),
+		Entries:    []serverapi.PlayerProfileEntry{},
+	}
+	for i := 0; i < resp.NumSeasons; i++ {
+		entries, err := h.server.getSeasonDB(i).PlayerEntries(playerName)
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, entries...)
+	}
 	return resp, nil
 }
 
@@ -92,10 +129,7 @@ func (h *requestHandler) HandleGetBoard(r *http.Request) (any, error) {
 		return nil, errBadParams
 	}
 	modeParam := r.URL.Query().Get("mode")
-	switch modeParam {
-	case "classic", "blitz", "arena", "inf_arena", "reverse":
-		// OK
-	default:
+	if !h.server.IsValidMode(modeParam) {
 		return nil, errBadParams
 	}
 	seasonNumber, err := strconv.Atoi(seasonParam)
@@ -106,8 +140,143 @@ func (h *requestHandler) HandleGetBoard(r *http.Request) (any, error) {
 		return nil, errBadParams
 	}
 
-	board := h.server.getBoardForMode(modeParam)
-	return board.json, nil
+	// See HandleGetPlayerBoard.
+	boardETag := h.server.BoardETag(modeParam)
+
+	q := r.URL.Query()
+	paginated := false
+	for _, key := range []string{"offset", "limit", "platform", "min_difficulty", "max_difficulty", "drone"} {
+		if q.Has(key) {
+			paginated = true
+			break
+		}
+	}
+	if !paginated {
+		// The older clients (and the online leaderboard) expect the entire board.
+		return newTaggedResp(boardETag, r, h.server.BoardJSON(modeParam)), nil
+	}
+
+	offset, err := intQueryParam(q.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		return nil, errBadParams
+	}
+	limit, err := intQueryParam(q.Get("limit"), 50)
+	if err != nil || limit <= 0 || limit > serverapi.MaxBoardPageSize {
+		return nil, errBadParams
+	}
+	var filter boardFilter
+	filter.platform = q.Get("platform")
+	if filter.platform != "" && !buildinfo.IsValidTag(filter.platform) {
+		return nil, errBadParams
+	}
+	filter.minDifficulty, err = intQueryParam(q.Get("min_difficulty"), 0)
+	if err != nil {
+		return nil, errBadParams
+	}
+	filter.maxDifficulty, err = intQueryParam(q.Get("max_difficulty"), 0)
+	if err != nil {
+		return nil, errBadParams
+	}
+	// An unknown drone name is not an error, it just matches nothing.
+	filter.drone = q.Get("drone")
+
+	entries, numEntries := h.server.BoardPage(modeParam, &filter, offset, limit)
+	resp := &serverapi.BoardPageResp{
+		NumEntries: numEntries,
+		Offset:     offset,
+		Entries:    entries,
+	}
+	if resp.Entries == nil {
+		resp.Entries = []serverapi.LeaderboardEntry{}
+	}
+	return newTaggedResp(boardETag, r, resp), nil
+}
+
+func intQueryParam(s string, defaultValue int) (int, error) {
+	if s == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func (h *requestHandler) HandleGetReplay(r *http.Request) (any, error) {
+	h.server.metrics.IncReqGetReplay()
+
+	seasonParam := r.URL.Query().Get("season")
+	if seasonParam == "" {
+		return nil, errBadParams
+	}
+	modeParam := r.URL.Query().Get("mode")
+	if !h.server.IsValidMode(modeParam) {
+		return nil, errBadParams
+	}
+	playerName := r.URL.Query().Get("name")
+	playerName = strings.TrimSpace(playerName)
+	if playerName == "" || !gamedata.IsValidUsername(playerName) {
+		return nil, errBadParams
+	}
+	seasonNumber, err := strconv.Atoi(seasonParam)
+	if err != nil {
+		return nil, errBadParams
+	}
+
+	db := h.server.getSeasonDB(seasonNumber)
+	if db == nil {
+		return nil, errBadParams
+	}
+
+	replayID, err := db.PlayerReplayID(modeParam, playerName)
+	if err != nil {
+		return nil, err
+	}
+	if replayID == 0 {
+		// Either there is no such player or their replay was not archived.
+		return nil, errNotFound
+	}
+
+	compressedReplayData, err := h.server.queue.GoodArchiveReplay(replayID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errNotFound
+		}
+		return nil, err
+	}
+	// The archive keeps the data that was verified by the simulator,
+	// so it can be sent as is unless the client prefers the binary encoding.
+	replayData, err := gzipUncompress(compressedReplayData)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(r.Header.Get("Accept"), serverapi.ReplayBinaryContentType) {
+		return replayData, nil
+	}
+	var replay serverapi.GameReplay
+	if err := json.Unmarshal(replayData, &replay); err != nil {
+		return nil, err
+	}
+	binaryData, err := serverapi.MarshalReplayBinary(&replay)
+	if err != nil {
+		return nil, err
+	}
+	return binaryReplayResp(binaryData), nil
+}
+
+func (h *requestHandler) HandleGetScoreChallenge(r *http.Request) (any, error) {
+	h.server.metrics.IncReqGetChallenge()
+
+	// The checksum is hex-encoded serverapi.ReplayChecksum result.
+	checksum, err := hex.DecodeString(r.URL.Query().Get("checksum"))
+	if err != nil || len(checksum) != sha1.Size {
+		return nil, errBadParams
+	}
+
+	queueSize, err := h.server.queue.Count()
+	if err != nil {
+		return nil, err
+	}
+	difficulty := h.server.scoreChallengeDifficulty(queueSize)
+
+	return h.server.NewScoreChallenge(string(checksum), difficulty, time.Now().Unix()), nil
 }
 
 func (h *requestHandler) HandleSavePlayerScore(r *http.Request) (any, error) {
@@ -140,8 +309,20 @@ func (h *requestHandler) HandleSavePlayerScore(r *http.Request) (any, error) {
 	}
 
 	var gameReplay serverapi.GameReplay
-	if err := json.Unmarshal(data, &gameReplay); err != nil {
-		return nil, errBadParams
+	if r.Header.Get("Content-Type") == serverapi.ReplayBinaryContentType {
+		if err := serverapi.UnmarshalReplayBinary(data, &gameReplay); err != nil {
+			return nil, errBadParams
+		}
+		// The queue and the archives keep JSON replays:
+		// the older runsim binaries can't decode the binary format.
+		data, err = json.Marshal(gameReplay)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if err := json.Unmarshal(data, &gameReplay); err != nil {
+			return nil, errBadParams
+		}
 	}
 	if err := h.isValidGameReplay(gameReplay); err != nil {
 		return nil, err
@@ -155,14 +336,28 @@ func (h *requestHandler) HandleSavePlayerScore(r *http.Request) (any, error) {
 		return nil, errBadParams
 	}
 
-	// TODO: hashcash check here to deal with spammers?
+	// The proof-of-work makes the spamming more expensive.
+	// The stamp is checked before any database access.
+	replayChecksum := serverapi.ReplayChecksum(&gameReplay)
+	powCounter, err := strconv.ParseUint(r.URL.Query().Get("pow_counter"), 10, 64)
+	if err != nil {
+		h.server.metrics.IncNumBadStamps()
+		return nil, errBadStamp
+	}
+	if !h.server.CheckScoreStamp(replayChecksum, r.URL.Query().Get("pow_nonce"), powCounter, time.Now().Unix()) {
+		h.server.metrics.IncNumBadStamps()
+		h.server.logger.Info("%q sent a replay with invalid stamp", playerName)
+		return nil, errBadStamp
+	}
 
-	// Check if we have the right runsim binary for this match.
-	runsimBinaryName := filepath.Join(h.server.runsimFolder, fmt.Sprintf("runsim_%d", gameReplay.GameVersion))
-	if !fileExists(runsimBinaryName) {
+	// Check if we can simulate this match.
+	if !h.server.canSimulate(gameReplay.GameVersion) {
 		h.server.logger.Info("unsupported game build %v is requested", gameReplay.GameVersion)
 		return nil, errUnsupportedBuild
 	}
+	// The game build is known to be supported at this point,
+	// so the number of distinct labels is bounded.
+	h.server.metrics.IncNumSubmissions(gameReplay.Config.RawGameMode, gameReplay.GameVersion)
 
 	db := h.server.getSeasonDB(seasonNumber)
 
@@ -171,13 +366,13 @@ func (h *requestHandler) HandleSavePlayerScore(r *http.Request) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	if queueSize > 512 {
+	h.server.metrics.ObserveQueueDepth(queueSize)
+	if queueSize > maxQueueSize {
 		h.server.metrics.IncNumReplaysRejected()
 		h.server.logger.Info("rejected %q replay, the queue is full", playerName)
 		return nil, errQueueIsFull
 	}
 
-	replayChecksum := h.calcReplayChecksum(&gameReplay)
 	checksumOwner, err := h.server.queue.ChecksumOwner(replayChecksum)
 	if err != nil {
 		return nil, err
@@ -245,28 +440,3 @@ func (h *requestHandler) isValidGameReplay(r serverapi.GameReplay) error {
 
 	return nil
 }
-
-func (h *requestHandler) calcReplayChecksum(replay *serverapi.GameReplay) string {
-	buf := make([]byte, 0, 256)
-
-	buf = strconv.AppendInt(buf, replay.Config.Seed, 10)
-	buf = append(buf, '/')
-	buf = append(buf, replay.Config.RawGameMode...)
-	buf = append(buf, '/')
-	for _, drone := range replay.Config.Tier2Recipes {
-		buf = append(buf, drone...)
-		buf = append(buf, '$')
-	}
-	buf = append(buf, replay.Config.TurretDesign...)
-	buf = append(buf, '$')
-	buf = strconv.AppendInt(buf, int64(len(replay.Actions)), 10)
-	buf = append(buf, '@')
-	buf = strconv.AppendInt(buf, int64(replay.Results.Score), 10)
-	buf = append(buf, ':')
-	buf = strconv.AppendInt(buf, int64(replay.Results.Ticks), 10)
-	buf = append(buf, ':')
-	buf = strconv.AppendInt(buf, int64(replay.Results.Time), 10)
-	buf = append(buf, ';')
-
-	return sha1encode(buf)
-}
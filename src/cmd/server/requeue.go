@@ -0,0 +1,60 @@
+package main
+
+import (
+	"time"
+
+	"github.com/quasilyte/roboden-game/queuedb"
+)
+
+// requeueStats describes the results of the archive scan.
+type requeueStats struct {
+	scanned   int
+	requeued  int
+	malformed int
+	skipped   int
+}
+
+// doRequeueArchived moves the replays that were archived due to
+// an unsupported build back to the queue if the server can simulate
+// them now (for example, the matching runsim binary was uploaded).
+//
+// It never fills the queue more than a half, so the new submissions
+// are not rejected because of the requeued replays.
+func (s *apiServer) doRequeueArchived() (requeueStats, error) {
+	var stats requeueStats
+
+	queueSize, err := s.queue.Count()
+	if err != nil {
+		return stats, err
+	}
+	capacity := (maxQueueSize / 2) - queueSize
+	if capacity <= 0 {
+		return stats, nil
+	}
+
+	filter := &queuedb.RequeueFilter{
+		Reason:      int(archiveUnsupportedBuild),
+		CanSimulate: s.canSimulate,
+		OnSkip: func(archiveID int, reason string) {
+			s.logger.Info("skip requeue of archived id=%d: %s", archiveID, reason)
+		},
+	}
+	candidates, scanStats, err := queuedb.FindRequeueCandidates(s.queue.conn, filter, capacity)
+	stats.scanned = scanStats.Scanned
+	stats.malformed = scanStats.Malformed
+	stats.skipped = scanStats.Skipped
+	if err != nil {
+		return stats, err
+	}
+	for i := range candidates {
+		c := &candidates[i]
+		if err := queuedb.Requeue(s.queue.conn, c, time.Now().Unix()); err != nil {
+			return stats, err
+		}
+		s.logger.Info("requeued archived replay id=%d (build %d)", c.ReplayID, c.GameVersion)
+		s.metrics.IncNumReplaysRequeued()
+		stats.requeued++
+	}
+
+	return stats, nil
+}
@@ -2,9 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
+	cryptorand "crypto/rand"
+	"crypto/sha1"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"math/rand"
 	"net/http"
 	"os"
@@ -17,6 +22,8 @@ import (
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/gamedata"
 	"github.com/quasilyte/roboden-game/serverapi"
 	"github.com/quasilyte/roboden-game/sqliteutil"
 )
@@ -37,51 +44,83 @@ type apiServer struct {
 	sleepStart time.Time
 	stop       int64
 
-	runsimFolder string
+	runsimFolder     string
+	numReplayWorkers int
+	inProcessSim     bool
 
 	rand *rand.Rand
 
 	metricsFile string
 	metrics     *serverMetrics
 
-	leaderboardMu       sync.RWMutex
-	classicLeaderboard  *leaderboardData
-	blitzLeaderboard    *leaderboardData
-	arenaLeaderboard    *leaderboardData
-	infArenaLeaderboard *leaderboardData
-	reverseLeaderboard  *leaderboardData
+	// The score submission proof-of-work settings, see hashcash.go
+	powSecret        []byte
+	powMinDifficulty int
+	powMaxDifficulty int
+
+	// leaderboards has a board for every game mode from gamedata.GameModeInfoMap.
+	// The map itself is never modified after the server creation;
+	// the boards contents are protected by the leaderboardMu.
+	leaderboardMu   sync.RWMutex
+	leaderboards    map[string]*leaderboardData
+	leaderboardList []*leaderboardData
 }
 
 type leaderboardData struct {
 	mode    string
 	entries []serverapi.LeaderboardEntry
 	json    []byte
+
+	// etag identifies the board contents, it's updated on every reload.
+	etag string
+
+	// untilReload is only used by the background task.
+	untilReload float64
 }
 
 type serverConfig struct {
-	httpHandler  http.Handler
-	runsimFolder string
-	dataFolder   string
-	metricsFile  string
-	logger       logger
+	httpHandler      http.Handler
+	runsimFolder     string
+	numReplayWorkers int
+	inProcessSim     bool
+	powMinDifficulty int
+	powMaxDifficulty int
+	dataFolder       string
+	metricsFile      string
+	logger           logger
 }
 
 func newAPIServer(config serverConfig) *apiServer {
 	s := &apiServer{
-		httpHandler:  config.httpHandler,
-		dataFolder:   config.dataFolder,
-		runsimFolder: config.runsimFolder,
-		logger:       config.logger,
-		rand:         rand.New(rand.NewSource(time.Now().Unix())),
-		metrics:      &serverMetrics{},
-		metricsFile:  config.metricsFile,
-
-		classicLeaderboard:  &leaderboardData{mode: "classic"},
-		blitzLeaderboard:    &leaderboardData{mode: "blitz"},
-		arenaLeaderboard:    &leaderboardData{mode: "arena"},
-		infArenaLeaderboard: &leaderboardData{mode: "inf_arena"},
-		reverseLeaderboard:  &leaderboardData{mode: "reverse"},
+		httpHandler:      config.httpHandler,
+		dataFolder:       config.dataFolder,
+		runsimFolder:     config.runsimFolder,
+		numReplayWorkers: config.numReplayWorkers,
+		inProcessSim:     config.inProcessSim,
+		logger:           config.logger,
+		rand:             rand.New(rand.NewSource(time.Now().Unix())),
+		metrics:          newServerMetrics(),
+		metricsFile:      config.metricsFile,
+		powSecret:        make([]byte, 32),
+		powMinDifficulty: config.powMinDifficulty,
+		powMaxDifficulty: config.powMaxDifficulty,
+
+		leaderboards: make(map[string]*leaderboardData, len(gamedata.GameModeInfoMap)),
 	}
+
+	if _, err := cryptorand.Read(s.powSecret); err != nil {
+		panic(err)
+	}
+
+	for mode := range gamedata.GameModeInfoMap {
+		board := &leaderboardData{mode: mode}
+		s.leaderboards[mode] = board
+		s.leaderboardList = append(s.leaderboardList, board)
+	}
+	sort.Slice(s.leaderboardList, func(i, j int) bool {
+		return s.leaderboardList[i].mode < s.leaderboardList[j].mode
+	})
+
 	return s
 }
 
@@ -90,7 +129,7 @@ func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if origin := r.Header.Get("Origin"); s.corsAllowed(origin) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, HEAD")
-			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Origin, X-Requested-With, Content-Type, Accept")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Origin, X-Requested-With, Content-Type, Accept, If-None-Match")
 			w.WriteHeader(http.StatusOK)
 			return
 		}
@@ -98,26 +137,15 @@ func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	const kb = 1024
-	r.Body = http.MaxBytesReader(w, r.Body, 128*kb)
+	r.Body = http.MaxBytesReader(w, r.Body, serverapi.MaxRequestBodySize)
 	s.httpHandler.ServeHTTP(w, r)
 }
 
 func (s *apiServer) Preload() error {
-	if err := s.reloadLeaderboard(s.classicLeaderboard); err != nil {
-		return err
-	}
-	if err := s.reloadLeaderboard(s.blitzLeaderboard); err != nil {
-		return err
-	}
-	if err := s.reloadLeaderboard(s.arenaLeaderboard); err != nil {
-		return err
-	}
-	if err := s.reloadLeaderboard(s.infArenaLeaderboard); err != nil {
-		return err
-	}
-	if err := s.reloadLeaderboard(s.reverseLeaderboard); err != nil {
-		return err
+	for _, board := range s.leaderboardList {
+		if err := s.reloadLeaderboard(board); err != nil {
+			return fmt.Errorf("%s leaderboard: %w", board.mode, err)
+		}
 	}
 	return nil
 }
@@ -128,10 +156,22 @@ func (s *apiServer) InitDatabases() error {
 	if err != nil {
 		return err
 	}
+	if err := s.migrateDB("queue.db", queueConn, queueMigrations); err != nil {
+		return err
+	}
 	s.queue = newReplayQueue(queueConn)
 	if err := s.queue.PrepareQueries(); err != nil {
 		return fmt.Errorf("prepare queue queries: %w", err)
 	}
+	// The replay workers are not running yet, so every claim we
+	// can find here was left by a previous server process.
+	numReleased, err := s.queue.ResetClaims()
+	if err != nil {
+		return fmt.Errorf("reset queue claims: %w", err)
+	}
+	if numReleased != 0 {
+		s.logger.Info("released %d stale replay claims", numReleased)
+	}
 
 	for i := 0; i <= currentSeason; i++ {
 		dbFilename := fmt.Sprintf("season%d.db", i)
@@ -140,6 +180,9 @@ func (s *apiServer) InitDatabases() error {
 		if err != nil {
 			return fmt.Errorf("season%d: %w", i, err)
 		}
+		if err := s.migrateDB(dbFilename, conn, seasonMigrations); err != nil {
+			return err
+		}
 		db := &seasonDB{
 			id:   i,
 			conn: conn,
@@ -153,6 +196,17 @@ func (s *apiServer) InitDatabases() error {
 	return nil
 }
 
+func (s *apiServer) migrateDB(name string, conn *sql.DB, migrations []sqliteutil.Migration) error {
+	numApplied, err := sqliteutil.Migrate(conn, migrations)
+	if err != nil {
+		return fmt.Errorf("migrate %s: %w", name, err)
+	}
+	if numApplied != 0 {
+		s.logger.Info("applied %d migrations to %s", numApplied, name)
+	}
+	return nil
+}
+
 func (s *apiServer) intervalMetricsFlush() float64 {
 	return floatRange(s.rand, 2*60, 6*60)
 }
@@ -161,12 +215,12 @@ func (s *apiServer) intervalLeaderboardUpdate() float64 {
 	return floatRange(s.rand, 45, 5*60)
 }
 
-func (s *apiServer) intervalLogRotate() float64 {
-	return floatRange(s.rand, 20, 40)
+func (s *apiServer) intervalRequeueArchived() float64 {
+	return floatRange(s.rand, 10*60, 20*60)
 }
 
-func (s *apiServer) intervalRunReplay() float64 {
-	return floatRange(s.rand, 5, 10)
+func (s *apiServer) intervalLogRotate() float64 {
+	return floatRange(s.rand, 20, 40)
 }
 
 func (s *apiServer) Stop() {
@@ -174,18 +228,28 @@ func (s *apiServer) Stop() {
 }
 
 func (s *apiServer) BackgroundTask() {
-	untilClassicLeaderboardUpdate := s.intervalLeaderboardUpdate()
-	untilBlitzLeaderboardUpdate := s.intervalLeaderboardUpdate()
-	untilArenaLeaderboardUpdate := s.intervalLeaderboardUpdate()
-	untilInfArenaLeaderboardUpdate := s.intervalLeaderboardUpdate()
-	untilReverseLeaderboardUpdate := s.intervalLeaderboardUpdate()
+	for _, board := range s.leaderboardList {
+		board.untilReload = s.intervalLeaderboardUpdate()
+	}
 	untilMetricsFlush := s.intervalMetricsFlush()
 	untilLogRotate := s.intervalLogRotate()
-	untilRunReplay := s.intervalRunReplay()
+	untilRequeueArchived := s.intervalRequeueArchived()
+
+	var workersWg sync.WaitGroup
+	for i := 0; i < s.numReplayWorkers; i++ {
+		w := newReplayWorker(s, i+1, s.rand.Int63())
+		workersWg.Add(1)
+		go func() {
+			w.Run()
+			workersWg.Done()
+		}()
+	}
+	s.logger.Info("started %d replay workers", s.numReplayWorkers)
 
 	for {
 		if atomic.LoadInt64(&s.stop) != 0 {
 			s.logger.Info("stopping the server")
+			workersWg.Wait()
 			return
 		}
 		// Sleet with a random jitter.
@@ -197,64 +261,7 @@ func (s *apiServer) BackgroundTask() {
 
 		s.metrics.data.Uptime += secondsSlept
 
-		untilClassicLeaderboardUpdate -= secondsSlept
-		if untilClassicLeaderboardUpdate <= 0 {
-			delayMultiplier := 1.0
-			if err := s.reloadLeaderboard(s.classicLeaderboard); err != nil {
-				s.logger.Error("classic leaderboard reload: %v", err)
-				delayMultiplier += floatRange(s.rand, 0.5, 1.5)
-			} else {
-				s.logger.Info("reloaded classic leaderboard")
-			}
-			untilClassicLeaderboardUpdate = s.intervalLeaderboardUpdate() * delayMultiplier
-			continue
-		}
-		untilBlitzLeaderboardUpdate -= secondsSlept
-		if untilBlitzLeaderboardUpdate <= 0 {
-			delayMultiplier := 1.0
-			if err := s.reloadLeaderboard(s.blitzLeaderboard); err != nil {
-				s.logger.Error("blitz leaderboard reload: %v", err)
-				delayMultiplier += floatRange(s.rand, 0.5, 1.5)
-			} else {
-				s.logger.Info("reloaded blitz leaderboard")
-			}
-			untilBlitzLeaderboardUpdate = s.intervalLeaderboardUpdate() * delayMultiplier
-			continue
-		}
-		untilArenaLeaderboardUpdate -= secondsSlept
-		if untilArenaLeaderboardUpdate <= 0 {
-			delayMultiplier := 1.0
-			if err := s.reloadLeaderboard(s.arenaLeaderboard); err != nil {
-				s.logger.Error("arena leaderboard reload: %v", err)
-				delayMultiplier += floatRange(s.rand, 0.5, 1.5)
-			} else {
-				s.logger.Info("reloaded arena leaderboard")
-			}
-			untilArenaLeaderboardUpdate = s.intervalLeaderboardUpdate() * delayMultiplier
-			continue
-		}
-		untilInfArenaLeaderboardUpdate -= secondsSlept
-		if untilInfArenaLeaderboardUpdate <= 0 {
-			delayMultiplier := 1.0
-			if err := s.reloadLeaderboard(s.infArenaLeaderboard); err != nil {
-				s.logger.Error("inf_arena leaderboard reload: %v", err)
-				delayMultiplier += floatRange(s.rand, 0.5, 1.5)
-			} else {
-				s.logger.Info("reloaded inf_arena leaderboard")
-			}
-			untilInfArenaLeaderboardUpdate = s.intervalLeaderboardUpdate() * delayMultiplier
-			continue
-		}
-		untilReverseLeaderboardUpdate -= secondsSlept
-		if untilReverseLeaderboardUpdate <= 0 {
-			delayMultiplier := 1.0
-			if err := s.reloadLeaderboard(s.reverseLeaderboard); err != nil {
-				s.logger.Error("reverse leaderboard reload: %v", err)
-				delayMultiplier += floatRange(s.rand, 0.5, 1.5)
-			} else {
-				s.logger.Info("reloaded reverse leaderboard")
-			}
-			untilReverseLeaderboardUpdate = s.intervalLeaderboardUpdate() * delayMultiplier
+		if s.updateLeaderboards(secondsSlept) {
 			continue
 		}
 
@@ -285,37 +292,79 @@ func (s *apiServer) BackgroundTask() {
 			continue
 		}
 
-		untilRunReplay -= secondsSlept
-		if untilRunReplay <= 0 {
-			delayMultiplier := 1.0
-			replayed, err := s.doRunReplay()
+		untilRequeueArchived -= secondsSlept
+		if untilRequeueArchived <= 0 {
+			stats, err := s.doRequeueArchived()
 			if err != nil {
-				delayMultiplier += floatRange(s.rand, 2.5, 4)
-				s.logger.Error("run replay: %v", err)
-			} else if replayed {
-				s.logger.Info("executed a replay")
-			} else {
-				delayMultiplier += floatRange(s.rand, 1, 2)
-				s.logger.Info("no replays to execute")
+				s.logger.Error("requeue archived replays: %v", err)
 			}
-			untilRunReplay = s.intervalRunReplay() * delayMultiplier
+			if stats.scanned != 0 {
+				s.logger.Info("archive scan: scanned=%d requeued=%d skipped=%d malformed=%d",
+					stats.scanned, stats.requeued, stats.skipped, stats.malformed)
+			}
+			untilRequeueArchived = s.intervalRequeueArchived()
 			continue
 		}
 	}
 }
 
-func (s *apiServer) doRunReplay() (bool, error) {
-	replayID, playerName, compressedReplayData, err := s.queue.Get()
+// updateLeaderboards reloads at most one leaderboard that is due.
+// It reports whether any reload was attempted.
+func (s *apiServer) updateLeaderboards(secondsSlept float64) bool {
+	for _, board := range s.leaderboardList {
+		board.untilReload -= secondsSlept
+	}
+	for _, board := range s.leaderboardList {
+		if board.untilReload > 0 {
+			continue
+		}
+		delayMultiplier := 1.0
+		if err := s.reloadLeaderboard(board); err != nil {
+			s.logger.Error("%s leaderboard reload: %v", board.mode, err)
+			delayMultiplier += floatRange(s.rand, 0.5, 1.5)
+		} else {
+			s.logger.Info("reloaded %s leaderboard", board.mode)
+		}
+		board.untilReload = s.intervalLeaderboardUpdate() * delayMultiplier
+		return true
+	}
+	return false
+}
+
+func (s *apiServer) doRunReplay(w *replayWorker) (bool, error) {
+	claimedAt := time.Now().Unix()
+	replayID, playerName, createdAt, compressedReplayData, err := s.queue.Claim(claimedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil
 		}
 		return false, err
 	}
+	s.metrics.ObserveQueueWait(time.Duration(claimedAt-createdAt) * time.Second)
 
+	replayed, err := s.runClaimedReplay(w, replayID, playerName, compressedReplayData)
+	if err != nil {
+		// The replay is still in the queue unless it was archived or deleted
+		// before the error occurred. Release it, so it can be retried later
+		// instead of being stuck until the server restart.
+		// Releasing a replay that was already removed is a no-op.
+		if err := s.queue.Release(replayID); err != nil {
+			s.logger.Error("can't release the replay claim with id=%d: %v", replayID, err)
+		}
+	}
+	return replayed, err
+}
+
+func (s *apiServer) runClaimedReplay(w *replayWorker, replayID int, playerName string, compressedReplayData []byte) (bool, error) {
 	uncompressedReplayData, err := gzipUncompress(compressedReplayData)
 	if err != nil {
-		return false, err
+		// Retrying won't help, the data is corrupted.
+		s.metrics.IncNumReplaysFailed()
+		if err := s.archiveFailedReplay(replayID, playerName, compressedReplayData, 0, archiveUnknown, nil); err != nil {
+			s.logger.Error("can't archive corrupted replay with id=%d: %v", replayID, err)
+			return false, err
+		}
+		return false, fmt.Errorf("uncompress replay with id=%d: %w", replayID, err)
 	}
 
 	var replayData serverapi.GameReplay
@@ -336,8 +385,7 @@ func (s *apiServer) doRunReplay() (bool, error) {
 	db := s.getSeasonDB(seasonNumber)
 	if db == nil {
 		s.metrics.IncNumReplaysFailed()
-		archivedAt := time.Now().Unix()
-		if err := s.queue.Archive(replayID, playerName, archivedAt, compressedReplayData, archiveMismatchingResults); err != nil {
+		if err := s.archiveFailedReplay(replayID, playerName, compressedReplayData, replayData.GameVersion, archiveMismatchingResults, nil); err != nil {
 			s.logger.Error("can't archive bad season replay with id=%d: %v", replayID, err)
 			return false, err
 		}
@@ -345,14 +393,35 @@ func (s *apiServer) doRunReplay() (bool, error) {
 		return true, nil
 	}
 
+	// The current build replays can be simulated in-process;
+	// the older builds need a matching runsim binary.
+	if s.inProcessSim && replayData.GameVersion == gamedata.BuildNumber {
+		start := time.Now()
+		result, failure, err := w.simulate(replayData)
+		elapsed := time.Since(start)
+		s.metrics.ObserveSimulationDuration(elapsed)
+		if err != nil {
+			s.metrics.IncNumReplaysFailed()
+			reason := archiveReasonForFailure(failure.Kind)
+			if err := s.archiveFailedReplay(replayID, playerName, compressedReplayData, replayData.GameVersion, reason, failure); err != nil {
+				s.logger.Error("can't archive bad-simulation replay with id=%d: %v", replayID, err)
+				return true, err
+			}
+			s.logger.Info("archived errored replay with id=%d", replayID)
+			return true, fmt.Errorf("in-process simulation: %w", err)
+		}
+		s.logger.Info("in-process simulation of replay id=%d took %v", replayID, elapsed)
+		s.metrics.IncNumReplaysCompleted()
+		return s.saveReplayResult(db, replayID, playerName, compressedReplayData, &replayData, result)
+	}
+
 	// See whether we have a runner for this replay.
 	// The server should check this beforehand, but bad things can happen:
 	// we may not have this binary anymore.
-	runsimBinaryName := filepath.Join(s.runsimFolder, fmt.Sprintf("runsim_%d", replayData.GameVersion))
+	runsimBinaryName := s.runsimBinaryPath(replayData.GameVersion)
 	if !fileExists(runsimBinaryName) {
 		s.metrics.IncNumReplaysFailed()
-		archivedAt := time.Now().Unix()
-		if err := s.queue.Archive(replayID, playerName, archivedAt, compressedReplayData, archiveUnsupportedBuild); err != nil {
+		if err := s.archiveFailedReplay(replayID, playerName, compressedReplayData, replayData.GameVersion, archiveUnsupportedBuild, nil); err != nil {
 			s.logger.Error("can't archive unsupported build replay with id=%d: %v", replayID, err)
 			return false, err
 		}
@@ -361,34 +430,32 @@ func (s *apiServer) doRunReplay() (bool, error) {
 	}
 
 	start := time.Now()
-	timeout := 30 * time.Second
+	timeoutSeconds := replayTimeoutSeconds(&replayData)
+	timeout := time.Duration(timeoutSeconds) * time.Second
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
-	var runsimArgs []string
-	if replayData.Config.RawGameMode == "inf_arena" {
-		// Infinite arenas may take much longer to simulate due to
-		// their "almost infinite" nature.
-		runsimArgs = append(runsimArgs, "--timeout=60")
-		timeout = 60 * time.Second
+	runsimArgs := []string{
+		fmt.Sprintf("--timeout=%d", timeoutSeconds),
 	}
-	cmd := exec.Command(runsimBinaryName, runsimArgs...)
+	// The simulation should never take that long, but better be safe than sorry.
+	// Every worker runs its own process, so this deadline is per-replay.
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, runsimBinaryName, runsimArgs...)
 	cmd.Stdin = bytes.NewReader(uncompressedReplayData)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
-	if err := cmd.Start(); err != nil {
-		return false, err
-	}
-	// The simulation should never take that long, but better be safe than sorry.
-	timer := time.AfterFunc(timeout, func() {
-		cmd.Process.Kill()
-	})
-	err = cmd.Wait()
-	timer.Stop()
+	err = cmd.Run()
 	elapsed := time.Since(start)
+	s.metrics.ObserveSimulationDuration(elapsed)
 	if err != nil {
 		s.metrics.IncNumReplaysFailed()
-		archivedAt := time.Now().Unix()
-		if err := s.queue.Archive(replayID, playerName, archivedAt, compressedReplayData, archiveExecError); err != nil {
+		failure := runsimFailure(ctx, stdout.Bytes())
+		reason := archiveExecError
+		if failure != nil {
+			reason = archiveReasonForFailure(failure.Kind)
+		}
+		if err := s.archiveFailedReplay(replayID, playerName, compressedReplayData, replayData.GameVersion, reason, failure); err != nil {
 			s.logger.Error("can't archive bad-exec replay with id=%d: %v", replayID, err)
 			return true, err
 		}
@@ -396,7 +463,7 @@ func (s *apiServer) doRunReplay() (bool, error) {
 		return true, fmt.Errorf("failed to execute runsim: %s: %w", stderr.String(), err)
 	}
 
-	s.logger.Info("simulation took %v", elapsed)
+	s.logger.Info("simulation of replay id=%d took %v", replayID, elapsed)
 	s.metrics.IncNumReplaysCompleted()
 
 	var result serverapi.GameResults
@@ -405,13 +472,49 @@ func (s *apiServer) doRunReplay() (bool, error) {
 		if len(part) > 128 {
 			part = part[:128]
 		}
+		// The same runsim binary will most likely print the same output again,
+		// so the replay is archived instead of being retried.
+		if err := s.archiveFailedReplay(replayID, playerName, compressedReplayData, replayData.GameVersion, archiveExecError, nil); err != nil {
+			s.logger.Error("can't archive bad-output replay with id=%d: %v", replayID, err)
+			return true, err
+		}
 		return true, fmt.Errorf("unmarshal runsim results: %w (%q)", err, string(part))
 	}
 
+	return s.saveReplayResult(db, replayID, playerName, compressedReplayData, &replayData, result)
+}
+
+// runsimFailure returns the failure details printed by the runsim binary.
+// The older runsim binaries panic instead, so nil is returned for them.
+func runsimFailure(ctx context.Context, stdout []byte) *serverapi.SimulationFailure {
+	if ctx.Err() == context.DeadlineExceeded {
+		return &serverapi.SimulationFailure{
+			Kind:    serverapi.FailureTimeout,
+			Message: "runsim process deadline exceeded",
+		}
+	}
+	var failure serverapi.SimulationFailure
+	if err := json.Unmarshal(stdout, &failure); err != nil || failure.Kind == "" {
+		return nil
+	}
+	return &failure
+}
+
+// archiveFailedReplay moves the replay from the queue to the failed replays archive.
+// The failure is optional: it's only available for the failed simulations.
+func (s *apiServer) archiveFailedReplay(replayID int, playerName string, compressedReplayData []byte, gameVersion int, reason archiveReason, failure *serverapi.SimulationFailure) error {
+	archivedAt := time.Now().Unix()
+	if err := s.queue.Archive(replayID, playerName, archivedAt, compressedReplayData, gameVersion, reason, failure); err != nil {
+		return err
+	}
+	s.metrics.IncNumReplaysArchived(reason)
+	return nil
+}
+
+func (s *apiServer) saveReplayResult(db *seasonDB, replayID int, playerName string, compressedReplayData []byte, replayData *serverapi.GameReplay, result serverapi.GameResults) (bool, error) {
 	if result != replayData.Results {
 		s.metrics.IncNumReplaysFailed()
-		archivedAt := time.Now().Unix()
-		if err := s.queue.Archive(replayID, playerName, archivedAt, compressedReplayData, archiveMismatchingResults); err != nil {
+		if err := s.archiveFailedReplay(replayID, playerName, compressedReplayData, replayData.GameVersion, archiveMismatchingResults, nil); err != nil {
 			s.logger.Error("can't archive mis-simulated replay with id=%d: %v", replayID, err)
 			return false, err
 		}
@@ -442,7 +545,7 @@ func (s *apiServer) doRunReplay() (bool, error) {
 	// verified results to the database.
 	// TODO: this should be done in a transaction.
 	drones := strings.Join(replayData.Config.Tier2Recipes, ",")
-	err = db.UpdatePlayerScore(replayData.Config.RawGameMode, playerName, savedReplayID, drones, result.Score, difficulty, result.Time, platform)
+	err := db.UpdatePlayerScore(replayData.Config.RawGameMode, playerName, savedReplayID, drones, result.Score, difficulty, result.Time, platform)
 	if err != nil {
 		return true, err
 	}
@@ -453,6 +556,28 @@ func (s *apiServer) doRunReplay() (bool, error) {
 	return true, nil
 }
 
+// canSimulate reports whether the server is able to verify
+// the replays made with the specified game build.
+func (s *apiServer) canSimulate(gameVersion int) bool {
+	if s.inProcessSim && gameVersion == gamedata.BuildNumber {
+		return true
+	}
+	return fileExists(s.runsimBinaryPath(gameVersion))
+}
+
+func (s *apiServer) runsimBinaryPath(gameVersion int) string {
+	return filepath.Join(s.runsimFolder, fmt.Sprintf("runsim_%d", gameVersion))
+}
+
+func replayTimeoutSeconds(replay *serverapi.GameReplay) int {
+	if replay.Config.RawGameMode == "inf_arena" {
+		// Infinite arenas may take much longer to simulate due to
+		// their "almost infinite" nature.
+		return 60
+	}
+	return 30
+}
+
 func (s *apiServer) doLogRotate() (bool, error) {
 	const kb = 1024
 	if s.logger.GetSize() < 256*kb {
@@ -470,8 +595,17 @@ func (s *apiServer) doMetricsFlush() error {
 	return os.WriteFile(s.metricsFile, jsonData, 0o666)
 }
 
+// IsValidMode reports whether the mode has a leaderboard.
+func (s *apiServer) IsValidMode(mode string) bool {
+	_, ok := s.leaderboards[mode]
+	return ok
+}
+
 func (s *apiServer) Top10(mode string) []serverapi.LeaderboardEntry {
-	leaderboard := s.getBoardForMode(mode)
+	s.leaderboardMu.RLock()
+	defer s.leaderboardMu.RUnlock()
+
+	leaderboard := s.leaderboards[mode]
 	n := 10
 	if n >= len(leaderboard.entries) {
 		n = len(leaderboard.entries)
@@ -479,28 +613,31 @@ func (s *apiServer) Top10(mode string) []serverapi.LeaderboardEntry {
 	return leaderboard.entries[:n]
 }
 
-func (s *apiServer) getBoardForMode(mode string) *leaderboardData {
-	switch mode {
-	case "classic":
-		return s.classicLeaderboard
-	case "blitz":
-		return s.blitzLeaderboard
-	case "arena":
-		return s.arenaLeaderboard
-	case "inf_arena":
-		return s.infArenaLeaderboard
-	case "reverse":
-		return s.reverseLeaderboard
-	}
-	return nil
+// BoardETag returns a tag that changes every time the board contents change.
+// The board responses use it to build their ETags.
+func (s *apiServer) BoardETag(mode string) string {
+	s.leaderboardMu.RLock()
+	defer s.leaderboardMu.RUnlock()
+	return s.leaderboards[mode].etag
+}
+
+func (s *apiServer) BoardJSON(mode string) []byte {
+	s.leaderboardMu.RLock()
+	defer s.leaderboardMu.RUnlock()
+	return s.leaderboards[mode].json
 }
 
 func (s *apiServer) NumBoardPlayers(mode string) int {
-	return len(s.getBoardForMode(mode).entries)
+	s.leaderboardMu.RLock()
+	defer s.leaderboardMu.RUnlock()
+	return len(s.leaderboards[mode].entries)
 }
 
 func (s *apiServer) PlayerBoard(mode, name string, score int) ([]serverapi.LeaderboardEntry, error) {
-	board := s.getBoardForMode(mode)
+	s.leaderboardMu.RLock()
+	defer s.leaderboardMu.RUnlock()
+
+	board := s.leaderboards[mode]
 	if len(board.entries) == 0 {
 		return nil, nil
 	}
@@ -551,10 +688,76 @@ func (s *apiServer) PlayerBoard(mode, name string, score int) ([]serverapi.Leade
 	return board.entries[from:to], nil
 }
 
-func (s *apiServer) reloadLeaderboard(leaderboard *leaderboardData) error {
-	s.leaderboardMu.Lock()
-	defer s.leaderboardMu.Unlock()
+// boardFilter describes the get-board request filters.
+// The zero value matches every entry.
+type boardFilter struct {
+	platform      string
+	minDifficulty int
+	maxDifficulty int // 0 means "no limit"
+	drone         string
+}
+
+func (f *boardFilter) Match(e *serverapi.LeaderboardEntry) bool {
+	if f.platform != "" && e.Platform != f.platform {
+		return false
+	}
+	if e.Difficulty < f.minDifficulty {
+		return false
+	}
+	if f.maxDifficulty != 0 && e.Difficulty > f.maxDifficulty {
+		return false
+	}
+	if f.drone != "" && !containsDrone(e.Drones, f.drone) {
+		return false
+	}
+	return true
+}
 
+// containsDrone reports whether the drone is listed in the comma-separated drones string.
+func containsDrone(drones, drone string) bool {
+	for drones != "" {
+		var name string
+		name, drones, _ = strings.Cut(drones, ",")
+		if name == drone {
+			return true
+		}
+	}
+	return false
+}
+
+// BoardPage returns the limit-sized slice of the filtered board entries
+// along with the total number of matched entries.
+// The entries keep their global ranks.
+func (s *apiServer) BoardPage(mode string, filter *boardFilter, offset, limit int) ([]serverapi.LeaderboardEntry, int) {
+	s.leaderboardMu.RLock()
+	defer s.leaderboardMu.RUnlock()
+
+	board := s.leaderboards[mode]
+
+	if *filter == (boardFilter{}) {
+		// The fast path: no need to copy anything.
+		numEntries := len(board.entries)
+		from := gmath.ClampMax(offset, numEntries)
+		to := gmath.ClampMax(from+limit, numEntries)
+		return board.entries[from:to], numEntries
+	}
+
+	var page []serverapi.LeaderboardEntry
+	numMatched := 0
+	for i := range board.entries {
+		e := &board.entries[i]
+		if !filter.Match(e) {
+			continue
+		}
+		if numMatched >= offset && len(page) < limit {
+			page = append(page, *e)
+		}
+		numMatched++
+	}
+	return page, numMatched
+}
+
+func (s *apiServer) reloadLeaderboard(leaderboard *leaderboardData) error {
 	entries, err := s.getSeasonDB(currentSeason).AllScores(leaderboard.mode)
 	if err != nil {
 		return err
@@ -575,9 +778,13 @@ func (s *apiServer) reloadLeaderboard(leaderboard *leaderboardData) error {
 	if err != nil {
 		return err
 	}
+	sum := sha1.Sum(data)
 
+	s.leaderboardMu.Lock()
 	leaderboard.json = data
 	leaderboard.entries = entries
+	leaderboard.etag = hex.EncodeToString(sum[:12])
+	s.leaderboardMu.Unlock()
 
 	return nil
 }
@@ -605,18 +812,51 @@ func (s *apiServer) writeError(w http.ResponseWriter, err error) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	case errQueueIsFull:
 		w.WriteHeader(http.StatusTooManyRequests)
+	case errBadStamp:
+		w.WriteHeader(http.StatusForbidden)
 	default:
 		s.logger.Error(err.Error())
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
 
+// binaryReplayResp is a handler result that is sent as is
+// with the binary replay content type.
+type binaryReplayResp []byte
+
+// taggedResp is a handler result with the ETag.
+// If the client already has the same response, it gets 304 without the body.
+type taggedResp struct {
+	etag string
+	v    any
+}
+
+// newTaggedResp creates a response that is tagged by the board contents
+// and the request parameters, so the requests with different params never collide.
+// The extra values are the response inputs that are not a part of the board.
+func newTaggedResp(boardETag string, r *http.Request, v any, extra ...any) taggedResp {
+	h := fnv.New64a()
+	h.Write([]byte(r.URL.RawQuery))
+	for _, x := range extra {
+		fmt.Fprint(h, x)
+	}
+	return taggedResp{
+		etag: fmt.Sprintf(`"%s-%x"`, boardETag, h.Sum64()),
+		v:    v,
+	}
+}
+
 func (s *apiServer) NewHandler(f func(*http.Request) (any, error)) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		v, err := f(r)
+		tagged, hasETag := v.(taggedResp)
 		if origin := r.Header.Get("origin"); s.corsAllowed(origin) {
 			w.Header().Set("Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if hasETag {
+				// Let the web build read it.
+				w.Header().Set("Access-Control-Expose-Headers", "ETag")
+			}
 		}
 		if err != nil {
 			s.writeError(w, err)
@@ -626,11 +866,25 @@ func (s *apiServer) NewHandler(f func(*http.Request) (any, error)) func(http.Res
 			w.Header().Set("Content-Type", "application/json")
 			return
 		}
+		if hasETag {
+			w.Header().Set("ETag", tagged.etag)
+			w.Header().Set("Cache-Control", "no-cache")
+			if r.Header.Get("If-None-Match") == tagged.etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			v = tagged.v
+		}
 
 		var data []byte
-		if vData, ok := v.([]byte); ok {
-			data = vData
-		} else {
+		contentType := "application/json"
+		switch v := v.(type) {
+		case []byte:
+			data = v
+		case binaryReplayResp:
+			data = v
+			contentType = serverapi.ReplayBinaryContentType
+		default:
 			data, err = json.Marshal(v)
 			if err != nil {
 				s.writeError(w, err)
@@ -638,7 +892,7 @@ func (s *apiServer) NewHandler(f func(*http.Request) (any, error)) func(http.Res
 			}
 		}
 
-		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Type", contentType)
 		w.Write(data)
 	}
 }
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+// fakeRunsimScript is a runsim binary replacement that
+// reports the replay results as they were claimed by the player.
+const fakeRunsimScript = `#!/bin/sh
+sed -n 's/.*"results":\({[^}]*}\).*/\1/p'
+`
+
+func newTestServer(t *testing.T) *apiServer {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake runsim binary is a shell script")
+	}
+
+	runsimFolder := t.TempDir()
+	runsimPath := filepath.Join(runsimFolder, fmt.Sprintf("runsim_%d", gamedata.BuildNumber))
+	if err := os.WriteFile(runsimPath, []byte(fakeRunsimScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newAPIServer(serverConfig{
+		runsimFolder:     runsimFolder,
+		numReplayWorkers: 1,
+		dataFolder:       t.TempDir(),
+		logger:           &fileLogger{f: os.Stderr},
+		metricsFile:      filepath.Join(t.TempDir(), "metrics.json"),
+	})
+	if err := s.InitDatabases(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newTestReplay(score int) serverapi.GameReplay {
+	replay := serverapi.GameReplay{
+		GameVersion:      gamedata.BuildNumber,
+		LevelGenChecksum: 1,
+		Results: serverapi.GameResults{
+			Time:    600,
+			Ticks:   600 * 60,
+			Score:   score,
+			Victory: true,
+		},
+		Config: serverapi.ReplayLevelConfig{
+			RawGameMode: "classic",
+			Seed:        7395164,
+			DronesPower: 1,
+		},
+	}
+	replay.Config.DifficultyScore = gamedata.CalcDifficultyScore(replay.Config, 0)
+	return replay
+}
+
+func submitTestReplay(t *testing.T, s *apiServer, playerName string, replay serverapi.GameReplay) *serverapi.SavePlayerScoreResp {
+	t.Helper()
+
+	checksum := serverapi.ReplayChecksum(&replay)
+	challenge := s.NewScoreChallenge(checksum, s.scoreChallengeDifficulty(0), time.Now().Unix())
+	counter := serverapi.SolveHashcash(checksum, challenge.Nonce, challenge.Difficulty)
+
+	data, err := json.Marshal(replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := url.Values{
+		"season":      {fmt.Sprint(currentSeason)},
+		"name":        {playerName},
+		"pow_nonce":   {challenge.Nonce},
+		"pow_counter": {fmt.Sprint(counter)},
+	}
+	h := newRequestHandler(s)
+	req := httptest.NewRequest(http.MethodPost, "/save-player-score?"+params.Encode(), bytes.NewReader(data))
+	rec := httptest.NewRecorder()
+	s.NewHandler(h.HandleSavePlayerScore)(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("submit %q replay: status %d", playerName, rec.Code)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	var resp serverapi.SavePlayerScoreResp
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("submit %q replay: %v", playerName, err)
+	}
+	return &resp
+}
+
+func TestReplayVerification(t *testing.T) {
+	s := newTestServer(t)
+	w := newReplayWorker(s, 0, 1)
+
+	submissions := []struct {
+		player string
+		score  int
+	}{
+		{"alice", 2000},
+		{"bob", 3500},
+		{"carol", 2000},
+	}
+	for i, sub := range submissions {
+		replay := newTestReplay(sub.score)
+		replay.Config.Seed += int64(i) // Every replay needs a unique checksum
+		resp := submitTestReplay(t, s, sub.player, replay)
+		if !resp.Queued {
+			t.Fatalf("%q replay is not queued", sub.player)
+		}
+	}
+
+	for range submissions {
+		replayed, err := s.doRunReplay(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !replayed {
+			t.Fatal("expected a replay to be executed")
+		}
+	}
+	queueSize, err := s.queue.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queueSize != 0 {
+		t.Fatalf("queue size is %d after the verification", queueSize)
+	}
+
+	if err := s.reloadLeaderboard(s.leaderboards["classic"]); err != nil {
+		t.Fatal(err)
+	}
+	entries, numEntries := s.BoardPage("classic", &boardFilter{}, 0, 10)
+	if numEntries != len(submissions) {
+		t.Fatalf("the board has %d entries, want %d", numEntries, len(submissions))
+	}
+	wantRanks := map[string]int{
+		"bob":   1,
+		"alice": 2,
+		"carol": 2,
+	}
+	for _, e := range entries {
+		if e.Rank != wantRanks[e.PlayerName] {
+			t.Errorf("%q rank is %d, want %d", e.PlayerName, e.Rank, wantRanks[e.PlayerName])
+		}
+	}
+}
+
+func TestBoardETag(t *testing.T) {
+	s := newTestServer(t)
+	h := newRequestHandler(s)
+
+	getBoard := func(query, etag string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/get-board?"+query, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		s.NewHandler(h.HandleGetBoard)(rec, req)
+		return rec
+	}
+	query := fmt.Sprintf("season=%d&mode=classic", currentSeason)
+
+	etag := getBoard(query, "").Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("the board response has no ETag")
+	}
+	if rec := getBoard(query, etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("unchanged board: status %d, body size %d", rec.Code, rec.Body.Len())
+	}
+	if rec := getBoard(query+"&limit=5", etag); rec.Code != http.StatusOK {
+		t.Fatalf("another page with the same ETag: status %d", rec.Code)
+	}
+
+	resp := submitTestReplay(t, s, "alice", newTestReplay(2000))
+	if !resp.Queued {
+		t.Fatal("the replay is not queued")
+	}
+	if _, err := s.doRunReplay(newReplayWorker(s, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.reloadLeaderboard(s.leaderboards["classic"]); err != nil {
+		t.Fatal(err)
+	}
+	rec := getBoard(query, etag)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("updated board: status %d", rec.Code)
+	}
+	if rec.Header().Get("ETag") == etag {
+		t.Fatal("the board update didn't change the ETag")
+	}
+}
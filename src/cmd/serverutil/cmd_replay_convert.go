@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+func cmdReplayConvert(args []string) error {
+	fs := flag.NewFlagSet("serverutil replay.convert", flag.ExitOnError)
+	inputName := fs.String("i", "", "input replay file name (JSON or binary)")
+	outputName := fs.String("o", "", "output file name")
+	format := fs.String("format", "", "output format: json or binary; inferred from the output file extension if empty")
+	fs.Parse(args)
+
+	if *inputName == "" {
+		return errors.New("input file name can't be empty")
+	}
+	if *outputName == "" {
+		return errors.New("output file name can't be empty")
+	}
+	if *format == "" {
+		*format = "json"
+		if strings.HasSuffix(*outputName, serverapi.ReplayBinaryFileExt) {
+			*format = "binary"
+		}
+	}
+
+	data, err := os.ReadFile(*inputName)
+	if err != nil {
+		return fmt.Errorf("read input: %w", err)
+	}
+	var replay serverapi.GameReplay
+	if err := serverapi.UnmarshalReplay(data, &replay); err != nil {
+		return fmt.Errorf("decode replay: %w", err)
+	}
+
+	var result []byte
+	switch *format {
+	case "json":
+		result, err = json.Marshal(replay)
+	case "binary":
+		result, err = serverapi.MarshalReplayBinary(&replay)
+	default:
+		return fmt.Errorf("unknown output format %q", *format)
+	}
+	if err != nil {
+		return fmt.Errorf("encode replay: %w", err)
+	}
+	if err := os.WriteFile(*outputName, result, 0o644); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+
+	fmt.Printf("%s: %d bytes -> %s: %d bytes\n", *inputName, len(data), *outputName, len(result))
+	return nil
+}
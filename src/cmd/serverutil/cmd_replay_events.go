@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+func cmdReplayEvents(args []string) error {
+	fs := flag.NewFlagSet("serverutil replay.events", flag.ExitOnError)
+	inputName := fs.String("i", "", "input replay file name (JSON or binary)")
+	outputName := fs.String("o", "", "output JSON lines file name; stdout if empty")
+	kind := fs.String("kind", "", "only print the events of this kind")
+	fs.Parse(args)
+
+	if *inputName == "" {
+		return errors.New("input file name can't be empty")
+	}
+
+	data, err := os.ReadFile(*inputName)
+	if err != nil {
+		return fmt.Errorf("read input: %w", err)
+	}
+	var replay serverapi.GameReplay
+	if err := serverapi.UnmarshalReplay(data, &replay); err != nil {
+		return fmt.Errorf("decode replay: %w", err)
+	}
+
+	events := replay.Events
+	if *kind != "" {
+		events = nil
+		for _, e := range replay.Events {
+			if e.Kind == serverapi.MatchEventKind(*kind) {
+				events = append(events, e)
+			}
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *outputName != "" {
+		f, err := os.Create(*outputName)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return serverapi.WriteMatchEventsJSONL(w, events)
+}
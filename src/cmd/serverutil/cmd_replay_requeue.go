@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/quasilyte/roboden-game/queuedb"
+	"github.com/quasilyte/roboden-game/sqliteutil"
+)
+
+// These values should be in sync with the server archiveReason constants.
+var archiveReasonByName = map[string]int{
+	"unknown":             0,
+	"unsupported_build":   1,
+	"mismatching_results": 2,
+	"invalid_season":      3,
+	"exec_error":          4,
+	"illegal_action":      5,
+	"bad_checkpoint":      6,
+}
+
+func cmdReplayRequeue(args []string) error {
+	fs := flag.NewFlagSet("serverutil replay.requeue", flag.ExitOnError)
+	dbPath := fs.String("queue", "", "path to the queue db file")
+	reasonName := fs.String("reason", "unsupported_build", "archive reason of the replays to requeue")
+	kind := fs.String("kind", "", "if not empty, requeue the replays with this failure kind instead of using the reason")
+	build := fs.Int("build", 0, "requeue only the replays of this game build; 0 means any build")
+	simulatorsFolder := fs.String("simulators-folder", "", "if not empty, requeue only the replays that have a runsim binary in this folder")
+	limit := fs.Int("limit", 256, "max number of replays to requeue")
+	dryRun := fs.Bool("dry-run", false, "only report what would be requeued")
+	fs.Parse(args)
+
+	if *dbPath == "" {
+		return errors.New("queue filename can't be empty")
+	}
+	reason, ok := archiveReasonByName[*reasonName]
+	if !ok {
+		return fmt.Errorf("unknown archive reason %q", *reasonName)
+	}
+
+	db, err := sqliteutil.Connect(*dbPath)
+	if err != nil {
+		return fmt.Errorf("connect to %q: %w", *dbPath, err)
+	}
+
+	filter := &queuedb.RequeueFilter{
+		Reason: reason,
+		Kind:   *kind,
+		CanSimulate: func(gameVersion int) bool {
+			if *build != 0 && gameVersion != *build {
+				return false
+			}
+			if *simulatorsFolder != "" {
+				runsimBinaryName := filepath.Join(*simulatorsFolder, fmt.Sprintf("runsim_%d", gameVersion))
+				if _, err := os.Stat(runsimBinaryName); err != nil {
+					return false
+				}
+			}
+			return true
+		},
+		OnSkip: func(archiveID int, reason string) {
+			fmt.Printf("skip archived id=%d: %s\n", archiveID, reason)
+		},
+	}
+	candidates, stats, err := queuedb.FindRequeueCandidates(db, filter, *limit)
+	if err != nil {
+		return fmt.Errorf("fetch archived replays: %w", err)
+	}
+
+	for i := range candidates {
+		c := &candidates[i]
+		if *dryRun {
+			fmt.Printf("would requeue archived id=%d (replay id=%d, player=%q, build=%d)\n", c.ArchiveID, c.ReplayID, c.PlayerName, c.GameVersion)
+			continue
+		}
+		if err := queuedb.Requeue(db, c, time.Now().Unix()); err != nil {
+			return fmt.Errorf("requeue archived id=%d: %w", c.ArchiveID, err)
+		}
+		fmt.Printf("requeued archived id=%d (replay id=%d, player=%q, build=%d)\n", c.ArchiveID, c.ReplayID, c.PlayerName, c.GameVersion)
+	}
+
+	if *dryRun {
+		fmt.Printf("dry run: %d replays would be requeued, %d skipped\n", len(candidates), stats.Skipped)
+	} else {
+		fmt.Printf("%d replays requeued, %d skipped\n", len(candidates), stats.Skipped)
+	}
+
+	return nil
+}
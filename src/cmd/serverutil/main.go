@@ -17,6 +17,24 @@ func main() {
 			Do:          makeMainFunc(cmdArchiveExtract),
 		},
 
+		{
+			Name:        "replay.convert",
+			Description: "convert replays between JSON and binary encodings",
+			Do:          makeMainFunc(cmdReplayConvert),
+		},
+
+		{
+			Name:        "replay.events",
+			Description: "print replay match events as JSON lines",
+			Do:          makeMainFunc(cmdReplayEvents),
+		},
+
+		{
+			Name:        "replay.requeue",
+			Description: "move archived replays back to the queue",
+			Do:          makeMainFunc(cmdReplayRequeue),
+		},
+
 		{
 			Name:        "version",
 			Description: "print tool version info",
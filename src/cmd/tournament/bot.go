@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/runsim"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+type botInfo struct {
+	name string
+
+	// Only one of these is set.
+	profile *gamedata.BotProfile
+	command []string
+}
+
+func parseBot(spec string) (*botInfo, error) {
+	if command, ok := strings.CutPrefix(spec, "exec:"); ok {
+		args := strings.Fields(command)
+		if len(args) == 0 {
+			return nil, fmt.Errorf("empty bot command: %q", spec)
+		}
+		return &botInfo{name: spec, command: args}, nil
+	}
+	i := gamedata.FindBotProfile(spec)
+	if i == -1 {
+		return nil, fmt.Errorf("unknown bot profile: %q", spec)
+	}
+	return &botInfo{name: spec, profile: gamedata.BotProfiles[i]}, nil
+}
+
+// botProcess is a running external bot executable.
+// Every game starts its own bot process.
+type botProcess struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// startBotProcess runs the bot executable for the given player.
+// The bot process is killed if it doesn't respond to an observation
+// in moveTimeout (0 means no limit).
+func startBotProcess(b *botInfo, playerID int, moveTimeout time.Duration) (*botProcess, runsim.ExternalBot, error) {
+	cmd := exec.Command(b.command[0], b.command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, runsim.ExternalBot{}, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, runsim.ExternalBot{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, runsim.ExternalBot{}, fmt.Errorf("start %s: %w", b.name, err)
+	}
+	p := &botProcess{cmd: cmd, stdin: stdin}
+	bot := runsim.ExternalBot{
+		PlayerID: playerID,
+		R:        bufio.NewReader(stdout),
+		W:        stdin,
+	}
+	if moveTimeout != 0 {
+		conn := &botConn{p: p, r: stdout, w: stdin, timeout: moveTimeout}
+		bot.R = bufio.NewReader(conn)
+		bot.W = conn
+	}
+	return p, bot, nil
+}
+
+// botConn limits the bot response time.
+//
+// The time is counted from the moment the observation is sent
+// and only while the game is waiting for the bot action.
+type botConn struct {
+	p        *botProcess
+	r        io.Reader
+	w        io.Writer
+	timeout  time.Duration
+	deadline time.Time
+}
+
+func (c *botConn) Write(data []byte) (int, error) {
+	c.deadline = time.Now().Add(c.timeout)
+	return c.w.Write(data)
+}
+
+func (c *botConn) Read(data []byte) (int, error) {
+	t := time.AfterFunc(time.Until(c.deadline), func() {
+		c.p.cmd.Process.Kill()
+	})
+	n, err := c.r.Read(data)
+	if !t.Stop() {
+		return n, fmt.Errorf("no response in %v", c.timeout)
+	}
+	return n, err
+}
+
+// stop closes the bot input and gives it some time to exit on its own.
+func (p *botProcess) stop() {
+	p.stdin.Close()
+	done := make(chan struct{})
+	go func() {
+		p.cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		p.cmd.Process.Kill()
+		<-done
+	}
+}
+
+// defaultLevelConfig returns the settings used by the autogame.
+func defaultLevelConfig() serverapi.ReplayLevelConfig {
+	var config serverapi.ReplayLevelConfig
+	config.DronesPower = 1
+	config.Teleporters = 1
+	config.OilRegenRate = 2
+	config.Terrain = 1
+	config.Resources = 2
+	config.WorldSize = 2
+	config.CreepDifficulty = 3
+	config.InitialCreeps = 1
+	config.NumCreepBases = 2
+	config.CreepSpawnRate = 1
+	config.BossDifficulty = 1
+	return config
+}
+
+// levelConfig returns a game config for the given seed and mode.
+// Unless the base config specifies the colony design,
+// a random build is picked using the game seed,
+// so all bots get the same build for the same seed.
+func (config *tournamentConfig) levelConfig(seed int64, mode string) serverapi.ReplayLevelConfig {
+	result := config.baseConfig
+	result.Seed = seed
+	result.RawGameMode = mode
+
+	var rng gmath.Rand
+	rng.SetSeed(seed)
+	if len(result.Tier2Recipes) == 0 {
+		result.Tier2Recipes = gamedata.CreateDroneBuild(&rng)
+	}
+	if result.CoreDesign == "" {
+		var coreDesigns []string
+		for _, core := range gamedata.CoreStatsList {
+			coreDesigns = append(coreDesigns, core.Name)
+		}
+		result.CoreDesign = gamedata.PickColonyDesign(coreDesigns, &rng)
+	}
+	if result.TurretDesign == "" {
+		var turretDesigns []string
+		for _, turret := range gamedata.TurretStatsList {
+			turretDesigns = append(turretDesigns, turret.Kind.String())
+		}
+		result.TurretDesign = gamedata.PickTurretDesign(result.CoreDesign, turretDesigns, &rng)
+	}
+	if config.randomEnvironment {
+		result.Environment = rng.IntRange(0, 3)
+	}
+	return result
+}
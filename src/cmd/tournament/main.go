@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+// The tournament runner plays the bots against each other.
+//
+// A bot is either a computer player profile name (like "turtle")
+// or an external bot executable that speaks the runsim play mode protocol
+// over its stdin/stdout ("exec:./mybot -v").
+//
+// There are two match formats:
+//
+//	seeds   - every bot plays the same seeds alone (single bot mode),
+//	          the results of the same seed are compared
+//	twobots - both bots share the same world (two bots mode),
+//	          the player stats are compared; every seed is played twice
+//	          with the player slots swapped
+//
+// A failed game is a forfeit loss for the external bot at fault:
+// the bot crashed, didn't respond in time (see -move-timeout)
+// or broke the protocol. A game that failed without a bot at fault
+// (like a game engine error) is void and it's not counted.
+//
+// Example:
+//
+//	go run ./cmd/tournament -bot turtle -bot rusher -bot hard -seeds 1,2,3 -modes classic,arena
+func main() {
+	var botFlags stringListFlag
+	flag.Var(&botFlags, "bot", "a tournament participant: a bot profile name or exec:COMMAND; can be repeated")
+	seedsFlag := flag.String("seeds", "1,2,3,4,5", "a comma-separated list of the game seeds")
+	modesFlag := flag.String("modes", "classic", "a comma-separated list of the game modes")
+	formatFlag := flag.String("format", "seeds", "a match format: seeds or twobots")
+	systemFlag := flag.String("system", "roundrobin", "a tournament system: roundrobin or swiss")
+	roundsFlag := flag.Int("rounds", 0, "the number of swiss rounds; 0 means log2 of the number of bots")
+	configFlag := flag.String("config", "", "a base level config JSON file; the mode, seed and players are overridden")
+	jobsFlag := flag.Int("j", runtime.NumCPU(), "how many games to run in parallel")
+	timeoutFlag := flag.Int("timeout", 120, "a profile bots game simulation timeout in seconds")
+	moveTimeoutFlag := flag.Duration("move-timeout", 10*time.Second, "an external bot response time limit; 0 means no limit")
+	outputFlag := flag.String("o", "", "where to write the JSON report")
+	debugFlag := flag.Bool("debug", false, "whether to enable debug logs")
+	flag.Parse()
+
+	config := tournamentConfig{
+		format:         *formatFlag,
+		system:         *systemFlag,
+		rounds:         *roundsFlag,
+		numWorkers:     gmath.ClampMin(*jobsFlag, 1),
+		timeoutSeconds: *timeoutFlag,
+		moveTimeout:    *moveTimeoutFlag,
+		debug:          *debugFlag,
+	}
+	if err := config.init(botFlags, *seedsFlag, *modesFlag, *configFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "tournament: %v\n", err)
+		os.Exit(1)
+	}
+
+	report := runTournament(&config)
+	printReport(os.Stdout, &config, report)
+
+	if *outputFlag != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		if err := os.WriteFile(*outputFlag, data, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "tournament: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+type stringListFlag []string
+
+func (f *stringListFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringListFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+type tournamentConfig struct {
+	bots  []*botInfo
+	seeds []int64
+	modes []string
+
+	baseConfig        serverapi.ReplayLevelConfig
+	randomEnvironment bool
+
+	format         string
+	system         string
+	rounds         int
+	numWorkers     int
+	timeoutSeconds int
+	moveTimeout    time.Duration
+	debug          bool
+}
+
+func (config *tournamentConfig) init(bots []string, seeds, modes, baseConfigPath string) error {
+	switch config.format {
+	case "seeds", "twobots":
+		// OK.
+	default:
+		return fmt.Errorf("unexpected match format: %q", config.format)
+	}
+	switch config.system {
+	case "roundrobin", "swiss":
+		// OK.
+	default:
+		return fmt.Errorf("unexpected tournament system: %q", config.system)
+	}
+
+	if len(bots) < 2 {
+		return fmt.Errorf("at least 2 bots are needed")
+	}
+	names := make(map[string]bool, len(bots))
+	for _, spec := range bots {
+		b, err := parseBot(spec)
+		if err != nil {
+			return err
+		}
+		if names[b.name] {
+			return fmt.Errorf("duplicated bot: %q", b.name)
+		}
+		names[b.name] = true
+		config.bots = append(config.bots, b)
+	}
+
+	for _, s := range strings.Split(seeds, ",") {
+		seed, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil || seed <= 0 {
+			return fmt.Errorf("invalid seed: %q", s)
+		}
+		config.seeds = append(config.seeds, seed)
+	}
+
+	hasExec := false
+	for _, b := range config.bots {
+		hasExec = hasExec || b.profile == nil
+	}
+	for _, mode := range strings.Split(modes, ",") {
+		mode = strings.TrimSpace(mode)
+		switch mode {
+		case "classic", "arena", "inf_arena":
+			// OK.
+		case "blitz":
+			if hasExec {
+				return fmt.Errorf("%s mode can't be played by the external bots", mode)
+			}
+		default:
+			return fmt.Errorf("unsupported game mode: %q", mode)
+		}
+		config.modes = append(config.modes, mode)
+	}
+
+	if baseConfigPath != "" {
+		data, err := os.ReadFile(baseConfigPath)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &config.baseConfig); err != nil {
+			return fmt.Errorf("unmarshal level config: %w", err)
+		}
+	} else {
+		config.baseConfig = defaultLevelConfig()
+		config.randomEnvironment = true
+	}
+
+	if config.rounds == 0 {
+		for n := 1; n < len(config.bots); n *= 2 {
+			config.rounds++
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/quasilyte/roboden-game/timeutil"
+)
+
+func printReport(w io.Writer, config *tournamentConfig, report *tournamentReport) {
+	fmt.Fprintf(w, "%s tournament, %s format, %d seeds, modes: %s\n\n",
+		report.System, report.Format, len(config.seeds), strings.Join(config.modes, ", "))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tbot\telo\tpoints\tW\tD\tL\t")
+	for i, s := range report.Standings {
+		fmt.Fprintf(tw, "%d\t%s\t%.0f\t%.1f\t%d\t%d\t%d\t\n",
+			i+1, s.Bot, s.Elo, s.Points, s.Wins, s.Draws, s.Losses)
+	}
+	tw.Flush()
+
+	// The per-seed breakdown shows the game points of every bot
+	// (and its solo game result for the seeds format).
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "mode\tseed\t")
+	for _, s := range report.Standings {
+		fmt.Fprintf(tw, "%s\t", s.Bot)
+	}
+	fmt.Fprintln(tw)
+	for _, mode := range config.modes {
+		for _, seed := range config.seeds {
+			fmt.Fprintf(tw, "%s\t%d\t", mode, seed)
+			for _, s := range report.Standings {
+				points := 0.0
+				numGames := 0
+				for _, g := range report.Games {
+					if g.Seed != seed || g.Mode != mode || g.Void {
+						continue
+					}
+					switch s.Bot {
+					case g.A:
+						points += g.Score
+						numGames++
+					case g.B:
+						points += 1 - g.Score
+						numGames++
+					}
+				}
+				cell := fmt.Sprintf("%.1f/%d", points, numGames)
+				for _, r := range report.Solo {
+					if r.Bot == s.Bot && r.Seed == seed && r.Mode == mode {
+						cell += " " + formatSoloResult(&r)
+						break
+					}
+				}
+				fmt.Fprintf(tw, "%s\t", cell)
+			}
+			fmt.Fprintln(tw)
+		}
+	}
+	tw.Flush()
+}
+
+func formatSoloResult(r *soloRecord) string {
+	if r.Forfeit {
+		return "(forfeit)"
+	}
+	if r.Error != "" {
+		return "(error)"
+	}
+	result := "defeat"
+	if r.Results.Victory {
+		result = "victory"
+	}
+	timePlayed := timeutil.FormatDurationCompact(time.Second * time.Duration(r.Results.Time))
+	if r.Results.Score != 0 {
+		return fmt.Sprintf("(%s %d, %s)", result, r.Results.Score, timePlayed)
+	}
+	return fmt.Sprintf("(%s, %s)", result, timePlayed)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	initialElo = 1500
+
+	// eloK is lower than the usual 32: every pairing
+	// consists of many games (seeds x modes).
+	eloK = 16
+)
+
+type standing struct {
+	Bot    string  `json:"bot"`
+	Elo    float64 `json:"elo"`
+	Points float64 `json:"points"`
+	Wins   int     `json:"wins"`
+	Draws  int     `json:"draws"`
+	Losses int     `json:"losses"`
+	Byes   int     `json:"byes,omitempty"`
+
+	index int
+	met   map[int]bool
+}
+
+type pairing struct {
+	a int
+	b int
+}
+
+func standingLess(x, y *standing) bool {
+	if x.Points != y.Points {
+		return x.Points > y.Points
+	}
+	return x.Elo > y.Elo
+}
+
+func roundRobinPairings(numBots int) []pairing {
+	var pairings []pairing
+	for i := 0; i < numBots; i++ {
+		for j := i + 1; j < numBots; j++ {
+			pairings = append(pairings, pairing{a: i, b: j})
+		}
+	}
+	return pairings
+}
+
+// swissPairings pairs the bots with the similar scores.
+// The bots that already met are not paired again unless there is no other choice.
+// With the odd number of bots, the lowest ranked one gets a bye
+// (every bot gets at most one bye while it's possible).
+func swissPairings(standings []*standing) ([]pairing, *standing) {
+	ranked := make([]*standing, len(standings))
+	copy(ranked, standings)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return standingLess(ranked[i], ranked[j])
+	})
+
+	var bye *standing
+	if len(ranked)%2 != 0 {
+		byeIndex := len(ranked) - 1
+		for i := len(ranked) - 1; i >= 0; i-- {
+			if ranked[i].Byes == 0 {
+				byeIndex = i
+				break
+			}
+		}
+		bye = ranked[byeIndex]
+		ranked = append(ranked[:byeIndex], ranked[byeIndex+1:]...)
+	}
+
+	var pairings []pairing
+	paired := make([]bool, len(ranked))
+	for i, x := range ranked {
+		if paired[i] {
+			continue
+		}
+		opponent := -1
+		for j := i + 1; j < len(ranked); j++ {
+			if paired[j] {
+				continue
+			}
+			if opponent == -1 {
+				opponent = j
+			}
+			if !x.met[ranked[j].index] {
+				opponent = j
+				break
+			}
+		}
+		paired[i] = true
+		paired[opponent] = true
+		pairings = append(pairings, pairing{a: x.index, b: ranked[opponent].index})
+	}
+
+	return pairings, bye
+}
+
+// eloUpdate returns the new a and b ratings after a game
+// where a got the given score.
+func eloUpdate(a, b, score float64) (float64, float64) {
+	expected := 1 / (1 + math.Pow(10, (b-a)/400))
+	delta := eloK * (score - expected)
+	return a + delta, b - delta
+}
+
+// updateStandings applies the round games to the standings.
+// The void games are not counted; a pairing without
+// the counted games gives no points, but the bots are considered met.
+func updateStandings(standings []*standing, pairings []pairing, games []gameRecord) {
+	byIndex := make([]*standing, len(standings))
+	for _, s := range standings {
+		byIndex[s.index] = s
+	}
+
+	matchScores := make(map[pairing]float64, len(pairings))
+	matchGames := make(map[pairing]int, len(pairings))
+	for _, g := range games {
+		if g.Void {
+			continue
+		}
+		a := byIndex[g.a]
+		b := byIndex[g.b]
+		switch g.Score {
+		case 1:
+			a.Wins++
+			b.Losses++
+		case 0:
+			a.Losses++
+			b.Wins++
+		default:
+			a.Draws++
+			b.Draws++
+		}
+		a.Elo, b.Elo = eloUpdate(a.Elo, b.Elo, g.Score)
+		p := pairing{a: g.a, b: g.b}
+		matchScores[p] += g.Score - 0.5
+		matchGames[p]++
+	}
+
+	// A pairing winner gets 1 point, a draw gives 0.5 points to both bots.
+	for _, p := range pairings {
+		a := byIndex[p.a]
+		b := byIndex[p.b]
+		a.met[p.b] = true
+		b.met[p.a] = true
+		if matchGames[p] == 0 {
+			continue
+		}
+		switch score := matchScores[p]; {
+		case score > 0:
+			a.Points++
+		case score < 0:
+			b.Points++
+		default:
+			a.Points += 0.5
+			b.Points += 0.5
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func newTestStandings(points ...float64) []*standing {
+	standings := make([]*standing, len(points))
+	for i, p := range points {
+		standings[i] = &standing{
+			Bot:    string(rune('a' + i)),
+			Elo:    initialElo,
+			Points: p,
+			index:  i,
+			met:    make(map[int]bool),
+		}
+	}
+	return standings
+}
+
+func TestEloUpdate(t *testing.T) {
+	tests := []struct {
+		a     float64
+		b     float64
+		score float64
+		wantA float64
+		wantB float64
+	}{
+		{1500, 1500, 1, 1508, 1492},
+		{1500, 1500, 0, 1492, 1508},
+		{1500, 1500, 0.5, 1500, 1500},
+		{1700, 1300, 1, 1701.45, 1298.55},
+		{1700, 1300, 0, 1685.45, 1314.55},
+		{1300, 1700, 0.5, 1306.55, 1693.45},
+	}
+
+	for _, test := range tests {
+		a, b := eloUpdate(test.a, test.b, test.score)
+		if math.Abs(a-test.wantA) > 0.01 || math.Abs(b-test.wantB) > 0.01 {
+			t.Errorf("eloUpdate(%v, %v, %v):\nhave: %.2f %.2f\nwant: %.2f %.2f",
+				test.a, test.b, test.score, a, b, test.wantA, test.wantB)
+		}
+		if math.Abs(a+b-test.a-test.b) > 1e-9 {
+			t.Errorf("eloUpdate(%v, %v, %v): the rating sum is not preserved",
+				test.a, test.b, test.score)
+		}
+	}
+}
+
+func TestSwissPairings(t *testing.T) {
+	tests := []struct {
+		name    string
+		points  []float64
+		met     [][2]int
+		byes    []int
+		want    []pairing
+		wantBye int
+	}{
+		{
+			name:    "by rank",
+			points:  []float64{0, 2, 1, 3},
+			want:    []pairing{{a: 3, b: 1}, {a: 2, b: 0}},
+			wantBye: -1,
+		},
+		{
+			name:    "avoid repeats",
+			points:  []float64{3, 2, 1, 0},
+			met:     [][2]int{{0, 1}},
+			want:    []pairing{{a: 0, b: 2}, {a: 1, b: 3}},
+			wantBye: -1,
+		},
+		{
+			name:    "forced repeat",
+			points:  []float64{1, 0},
+			met:     [][2]int{{0, 1}},
+			want:    []pairing{{a: 0, b: 1}},
+			wantBye: -1,
+		},
+		{
+			name:    "bye to the lowest ranked",
+			points:  []float64{2, 0, 1},
+			want:    []pairing{{a: 0, b: 2}},
+			wantBye: 1,
+		},
+		{
+			name:    "no second bye",
+			points:  []float64{2, 0, 1},
+			byes:    []int{1},
+			want:    []pairing{{a: 0, b: 1}},
+			wantBye: 2,
+		},
+		{
+			name:    "everyone had a bye",
+			points:  []float64{2, 0, 1},
+			byes:    []int{0, 1, 2},
+			want:    []pairing{{a: 0, b: 2}},
+			wantBye: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			standings := newTestStandings(test.points...)
+			for _, m := range test.met {
+				standings[m[0]].met[m[1]] = true
+				standings[m[1]].met[m[0]] = true
+			}
+			for _, i := range test.byes {
+				standings[i].Byes++
+			}
+			pairings, bye := swissPairings(standings)
+			if len(pairings) != len(test.want) {
+				t.Fatalf("pairings:\nhave: %v\nwant: %v", pairings, test.want)
+			}
+			for i := range pairings {
+				if pairings[i] != test.want[i] {
+					t.Fatalf("pairings:\nhave: %v\nwant: %v", pairings, test.want)
+				}
+			}
+			byeIndex := -1
+			if bye != nil {
+				byeIndex = bye.index
+			}
+			if byeIndex != test.wantBye {
+				t.Fatalf("bye: have %d, want %d", byeIndex, test.wantBye)
+			}
+		})
+	}
+}
+
+func TestUpdateStandings(t *testing.T) {
+	type result struct {
+		points float64
+		wins   int
+		draws  int
+		losses int
+	}
+	tests := []struct {
+		name  string
+		games []gameRecord
+		want  [2]result
+	}{
+		{
+			name:  "win",
+			games: []gameRecord{{Score: 1}, {Score: 0.5}},
+			want:  [2]result{{points: 1, wins: 1, draws: 1}, {draws: 1, losses: 1}},
+		},
+		{
+			name:  "loss",
+			games: []gameRecord{{Score: 0}, {Score: 0.5}},
+			want:  [2]result{{draws: 1, losses: 1}, {points: 1, wins: 1, draws: 1}},
+		},
+		{
+			name:  "draw",
+			games: []gameRecord{{Score: 1}, {Score: 0}},
+			want:  [2]result{{points: 0.5, wins: 1, losses: 1}, {points: 0.5, wins: 1, losses: 1}},
+		},
+		{
+			name:  "void games are not counted",
+			games: []gameRecord{{Score: 1}, {Void: true}, {Void: true}},
+			want:  [2]result{{points: 1, wins: 1}, {losses: 1}},
+		},
+		{
+			name:  "all void",
+			games: []gameRecord{{Void: true}},
+			want:  [2]result{{}, {}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			standings := newTestStandings(0, 0)
+			for i := range test.games {
+				test.games[i].a = 0
+				test.games[i].b = 1
+			}
+			updateStandings(standings, []pairing{{a: 0, b: 1}}, test.games)
+			for i, s := range standings {
+				have := result{points: s.Points, wins: s.Wins, draws: s.Draws, losses: s.Losses}
+				if have != test.want[i] {
+					t.Errorf("%s standing:\nhave: %+v\nwant: %+v", s.Bot, have, test.want[i])
+				}
+				if !s.met[1-i] {
+					t.Errorf("%s didn't meet the opponent", s.Bot)
+				}
+			}
+			if math.Abs(standings[0].Elo+standings[1].Elo-2*initialElo) > 1e-9 {
+				t.Errorf("the rating sum is not preserved")
+			}
+			if test.want[0].wins+test.want[0].losses == 0 && standings[0].Elo != initialElo {
+				t.Errorf("unexpected rating change without the decisive games: %.2f", standings[0].Elo)
+			}
+		})
+	}
+}
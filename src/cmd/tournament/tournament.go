@@ -0,0 +1,366 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/runsim"
+	"github.com/quasilyte/roboden-game/scenes/staging"
+	"github.com/quasilyte/roboden-game/serverapi"
+	"github.com/quasilyte/roboden-game/session"
+)
+
+type tournamentReport struct {
+	Format string `json:"format"`
+	System string `json:"system"`
+
+	Standings []*standing  `json:"standings"`
+	Games     []gameRecord `json:"games"`
+
+	// Solo are the seeds format results of every bot.
+	Solo []soloRecord `json:"solo,omitempty"`
+}
+
+// gameRecord is a comparison of two bots on the same seed and mode.
+type gameRecord struct {
+	Round int    `json:"round"`
+	A     string `json:"a"`
+	B     string `json:"b"`
+	Seed  int64  `json:"seed"`
+	Mode  string `json:"mode"`
+
+	// Score is the A result: 1 for a win, 0.5 for a draw and 0 for a loss.
+	// A bot that crashes, doesn't respond in time or breaks the protocol
+	// forfeits the game.
+	Score float64 `json:"score"`
+
+	// Void is set when the game failed without a bot at fault
+	// (like a game engine error); such games are not counted.
+	Void bool `json:"void,omitempty"`
+
+	Error string `json:"error,omitempty"`
+
+	a int
+	b int
+}
+
+type soloRecord struct {
+	Bot     string                `json:"bot"`
+	Seed    int64                 `json:"seed"`
+	Mode    string                `json:"mode"`
+	Results serverapi.GameResults `json:"results"`
+	Error   string                `json:"error,omitempty"`
+
+	// Forfeit is set when the game failed because of the bot.
+	Forfeit bool `json:"forfeit,omitempty"`
+}
+
+func runTournament(config *tournamentConfig) *tournamentReport {
+	report := &tournamentReport{
+		Format: config.format,
+		System: config.system,
+	}
+	for i, b := range config.bots {
+		report.Standings = append(report.Standings, &standing{
+			Bot:   b.name,
+			Elo:   initialElo,
+			index: i,
+			met:   make(map[int]bool),
+		})
+	}
+
+	if config.format == "seeds" {
+		// The solo games don't depend on the pairings,
+		// so they're all played at once.
+		report.Solo = playSoloGames(config)
+	}
+
+	numRounds := 1
+	if config.system == "swiss" {
+		numRounds = config.rounds
+	}
+	for round := 1; round <= numRounds; round++ {
+		var pairings []pairing
+		if config.system == "swiss" {
+			var bye *standing
+			pairings, bye = swissPairings(report.Standings)
+			if bye != nil {
+				bye.Byes++
+				bye.Points++
+			}
+		} else {
+			pairings = roundRobinPairings(len(config.bots))
+		}
+
+		var games []gameRecord
+		if config.format == "seeds" {
+			games = compareSoloGames(config, report.Solo, pairings)
+		} else {
+			games = playDuelGames(config, pairings)
+		}
+		for i := range games {
+			games[i].Round = round
+		}
+		report.Games = append(report.Games, games...)
+
+		updateStandings(report.Standings, pairings, games)
+	}
+
+	sort.SliceStable(report.Standings, func(i, j int) bool {
+		return standingLess(report.Standings[i], report.Standings[j])
+	})
+
+	return report
+}
+
+func playSoloGames(config *tournamentConfig) []soloRecord {
+	var records []soloRecord
+	for _, b := range config.bots {
+		for _, mode := range config.modes {
+			for _, seed := range config.seeds {
+				records = append(records, soloRecord{Bot: b.name, Seed: seed, Mode: mode})
+			}
+		}
+	}
+
+	jobs := make([]func(*session.State) error, len(records))
+	for i := range records {
+		r := &records[i]
+		b := config.bots[i/(len(config.modes)*len(config.seeds))]
+		jobs[i] = func(state *session.State) error {
+			results, err := config.playMatch(state, config.levelConfig(r.Seed, r.Mode), []*botInfo{b})
+			r.Results = results.Results
+			if err != nil {
+				r.Error = err.Error()
+				r.Forfeit = gameFault(err) != -1
+			}
+			return err
+		}
+	}
+	runJobs(config, "solo games", jobs)
+
+	return records
+}
+
+func compareSoloGames(config *tournamentConfig, solo []soloRecord, pairings []pairing) []gameRecord {
+	find := func(bot int, seed int64, mode string) *soloRecord {
+		name := config.bots[bot].name
+		for i := range solo {
+			r := &solo[i]
+			if r.Bot == name && r.Seed == seed && r.Mode == mode {
+				return r
+			}
+		}
+		panic(fmt.Sprintf("no solo results for %s", name))
+	}
+
+	var games []gameRecord
+	for _, p := range pairings {
+		for _, mode := range config.modes {
+			for _, seed := range config.seeds {
+				x := find(p.a, seed, mode)
+				y := find(p.b, seed, mode)
+				g := newGameRecord(config, p, seed, mode)
+				switch {
+				case x.Error == "" && y.Error == "":
+					g.Score = compareResults(x.Results, y.Results)
+				case x.Error != "" && !x.Forfeit, y.Error != "" && !y.Forfeit:
+					g.Void = true
+				case x.Forfeit && y.Forfeit:
+					// There is no winner when both bots are at fault.
+					g.Void = true
+				default:
+					g.Score = scoreOf(y.Forfeit)
+				}
+				switch {
+				case x.Error != "":
+					g.Error = x.Error
+				case y.Error != "":
+					g.Error = y.Error
+				}
+				games = append(games, g)
+			}
+		}
+	}
+	return games
+}
+
+// compareResults returns the x result score against y.
+//
+// The victory is always better than the defeat.
+// Then the higher score wins.
+// If the scores are equal, the faster victory or the longer survival wins.
+func compareResults(x, y serverapi.GameResults) float64 {
+	if x.Victory != y.Victory {
+		return scoreOf(x.Victory)
+	}
+	if x.Score != y.Score {
+		return scoreOf(x.Score > y.Score)
+	}
+	if x.Ticks != y.Ticks {
+		if x.Victory {
+			return scoreOf(x.Ticks < y.Ticks)
+		}
+		return scoreOf(x.Ticks > y.Ticks)
+	}
+	return 0.5
+}
+
+// compareStats returns the x player stats score against y.
+// It's used for the two bots games, where the game results are shared.
+func compareStats(x, y serverapi.BotStats) float64 {
+	if x.CreepsKilled != y.CreepsKilled {
+		return scoreOf(x.CreepsKilled > y.CreepsKilled)
+	}
+	if x.DamageDealt != y.DamageDealt {
+		return scoreOf(x.DamageDealt > y.DamageDealt)
+	}
+	return 0.5
+}
+
+func scoreOf(win bool) float64 {
+	if win {
+		return 1
+	}
+	return 0
+}
+
+func playDuelGames(config *tournamentConfig, pairings []pairing) []gameRecord {
+	var games []gameRecord
+	for _, p := range pairings {
+		for _, mode := range config.modes {
+			for _, seed := range config.seeds {
+				games = append(games, newGameRecord(config, p, seed, mode))
+			}
+		}
+	}
+
+	// Every game is played twice with the player slots swapped.
+	scores := make([][2]float64, len(games))
+	failures := make([][2]error, len(games))
+	jobs := make([]func(*session.State) error, 0, 2*len(games))
+	for i := range games {
+		g := &games[i]
+		for side := 0; side < 2; side++ {
+			i := i
+			side := side
+			jobs = append(jobs, func(state *session.State) error {
+				bots := []*botInfo{config.bots[g.a], config.bots[g.b]}
+				if side == 1 {
+					bots[0], bots[1] = bots[1], bots[0]
+				}
+				results, err := config.playMatch(state, config.levelConfig(g.Seed, g.Mode), bots)
+				if err != nil {
+					failures[i][side] = err
+					return err
+				}
+				aID := side
+				bID := 1 - side
+				scores[i][side] = compareStats(results.Players[aID], results.Players[bID])
+				return nil
+			})
+		}
+	}
+	runJobs(config, "two bots games", jobs)
+
+	for i := range games {
+		g := &games[i]
+		score := 0.0
+		numCounted := 0
+		for side := 0; side < 2; side++ {
+			err := failures[i][side]
+			if err == nil {
+				score += scores[i][side]
+				numCounted++
+				continue
+			}
+			g.Error = err.Error()
+			fault := gameFault(err)
+			if fault == -1 {
+				// Nobody is at fault, this side is not counted.
+				continue
+			}
+			// The A bot is the player 0 for side 0 and the player 1 for side 1.
+			score += scoreOf(fault != side)
+			numCounted++
+		}
+		if numCounted == 0 {
+			g.Void = true
+			continue
+		}
+		g.Score = score / float64(numCounted)
+	}
+
+	return games
+}
+
+func newGameRecord(config *tournamentConfig, p pairing, seed int64, mode string) gameRecord {
+	return gameRecord{
+		A:    config.bots[p.a].name,
+		B:    config.bots[p.b].name,
+		Seed: seed,
+		Mode: mode,
+		a:    p.a,
+		b:    p.b,
+	}
+}
+
+// playMatch runs a game where bots[i] controls the player i.
+func (config *tournamentConfig) playMatch(state *session.State, levelConfig serverapi.ReplayLevelConfig, bots []*botInfo) (runsim.MatchResults, error) {
+	m := runsim.Match{
+		Config:         levelConfig,
+		BotProfiles:    make(map[int]*gamedata.BotProfile),
+		TimeoutSeconds: config.timeoutSeconds,
+	}
+	if len(bots) == 1 {
+		m.Config.PlayersMode = serverapi.PmodeSingleBot
+	} else {
+		m.Config.PlayersMode = serverapi.PmodeTwoBots
+	}
+	for playerID, b := range bots {
+		if b.profile != nil {
+			m.BotProfiles[playerID] = b.profile
+			continue
+		}
+		proc, bot, err := startBotProcess(b, playerID, config.moveTimeout)
+		if err != nil {
+			return runsim.MatchResults{}, &staging.ExternalBotError{PlayerID: playerID, Err: err}
+		}
+		defer proc.stop()
+		m.Bots = append(m.Bots, bot)
+		// The external bots are limited by the move timeout instead.
+		m.TimeoutSeconds = 0
+	}
+	return runsim.RunMatch(state, m)
+}
+
+// gameFault returns the ID of the player whose bot caused the game failure.
+// It returns -1 if the failure is not caused by any bot,
+// like a game engine error or a simulation timeout.
+func gameFault(err error) int {
+	var botErr *staging.ExternalBotError
+	if errors.As(err, &botErr) {
+		return botErr.PlayerID
+	}
+	return -1
+}
+
+// runJobs executes the jobs in parallel and reports the progress.
+func runJobs(config *tournamentConfig, label string, jobs []func(*session.State) error) {
+	poolConfig := runsim.PoolConfig{
+		NumWorkers: config.numWorkers,
+		DebugLogs:  config.debug,
+	}
+	numDone := 0
+	runsim.RunJobs(poolConfig, jobs, func(i int, err error) {
+		numDone++
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "game failed: %v\n", err)
+		}
+		fmt.Fprintf(os.Stderr, "\r%s: %d/%d", label, numDone, len(jobs))
+	})
+	fmt.Fprintln(os.Stderr)
+}
@@ -56,6 +56,12 @@ const (
 	ActionChoice4
 	ActionChoice5
 	ActionMoveChoice
+
+	ActionReplayStep
+	ActionReplayStepBack
+	ActionReplaySeekForward
+	ActionReplaySeekBackward
+	ActionReplayTakeOver
 )
 
 type KeymapSet struct {
@@ -168,6 +174,12 @@ func BindKeymap(ctx *ge.Context) KeymapSet {
 		ActionMoveChoice: {input.KeyMouseRight},
 
 		ActionClick: {input.KeyMouseLeft},
+
+		ActionReplayStep:         {input.KeyPeriod},
+		ActionReplayStepBack:     {input.KeyComma},
+		ActionReplaySeekForward:  {input.KeyBracketRight},
+		ActionReplaySeekBackward: {input.KeyBracketLeft},
+		ActionReplayTakeOver:     {input.KeyT},
 	}
 
 	mainKeymap := input.Keymap{
@@ -61,6 +61,13 @@ func ReplayText(d *langs.Dictionary, r *session.SavedReplay) string {
 	}
 	lines = append(lines, fmt.Sprintf("%s: %d%s", d.Get("menu.main.build"), r.Replay.GameVersion, mismatchSuffix))
 	lines = append(lines, fmt.Sprintf("%s: %s", d.Get("menu.lobby.players"), playerModeValues[r.Replay.Config.PlayersMode]))
+	switch r.Replay.Config.PlayersMode {
+	case serverapi.PmodeSingleBot, serverapi.PmodePlayerAndBot, serverapi.PmodeTwoBots:
+		if r.Replay.Config.BotProfile >= 0 && r.Replay.Config.BotProfile < len(gamedata.BotProfiles) {
+			profile := gamedata.BotProfiles[r.Replay.Config.BotProfile]
+			lines = append(lines, fmt.Sprintf("%s: %s", d.Get("menu.lobby.bot_profile"), d.Get("menu.lobby.bot_profile", profile.Name)))
+		}
+	}
 	if r.Replay.Config.RawGameMode != "inf_arena" {
 		resultsKey := r.ResultTag
 		lines = append(lines, fmt.Sprintf("%s: %s", d.Get("menu.replay.game_result"), strings.ToLower(d.Get(resultsKey))))
@@ -85,10 +92,32 @@ func ReplayText(d *langs.Dictionary, r *session.SavedReplay) string {
 	lines = append(lines, fmt.Sprintf("%s: %s", d.Get("menu.results.time_played"), timeutil.FormatDurationCompact(timePlayed)))
 	gameSpeedValues := []string{"x1.0", "x1.2", "x1.5", "x2.0"}
 	lines = append(lines, fmt.Sprintf("%s: %s", d.Get("menu.lobby.game_speed"), gameSpeedValues[r.Replay.Config.GameSpeed]))
+	if len(r.Replay.Config.Tier2Recipes) != 0 {
+		drones := make([]string, len(r.Replay.Config.Tier2Recipes))
+		for i, recipe := range r.Replay.Config.Tier2Recipes {
+			drones[i] = d.Get("drone", strings.ToLower(recipe))
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", d.Get("menu.schema.drones"), strings.Join(drones, ", ")))
+	}
 
 	return strings.Join(lines, "\n")
 }
 
+func MatchSaveText(d *langs.Dictionary, s *session.MatchSave) string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("%s [%s]", d.Get("menu.play", s.Config.RawGameMode), timeutil.FormatDateISO8601(s.Date, true)))
+	lines = append(lines, "")
+	lines = append(lines, fmt.Sprintf("%s: %d", d.Get("menu.lobby.game_seed"), s.Config.Seed))
+	lines = append(lines, fmt.Sprintf("%s: %d%%", d.Get("menu.lobby.tab.difficulty"), s.Config.DifficultyScore))
+	timePlayed := time.Second * time.Duration(s.Time)
+	lines = append(lines, fmt.Sprintf("%s: %s", d.Get("menu.results.time_played"), timeutil.FormatDurationCompact(timePlayed)))
+	lines = append(lines, fmt.Sprintf("%s: %d", d.Get("menu.stats.colonies"), s.Colonies))
+	lines = append(lines, fmt.Sprintf("%s: %d", d.Get("menu.play.continue.drones"), s.Drones))
+	lines = append(lines, "")
+	lines = append(lines, d.Get("menu.play.continue.unranked"))
+	return strings.Join(lines, "\n")
+}
+
 func LockedDroneText(d *langs.Dictionary, stats *session.PlayerStats, drone *gamedata.AgentStats) string {
 	textLines := make([]string, 0, 4)
 	textLines = append(textLines, d.Get("drone.locked"))
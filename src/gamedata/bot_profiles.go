@@ -0,0 +1,132 @@
+package gamedata
+
+// BotProfile tunes the computer player behavior.
+//
+// The delay values are multipliers for the default bot timings,
+// so 1 keeps the default behavior and greater values make the bot slower.
+type BotProfile struct {
+	Name string
+
+	// ActionDelay scales the pauses between the bot actions.
+	ActionDelay float64
+
+	// ReactionDelay scales the colony defense and relocation timings.
+	ReactionDelay float64
+
+	// BuildColonyDelay scales the pauses between the new colony attempts.
+	BuildColonyDelay float64
+
+	// AttackBaseDelay scales the pauses between the creep base attacks.
+	AttackBaseDelay float64
+
+	// MinAttackDrones is a colony size required to attack a creep base.
+	// A colony that has less than 1.5x of that also needs enough resources.
+	MinAttackDrones int
+
+	// MinAttackPower is a colony power required to attack a creep base.
+	MinAttackPower int
+
+	// MinColonyDrones is a colony size required to build a new colony.
+	MinColonyDrones int
+
+	ExtraColonies int
+	ExtraTurrets  int
+
+	// BossRetreatChance is a chance to get out of the dreadnought path.
+	BossRetreatChance float64
+}
+
+// BotProfiles are indexed by the ReplayLevelConfig.BotProfile value.
+// The replays depend on this order, so new profiles should be appended.
+var BotProfiles = []*BotProfile{
+	{
+		Name:              "normal",
+		ActionDelay:       1,
+		ReactionDelay:     1,
+		BuildColonyDelay:  1,
+		AttackBaseDelay:   1,
+		MinAttackDrones:   30,
+		MinAttackPower:    70,
+		MinColonyDrones:   20,
+		BossRetreatChance: 0.65,
+	},
+
+	{
+		Name:              "easy",
+		ActionDelay:       2,
+		ReactionDelay:     2,
+		BuildColonyDelay:  1.5,
+		AttackBaseDelay:   1.5,
+		MinAttackDrones:   40,
+		MinAttackPower:    100,
+		MinColonyDrones:   30,
+		ExtraColonies:     -1,
+		ExtraTurrets:      -1,
+		BossRetreatChance: 0.4,
+	},
+
+	{
+		Name:              "hard",
+		ActionDelay:       0.6,
+		ReactionDelay:     0.6,
+		BuildColonyDelay:  0.85,
+		AttackBaseDelay:   0.8,
+		MinAttackDrones:   30,
+		MinAttackPower:    70,
+		MinColonyDrones:   20,
+		ExtraTurrets:      1,
+		BossRetreatChance: 0.85,
+	},
+
+	{
+		Name:              "turtle",
+		ActionDelay:       1,
+		ReactionDelay:     0.8,
+		BuildColonyDelay:  1.5,
+		AttackBaseDelay:   2,
+		MinAttackDrones:   45,
+		MinAttackPower:    110,
+		MinColonyDrones:   25,
+		ExtraColonies:     -1,
+		ExtraTurrets:      2,
+		BossRetreatChance: 0.9,
+	},
+
+	{
+		Name:              "rusher",
+		ActionDelay:       0.8,
+		ReactionDelay:     1,
+		BuildColonyDelay:  1.2,
+		AttackBaseDelay:   0.5,
+		MinAttackDrones:   20,
+		MinAttackPower:    50,
+		MinColonyDrones:   25,
+		ExtraColonies:     -1,
+		ExtraTurrets:      -1,
+		BossRetreatChance: 0.5,
+	},
+
+	{
+		Name:              "expander",
+		ActionDelay:       1,
+		ReactionDelay:     1,
+		BuildColonyDelay:  0.5,
+		AttackBaseDelay:   1.3,
+		MinAttackDrones:   35,
+		MinAttackPower:    80,
+		MinColonyDrones:   14,
+		ExtraColonies:     2,
+		BossRetreatChance: 0.65,
+	},
+}
+
+// FindBotProfile returns the BotProfiles index of the profile with the given name.
+// If there is no such profile, -1 is returned.
+func FindBotProfile(name string) int {
+	for i, p := range BotProfiles {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -104,6 +104,34 @@ var AtomicBombWeapon = InitWeaponStats(&WeaponStats{
 	AlwaysExplodes:      true,
 })
 
+var CreepStatsList = []*CreepStats{
+	IonMortarCreepStats,
+	TurretCreepStats,
+	FortressCreepStats,
+	BaseCreepStats,
+	CrawlerBaseCreepStats,
+	CrawlerBaseConstructionCreepStats,
+	TurretConstructionCreepStats,
+	IonMortarConstructionCreepStats,
+	WandererCreepStats,
+	WispCreepStats,
+	WispLairCreepStats,
+	ServantCreepStats,
+	CrawlerCreepStats,
+	EliteCrawlerCreepStats,
+	HeavyCrawlerCreepStats,
+	HowitzerCreepStats,
+	StealthCrawlerCreepStats,
+	GrenadierCreepStats,
+	AssaultCreepStats,
+	DominatorCreepStats,
+	BuilderCreepStats,
+	UberBossCreepStats,
+	TemplarCreepStats,
+	CenturionCreepStats,
+	StunnerCreepStats,
+}
+
 var IonMortarCreepStats = &CreepStats{
 	Kind:      CreepTurret,
 	Image:     assets.ImageIonMortarCreep,
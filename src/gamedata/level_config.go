@@ -87,6 +87,10 @@ type LevelConfig struct {
 	EliteResources bool
 	EnemyBoss      bool
 
+	// StateHashInterval is a number of ticks between the recorded state hashes.
+	// Zero means "use the default interval".
+	StateHashInterval int
+
 	ExtraDrones []*AgentStats
 }
 
@@ -30,12 +30,8 @@ func Validate() {
 }
 
 func IsRunnableReplay(r serverapi.GameReplay) bool {
-	switch r.Config.RawGameMode {
-	case "classic", "arena", "inf_arena", "reverse", "blitz":
-		return true
-	default:
-		return false
-	}
+	_, ok := GameModeInfoMap[r.Config.RawGameMode]
+	return ok
 }
 
 func IsSendableReplay(r serverapi.GameReplay) bool {
@@ -45,6 +41,9 @@ func IsSendableReplay(r serverapi.GameReplay) bool {
 	if GetSeedKind(r.Config.Seed, r.Config) != SeedNormal {
 		return false
 	}
+	if r.Debug.Resumed {
+		return false
+	}
 	if r.Results.Score <= 0 {
 		return false
 	}
@@ -74,9 +73,18 @@ func IsValidReplay(replay serverapi.GameReplay) bool {
 	if len(replay.Debug.Checkpoints) > 48 {
 		return false
 	}
+	if len(replay.Debug.StateHashes) > serverapi.MaxStateHashes || replay.Debug.StateHashInterval < 0 {
+		return false
+	}
 	if len(replay.Actions) > 6000 {
 		return false
 	}
+	if len(replay.Events) > serverapi.MaxMatchEvents {
+		return false
+	}
+	if replay.Debug.Resumed {
+		return false
+	}
 	if (time.Second * time.Duration(replay.Results.Time)) > 8*time.Hour {
 		return false
 	}
@@ -85,10 +93,7 @@ func IsValidReplay(replay serverapi.GameReplay) bool {
 		return false
 	}
 
-	switch replay.Config.RawGameMode {
-	case "blitz", "classic", "arena", "inf_arena", "reverse":
-		// OK.
-	default:
+	if _, ok := GameModeInfoMap[replay.Config.RawGameMode]; !ok {
 		return false
 	}
 
@@ -111,6 +116,12 @@ func IsValidReplay(replay serverapi.GameReplay) bool {
 		}
 	}
 
+	// The bot profile is ignored without the computer players,
+	// so it should stay at its default value.
+	if replay.Config.PlayersMode == serverapi.PmodeSinglePlayer && replay.Config.BotProfile != 0 {
+		return false
+	}
+
 	cfg := &replay.Config
 
 	pointsAllocated := 0
@@ -130,39 +141,47 @@ func IsValidReplay(replay serverapi.GameReplay) bool {
 		return false
 	}
 
+	return ValidateLevelOptions(cfg) == nil
+}
+
+// ValidateLevelOptions reports the first level config option
+// that is out of its range.
+// It's a part of IsValidReplay that is also used for the generated configs.
+func ValidateLevelOptions(cfg *serverapi.ReplayLevelConfig) error {
 	type optionValidator struct {
+		name   string
 		actual int
 		min    int
 		max    int
 	}
 	toValidate := [...]optionValidator{
-		{cfg.InitialCreeps, 0, 2},
-		{cfg.NumCreepBases, 0, 5},
-		{cfg.CreepDifficulty, 0, 13},
-		{cfg.DronesPower, 0, 7},
-		{cfg.TechProgressRate, 0, 8},
-		{cfg.CreepSpawnRate, 0, 5},
-		{cfg.BossDifficulty, 0, 3},
-		{cfg.ArenaProgression, 0, 7},
-		{cfg.GameSpeed, 0, 3},
-		{cfg.Teleporters, 0, 2},
-		{cfg.WorldSize, 0, 3},
-		{cfg.WorldShape, 0, 2},
-		{cfg.Resources, 0, 4},
-		{cfg.OilRegenRate, 0, 3},
-		{cfg.Terrain, 0, 2},
-		{cfg.InterfaceMode, 0, 2},
-		{cfg.Environment, 0, 3},
-		{cfg.CreepProductionRate, 0, 10},
-		{cfg.PlayersMode, serverapi.PmodeSinglePlayer, serverapi.PmodeTwoBots},
+		{"initial_creeps", cfg.InitialCreeps, 0, 2},
+		{"num_creep_bases", cfg.NumCreepBases, 0, 5},
+		{"creep_difficulty", cfg.CreepDifficulty, 0, 13},
+		{"drones_power", cfg.DronesPower, 0, 7},
+		{"tech_progress_rate", cfg.TechProgressRate, 0, 8},
+		{"creep_spawn_rate", cfg.CreepSpawnRate, 0, 5},
+		{"boss_difficulty", cfg.BossDifficulty, 0, 3},
+		{"arena_progression", cfg.ArenaProgression, 0, 7},
+		{"game_speed", cfg.GameSpeed, 0, 3},
+		{"teleporters", cfg.Teleporters, 0, 2},
+		{"world_size", cfg.WorldSize, 0, 3},
+		{"world_shape", cfg.WorldShape, 0, 2},
+		{"resources", cfg.Resources, 0, 4},
+		{"oil_regen_rage", cfg.OilRegenRate, 0, 3},
+		{"terrain", cfg.Terrain, 0, 2},
+		{"ui_mode", cfg.InterfaceMode, 0, 2},
+		{"environment", cfg.Environment, 0, 3},
+		{"creep_production_rate", cfg.CreepProductionRate, 0, 10},
+		{"players_mode", cfg.PlayersMode, serverapi.PmodeSinglePlayer, serverapi.PmodeTwoBots},
+		{"bot_profile", cfg.BotProfile, 0, len(BotProfiles) - 1},
 	}
 	for _, o := range toValidate {
 		if o.actual < o.min || o.actual > o.max {
-			return false
+			return fmt.Errorf("%s: %d is out of the [%d, %d] range", o.name, o.actual, o.min, o.max)
 		}
 	}
-
-	return true
+	return nil
 }
 
 func isValidChar(ch byte) bool {
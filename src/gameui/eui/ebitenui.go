@@ -601,6 +601,13 @@ func (b *SelectButton) ChangeValue(increase bool) {
 	}
 }
 
+// SetValue changes the value without triggering the OnPressed callback.
+func (b *SelectButton) SetValue(v int) {
+	b.slider.TrySetValue(v)
+	*b.value = b.slider.Value()
+	b.Widget.Text().Label = b.makeLabel()
+}
+
 func (b *SelectButton) makeLabel() string {
 	if b.key == "" {
 		return b.valueNames[b.slider.Value()]
@@ -17,6 +17,7 @@ require (
 	github.com/quasilyte/gsignal v0.0.0-20231010082051-3c00e9ebb4e5
 	github.com/quasilyte/xm v0.0.0-20240228102732-966acb9af598
 	golang.org/x/image v0.15.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
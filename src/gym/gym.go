@@ -0,0 +1,211 @@
+// Package gym implements a headless training environment for the colony bots.
+//
+// The environment follows the usual reinforcement learning loop:
+// Reset starts a new episode, Step executes an action and advances
+// the simulation by a fixed number of ticks.
+// The observations and actions are the external bot protocol types
+// (see serverapi.BotObservation), so a trained bot can be
+// connected to the runsim play mode later.
+//
+// Every Env owns its own game context, so many environments
+// can run in parallel in one process, one goroutine per Env.
+// A single Env is not thread-safe.
+package gym
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/quasilyte/ge"
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/runsim"
+	"github.com/quasilyte/roboden-game/scenes/staging"
+	"github.com/quasilyte/roboden-game/serverapi"
+	"github.com/quasilyte/roboden-game/session"
+)
+
+type Observation = serverapi.BotObservation
+
+type Action = serverapi.BotAction
+
+// DefaultStepTicks is a step duration (~0.5 seconds),
+// the same as the external bots decision interval.
+const DefaultStepTicks = 30
+
+var ErrEpisodeDone = errors.New("the episode is over, Reset should be called")
+
+type Config struct {
+	Level serverapi.ReplayLevelConfig
+
+	// PlayerID is the controlled player.
+	// The other human player slots are not allowed.
+	PlayerID int
+
+	// StepTicks is the number of simulated ticks per step.
+	// Zero means DefaultStepTicks.
+	StepTicks int
+
+	// MaxTicks limits the episode duration.
+	// Zero means that the episode lasts until the game is over.
+	MaxTicks int
+
+	Reward RewardConfig
+}
+
+// RewardConfig describes the step reward composition.
+// The zero config means DefaultRewardConfig.
+type RewardConfig struct {
+	// Score is a multiplier for the final game score.
+	// It's only rewarded once, at the end of the game.
+	Score float64
+
+	// ColonyHealth is a multiplier for the colonies health changes.
+	// The colony health is measured in the [0, 1] range,
+	// so a destroyed colony costs exactly ColonyHealth.
+	ColonyHealth float64
+
+	// CreepKill is a reward for every creep killed by the player.
+	CreepKill float64
+}
+
+var DefaultRewardConfig = RewardConfig{
+	Score:        0.01,
+	ColonyHealth: 1,
+	CreepKill:    0.1,
+}
+
+type Env struct {
+	state *session.State
+
+	config Config
+
+	controller *staging.Controller
+	runner     *ge.SimulationRunner
+
+	done bool
+
+	creepsKilled int
+	colonyHealth float64
+}
+
+// NewEnv creates a new environment.
+// Reset should be called before the first Step.
+func NewEnv() *Env {
+	return &Env{
+		state: runsim.NewState(runsim.NewContext()),
+		done:  true,
+	}
+}
+
+// Reset starts a new episode and returns its first observation.
+func (e *Env) Reset(config Config) (o Observation, err error) {
+	e.done = true
+
+	switch config.Level.RawGameMode {
+	case "tutorial", "blitz":
+		return o, fmt.Errorf("%s mode is not supported", config.Level.RawGameMode)
+	}
+	if _, ok := gamedata.GameModeInfoMap[config.Level.RawGameMode]; !ok {
+		return o, fmt.Errorf("unexpected game mode: %q", config.Level.RawGameMode)
+	}
+	if config.StepTicks == 0 {
+		config.StepTicks = DefaultStepTicks
+	}
+	if config.StepTicks < 0 || config.MaxTicks < 0 {
+		return o, errors.New("negative ticks limit")
+	}
+	if config.Reward == (RewardConfig{}) {
+		config.Reward = DefaultRewardConfig
+	}
+
+	levelConfig := gamedata.MakeLevelConfig(gamedata.ExecuteSimulation, config.Level)
+	levelConfig.Finalize()
+	if config.PlayerID < 0 || config.PlayerID >= len(levelConfig.Players) {
+		return o, fmt.Errorf("invalid player ID: %d", config.PlayerID)
+	}
+	for i, pk := range levelConfig.Players {
+		if pk == gamedata.PlayerHuman && i != config.PlayerID {
+			return o, fmt.Errorf("player %d is not controlled by anyone", i)
+		}
+	}
+
+	e.config = config
+	e.controller = staging.NewController(e.state, levelConfig, nil)
+	e.controller.SetDirectExternalPlayer(config.PlayerID)
+
+	err = runsim.Guard(func() {
+		var scene *ge.Scene
+		e.runner, scene = ge.NewSimulatedScene(e.state.Context, e.controller)
+		e.controller.Init(scene)
+		o = *e.controller.ObserveExternalPlayer(config.PlayerID)
+	})
+	if err != nil {
+		return o, err
+	}
+
+	e.done = false
+	e.creepsKilled = o.Stats.CreepsKilled
+	e.colonyHealth = colonyHealth(&o)
+	return o, nil
+}
+
+// Step executes the action and then runs the simulation for StepTicks.
+//
+// The returned observation LastAction reports whether the action was accepted.
+// After the episode is done, the final observation includes
+// the game results (unless the episode was cut by MaxTicks).
+func (e *Env) Step(a Action) (o Observation, reward float64, done bool, err error) {
+	if e.done {
+		return o, 0, true, ErrEpisodeDone
+	}
+
+	var results serverapi.GameResults
+	err = runsim.Guard(func() {
+		e.controller.ExecuteExternalAction(e.config.PlayerID, a)
+
+		target := e.controller.GetTicks() + e.config.StepTicks
+		if e.config.MaxTicks != 0 && target > e.config.MaxTicks {
+			target = e.config.MaxTicks
+		}
+		for e.controller.GetTicks() < target {
+			e.runner.Update(1.0 / 60.0)
+			results, done = e.controller.GetSimulationResult()
+			if done {
+				break
+			}
+		}
+
+		o = *e.controller.ObserveExternalPlayer(e.config.PlayerID)
+	})
+	if err != nil {
+		e.done = true
+		return o, 0, true, err
+	}
+
+	reward = e.config.Reward.CreepKill * float64(o.Stats.CreepsKilled-e.creepsKilled)
+	health := colonyHealth(&o)
+	reward += e.config.Reward.ColonyHealth * (health - e.colonyHealth)
+	e.creepsKilled = o.Stats.CreepsKilled
+	e.colonyHealth = health
+
+	if done {
+		o.Results = &results
+		reward += e.config.Reward.Score * float64(results.Score)
+	} else if e.config.MaxTicks != 0 && e.controller.GetTicks() >= e.config.MaxTicks {
+		done = true
+	}
+	o.Done = done
+	e.done = done
+
+	return o, reward, done, nil
+}
+
+func colonyHealth(o *Observation) float64 {
+	total := 0.0
+	for _, c := range o.Colonies {
+		if c.MaxHealth != 0 {
+			total += c.Health / c.MaxHealth
+		}
+	}
+	return total
+}
@@ -0,0 +1,59 @@
+package gym
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+func TestConcurrentEnvs(t *testing.T) {
+	// Every Env owns its game context, so running them
+	// in parallel should be safe (see the -race mode).
+	const numEnvs = 2
+	const numSteps = 5
+
+	var wg sync.WaitGroup
+	errs := make([]error, numEnvs)
+	for i := 0; i < numEnvs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			env := NewEnv()
+			config := Config{
+				Level: serverapi.ReplayLevelConfig{
+					RawGameMode: "classic",
+					Seed:        int64(7395164 + i),
+					DronesPower: 1,
+				},
+			}
+			o, err := env.Reset(config)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			tick := o.Tick
+			for step := 0; step < numSteps; step++ {
+				o, _, done, err := env.Step(Action{})
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				if done {
+					break
+				}
+				if o.Tick <= tick {
+					t.Errorf("env %d step %d: tick %d is not advanced", i, step, tick)
+				}
+				tick = o.Tick
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("env %d: %v", i, err)
+		}
+	}
+}
@@ -13,13 +13,19 @@ import (
 type Response struct {
 	Data []byte
 	Code int
+	ETag string
 }
 
 func PostJSON(targetURL string, jsonBytes []byte) (Response, error) {
+	return Post(targetURL, "application/json", jsonBytes)
+}
+
+// Post is like PostJSON, but it allows the non-JSON data to be sent.
+func Post(targetURL, contentType string, data []byte) (Response, error) {
 	var err error
 	for i := 0; i < 2; i++ {
 		var result Response
-		result, err = tryPostJSON(targetURL, jsonBytes)
+		result, err = tryPost(targetURL, contentType, data)
 		if err == nil {
 			return result, nil
 		}
@@ -28,18 +34,17 @@ func PostJSON(targetURL string, jsonBytes []byte) (Response, error) {
 	return Response{}, err
 }
 
-func tryPostJSON(targetURL string, jsonBytes []byte) (Response, error) {
-	resp, err := http.Post(targetURL, "application/json", bytes.NewReader(jsonBytes))
+func tryPost(targetURL, contentType string, data []byte) (Response, error) {
+	resp, err := http.Post(targetURL, contentType, bytes.NewReader(data))
 	if err != nil {
 		return Response{}, err
 	}
 	defer resp.Body.Close()
-	var data []byte
-	data, err = io.ReadAll(resp.Body)
+	respData, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return Response{}, err
 	}
-	return Response{Data: data, Code: resp.StatusCode}, nil
+	return Response{Data: respData, Code: resp.StatusCode}, nil
 }
 
 func GetBytes(targetURL string) ([]byte, error) {
@@ -50,3 +55,42 @@ func GetBytes(targetURL string) ([]byte, error) {
 	defer resp.Body.Close()
 	return ioutil.ReadAll(resp.Body)
 }
+
+// GetBytesAccept is like GetBytes, but it also sends the Accept header.
+// The caller should be ready to handle any response content type.
+func GetBytesAccept(targetURL, accept string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// GetIfNoneMatch performs a conditional GET request.
+// If etag matches the current resource version,
+// the response has a 304 code and no data.
+func GetIfNoneMatch(targetURL, etag string) (Response, error) {
+	req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+	if err != nil {
+		return Response{}, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Data: data, Code: resp.StatusCode, ETag: resp.Header.Get("ETag")}, nil
+}
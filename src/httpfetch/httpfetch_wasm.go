@@ -10,16 +10,24 @@ import (
 type Response struct {
 	Data []byte
 	Code int
+	ETag string
 }
 
 func PostJSON(targetURL string, jsonBytes []byte) (Response, error) {
+	return Post(targetURL, "application/json", jsonBytes)
+}
+
+// Post is like PostJSON, but it allows the non-JSON data to be sent.
+func Post(targetURL, contentType string, data []byte) (Response, error) {
+	u8array := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(u8array, data)
 	res := doFetch(targetURL, map[string]any{
 		"method": "POST",
 		"headers": map[string]any{
 			"Accept":       "application/json",
-			"Content-Type": "application/json",
+			"Content-Type": contentType,
 		},
-		"body": string(jsonBytes),
+		"body": u8array,
 	})
 	return Response{Data: res.data, Code: res.status}, res.err
 }
@@ -29,9 +37,37 @@ func GetBytes(targetURL string) ([]byte, error) {
 	return res.data, res.err
 }
 
+// GetBytesAccept is like GetBytes, but it also sends the Accept header.
+// The caller should be ready to handle any response content type.
+func GetBytesAccept(targetURL, accept string) ([]byte, error) {
+	res := doFetch(targetURL, map[string]any{
+		"headers": map[string]any{
+			"Accept": accept,
+		},
+	})
+	return res.data, res.err
+}
+
+// GetIfNoneMatch performs a conditional GET request.
+// If etag matches the current resource version,
+// the response has a 304 code and no data.
+func GetIfNoneMatch(targetURL, etag string) (Response, error) {
+	var params map[string]any
+	if etag != "" {
+		params = map[string]any{
+			"headers": map[string]any{
+				"If-None-Match": etag,
+			},
+		}
+	}
+	res := doFetch(targetURL, params)
+	return Response{Data: res.data, Code: res.status, ETag: res.etag}, res.err
+}
+
 type fetchResult struct {
 	data   []byte
 	status int
+	etag   string
 	err    error
 }
 
@@ -47,6 +83,10 @@ func doFetch(targetURL string, params map[string]any) fetchResult {
 
 	fetch.Call("then", js.FuncOf(func(this js.Value, args []js.Value) any {
 		status := args[0].Get("status").Int()
+		etag := ""
+		if v := args[0].Get("headers").Call("get", "ETag"); v.Type() == js.TypeString {
+			etag = v.String()
+		}
 		args[0].Call("arrayBuffer").Call("then", js.FuncOf(func(this js.Value, args []js.Value) any {
 			size := args[0].Get("byteLength").Int()
 			data := make([]byte, size)
@@ -55,7 +95,7 @@ func doFetch(targetURL string, params map[string]any) fetchResult {
 			if numBytes != size {
 				ch <- fetchResult{status: status, err: errors.New("incomplete bytes copy")}
 			}
-			ch <- fetchResult{status: status, data: data}
+			ch <- fetchResult{status: status, etag: etag, data: data}
 			return nil
 		}))
 		return nil
@@ -1,6 +1,8 @@
 package pathing
 
 import (
+	"errors"
+
 	"github.com/quasilyte/gmath"
 )
 
@@ -59,6 +61,21 @@ func (g *Grid) Size() (numCols, numRows int) {
 	return int(g.numCols), int(g.numRows)
 }
 
+// MarshalBinary encodes the cell tags of the grid.
+// The grid dimensions are not included: the decoded data
+// can only be loaded into a grid of the same size.
+func (g *Grid) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), g.bytes...), nil
+}
+
+func (g *Grid) UnmarshalBinary(data []byte) error {
+	if len(data) != len(g.bytes) {
+		return errors.New("grid size mismatch")
+	}
+	copy(g.bytes, data)
+	return nil
+}
+
 func (g *Grid) SetCellTag(c GridCoord, tag uint8) {
 	i := uint(c.Y)*g.numCols + uint(c.X)
 	byteIndex := i / 4
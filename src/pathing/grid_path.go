@@ -1,6 +1,7 @@
 package pathing
 
 import (
+	"errors"
 	"strings"
 )
 
@@ -34,6 +35,27 @@ func (p GridPath) String() string {
 	return "{" + strings.Join(parts, ",") + "}"
 }
 
+// MarshalBinary encodes the path steps along with its current position.
+func (p GridPath) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, gridPathBytes+2)
+	data = append(data, p.bytes[:]...)
+	data = append(data, p.len, p.pos)
+	return data, nil
+}
+
+func (p *GridPath) UnmarshalBinary(data []byte) error {
+	if len(data) != gridPathBytes+2 {
+		return errors.New("invalid grid path data length")
+	}
+	copy(p.bytes[:], data)
+	p.len = data[gridPathBytes]
+	p.pos = data[gridPathBytes+1]
+	if p.pos > p.len || p.len > gridPathMaxLen {
+		return errors.New("invalid grid path position")
+	}
+	return nil
+}
+
 func (p *GridPath) Len() int {
 	return int(p.len)
 }
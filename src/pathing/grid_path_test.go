@@ -116,3 +116,23 @@ func TestGridPath(t *testing.T) {
 		}
 	}
 }
+
+func TestGridPathBinary(t *testing.T) {
+	p := makeGridPath([]Direction{DirLeft, DirLeft, DirUp, DirRight, DirDown})
+	p.Next()
+	p.Next()
+	data, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded GridPath
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != p {
+		t.Fatalf("decoded path mismatch: %s vs %s", decoded, p)
+	}
+	if err := decoded.UnmarshalBinary(data[1:]); err == nil {
+		t.Fatal("expected an error for a truncated path data")
+	}
+}
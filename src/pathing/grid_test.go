@@ -179,6 +179,35 @@ func TestGridValueChange(t *testing.T) {
 	}
 }
 
+func TestGridBinary(t *testing.T) {
+	p := pathing.NewGrid(5*pathing.CellSize, 3*pathing.CellSize, 1)
+	l := pathing.MakeGridLayer(0, 1, 2, 3)
+	p.SetCellTag(pathing.GridCoord{X: 1, Y: 2}, 3)
+	p.SetCellTag(pathing.GridCoord{X: 4, Y: 0}, 2)
+	data, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p2 := pathing.NewGrid(5*pathing.CellSize, 3*pathing.CellSize, 0)
+	if err := p2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 5; x++ {
+			coord := pathing.GridCoord{X: x, Y: y}
+			if have, want := p2.GetCellValue(coord, l), p.GetCellValue(coord, l); have != want {
+				t.Fatalf("cell %v: have %v, want %v", coord, have, want)
+			}
+		}
+	}
+
+	p3 := pathing.NewGrid(6*pathing.CellSize, 3*pathing.CellSize, 0)
+	if err := p3.UnmarshalBinary(data); err == nil {
+		t.Fatal("expected an error for a grid size mismatch")
+	}
+}
+
 func TestSmallGrid(t *testing.T) {
 	p := pathing.NewGrid(9*pathing.CellSize, 6*pathing.CellSize, 0)
 
@@ -0,0 +1,237 @@
+// Package queuedb implements the replay queue database operations
+// that are shared by the server and the serverutil tool.
+package queuedb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+// RequeueFilter selects the archived replays to requeue.
+type RequeueFilter struct {
+	// Reason is the archive reason of the replays.
+	// It's ignored if Kind is not empty.
+	Reason int
+
+	// Kind is the failure kind of the replays.
+	// It's more precise than the reason: for example,
+	// the exec_error reason covers the timeouts and crashes.
+	Kind string
+
+	// CanSimulate reports whether the replays of this game build
+	// can be verified right now.
+	CanSimulate func(gameVersion int) bool
+
+	// OnSkip is called for every archived replay that can't be requeued.
+	// It's optional.
+	OnSkip func(archiveID int, reason string)
+}
+
+// RequeueCandidate is an archived replay that can be moved back to the queue.
+type RequeueCandidate struct {
+	ArchiveID      int
+	ReplayID       int
+	PlayerName     string
+	GameVersion    int
+	Checksum       string
+	CompressedData []byte
+}
+
+// RequeueStats describes the results of the archive scan.
+type RequeueStats struct {
+	Scanned   int
+	Malformed int
+	Skipped   int
+}
+
+// FindRequeueCandidates returns up to limit archived replays that can be requeued.
+//
+// The builds are filtered by the game_version column,
+// so only the replays of the builds that can be simulated are decoded.
+// The replays that belong to another player (see replay_checksums)
+// and the duplicates are skipped.
+func FindRequeueCandidates(conn *sql.DB, filter *RequeueFilter, limit int) ([]RequeueCandidate, RequeueStats, error) {
+	var stats RequeueStats
+
+	filterColumn := "fail_reason"
+	var filterValue any = filter.Reason
+	if filter.Kind != "" {
+		filterColumn = "fail_kind"
+		filterValue = filter.Kind
+	}
+
+	versions, err := archivedVersions(conn, filterColumn, filterValue)
+	if err != nil {
+		return nil, stats, err
+	}
+	var args []any
+	for _, v := range versions {
+		if filter.CanSimulate(v) {
+			args = append(args, v)
+		}
+	}
+	if len(args) == 0 {
+		return nil, stats, nil
+	}
+	query := fmt.Sprintf(`
+		SELECT id, replay_id, player_name, replay_json
+		FROM failed_replay_archive
+		WHERE %s = ? AND game_version IN (%s) AND id > ?
+		ORDER BY id
+		LIMIT 64
+	`, filterColumn, strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", "))
+
+	skip := func(archiveID int, reason string) {
+		stats.Skipped++
+		if filter.OnSkip != nil {
+			filter.OnSkip(archiveID, reason)
+		}
+	}
+
+	// The same match could be archived more than once.
+	seen := make(map[string]struct{})
+
+	var candidates []RequeueCandidate
+	afterID := 0
+	for len(candidates) < limit {
+		page, err := archivedPage(conn, query, append([]any{filterValue}, append(args, afterID)...))
+		if err != nil {
+			return nil, stats, err
+		}
+		if len(page) == 0 {
+			break
+		}
+		afterID = page[len(page)-1].ArchiveID
+
+		for _, c := range page {
+			if len(candidates) >= limit {
+				break
+			}
+			stats.Scanned++
+
+			data, err := gzipUncompress(c.CompressedData)
+			if err != nil {
+				stats.Malformed++
+				skip(c.ArchiveID, fmt.Sprintf("uncompress: %v", err))
+				continue
+			}
+			var replay serverapi.GameReplay
+			if err := json.Unmarshal(data, &replay); err != nil {
+				stats.Malformed++
+				skip(c.ArchiveID, fmt.Sprintf("unmarshal: %v", err))
+				continue
+			}
+			c.GameVersion = replay.GameVersion
+			c.Checksum = serverapi.ReplayChecksum(&replay)
+
+			if _, ok := seen[c.Checksum]; ok {
+				skip(c.ArchiveID, "duplicate")
+				continue
+			}
+			seen[c.Checksum] = struct{}{}
+			var owner string
+			err = conn.QueryRow("SELECT player_name FROM replay_checksums WHERE replay_hash = ?", c.Checksum).Scan(&owner)
+			if err != nil && err != sql.ErrNoRows {
+				return nil, stats, fmt.Errorf("check replay checksum: %w", err)
+			}
+			if owner != "" && owner != c.PlayerName {
+				skip(c.ArchiveID, fmt.Sprintf("%q replay is owned by %q", c.PlayerName, owner))
+				continue
+			}
+
+			candidates = append(candidates, c)
+		}
+	}
+
+	return candidates, stats, nil
+}
+
+// Requeue moves the archived replay back to the queue.
+// The replay gets a new queue ID.
+func Requeue(conn *sql.DB, c *RequeueCandidate, createdAt int64) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The checksum is normally registered during the first push,
+	// but let's not rely on that.
+	_, err = tx.Exec(`
+		INSERT OR IGNORE INTO replay_checksums
+		       ('replay_hash', 'player_name')
+		VALUES (?, ?)
+	`, c.Checksum, c.PlayerName)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO replay_queue
+		       ('player_name', 'created_at', 'replay_json')
+		VALUES (?, ?, ?)
+	`, c.PlayerName, createdAt, c.CompressedData)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM failed_replay_archive WHERE id = ?", c.ArchiveID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func archivedVersions(conn *sql.DB, filterColumn string, filterValue any) ([]int, error) {
+	rows, err := conn.Query(fmt.Sprintf(`
+		SELECT DISTINCT game_version
+		FROM failed_replay_archive
+		WHERE %s = ?
+	`, filterColumn), filterValue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+func archivedPage(conn *sql.DB, query string, args []any) ([]RequeueCandidate, error) {
+	rows, err := conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var page []RequeueCandidate
+	for rows.Next() {
+		var c RequeueCandidate
+		if err := rows.Scan(&c.ArchiveID, &c.ReplayID, &c.PlayerName, &c.CompressedData); err != nil {
+			return nil, err
+		}
+		page = append(page, c)
+	}
+	return page, rows.Err()
+}
+
+func gzipUncompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
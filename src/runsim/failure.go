@@ -0,0 +1,45 @@
+package runsim
+
+import (
+	"errors"
+
+	"github.com/quasilyte/roboden-game/scenes/staging"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+// DescribeFailure converts the RunReplay error into its machine-readable form.
+//
+// The unrecognized errors are reported as serverapi.FailureCrash.
+func DescribeFailure(err error) *serverapi.SimulationFailure {
+	failure := &serverapi.SimulationFailure{
+		Kind:    serverapi.FailureCrash,
+		Message: err.Error(),
+	}
+
+	var actionErr *staging.IllegalActionError
+	var checkpointErr *staging.BadCheckpointError
+	switch {
+	case errors.As(err, &actionErr):
+		failure.Kind = serverapi.FailureIllegalAction
+		failure.Tick = actionErr.Tick
+		failure.Action = &serverapi.FailedAction{
+			PlayerID: actionErr.PlayerID,
+			Index:    actionErr.ActionIndex,
+			Action:   actionErr.Action,
+		}
+	case errors.As(err, &checkpointErr):
+		failure.Kind = serverapi.FailureBadCheckpoint
+		failure.Tick = checkpointErr.Tick
+		failure.Checkpoint = &serverapi.FailedCheckpoint{
+			Index:    checkpointErr.Index,
+			Expected: checkpointErr.Expected,
+			Actual:   checkpointErr.Actual,
+		}
+	case errors.Is(err, ErrTimeout):
+		failure.Kind = serverapi.FailureTimeout
+	case errors.Is(err, ErrLevelGenMismatch):
+		failure.Kind = serverapi.FailureLevelGenMismatch
+	}
+
+	return failure
+}
@@ -0,0 +1,77 @@
+package runsim
+
+import (
+	"sync"
+
+	"github.com/quasilyte/roboden-game/session"
+)
+
+type PoolConfig struct {
+	// NumWorkers is the number of jobs executed in parallel.
+	// Zero or a negative value means one worker.
+	NumWorkers int
+
+	// DebugLogs enables the debug logs for the workers session states.
+	DebugLogs bool
+}
+
+// RunJobs executes the simulation jobs in parallel.
+//
+// Every worker has its own game context and session state:
+// they're not thread-safe.
+// A job panic is reported as its error (see Guard).
+// After a failed job, the worker creates a new state, as the
+// failed simulation could leave the state in a weird condition.
+//
+// The onDone callback is called for every finished job in the completion order.
+// It's executed by the RunJobs caller goroutine, so it doesn't need any synchronization.
+func RunJobs(config PoolConfig, jobs []func(*session.State) error, onDone func(i int, err error)) {
+	numWorkers := config.NumWorkers
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+
+	newState := func() *session.State {
+		state := NewState(NewContext())
+		state.Persistent.Settings.DebugLogs = config.DebugLogs
+		return state
+	}
+
+	type jobResult struct {
+		index int
+		err   error
+	}
+
+	jobCh := make(chan int)
+	doneCh := make(chan jobResult)
+	var wg sync.WaitGroup
+	wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			defer wg.Done()
+			state := newState()
+			for i := range jobCh {
+				var err error
+				if panicErr := Guard(func() { err = jobs[i](state) }); panicErr != nil {
+					err = panicErr
+				}
+				if err != nil {
+					state = newState()
+				}
+				doneCh <- jobResult{index: i, err: err}
+			}
+		}()
+	}
+	go func() {
+		for i := range jobs {
+			jobCh <- i
+		}
+		close(jobCh)
+		wg.Wait()
+		close(doneCh)
+	}()
+
+	for result := range doneCh {
+		onDone(result.index, result.err)
+	}
+}
@@ -1,18 +1,26 @@
 package runsim
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"time"
 
 	"github.com/quasilyte/ge"
+	"github.com/quasilyte/ge/langs"
 	"github.com/quasilyte/roboden-game/assets"
+	"github.com/quasilyte/roboden-game/gamedata"
 	"github.com/quasilyte/roboden-game/gameinput"
 	"github.com/quasilyte/roboden-game/scenes/staging"
 	"github.com/quasilyte/roboden-game/serverapi"
 	"github.com/quasilyte/roboden-game/session"
 )
 
-var errTimeout = errors.New("simulation takes too long")
+var (
+	ErrTimeout          = errors.New("simulation takes too long")
+	ErrLevelGenMismatch = errors.New("levelgen checksum mismatch")
+)
 
 func NewState(ctx *ge.Context) *session.State {
 	state := &session.State{
@@ -35,6 +43,22 @@ func NewState(ctx *ge.Context) *session.State {
 	return state
 }
 
+// NewContext creates a muted fixed-delta game context
+// with all simulation-related assets registered.
+//
+// The context is not thread-safe: concurrent simulations
+// should use a separate context each.
+func NewContext() *ge.Context {
+	ctx := ge.NewContext(ge.ContextConfig{
+		Mute:          true,
+		TimeDeltaMode: ge.TimeDeltaFixed60,
+	})
+	ctx.Loader.OpenAssetFunc = assets.MakeOpenAssetFunc(ctx, "")
+	ctx.Dict = langs.NewDictionary("en", 2)
+	PrepareAssets(ctx)
+	return ctx
+}
+
 func PrepareAssets(ctx *ge.Context) {
 	assetsConfig := &assets.Config{
 		XM: true,
@@ -45,6 +69,29 @@ func PrepareAssets(ctx *ge.Context) {
 	assets.RegisterShaderResources(ctx, assetsConfig, &progress)
 }
 
+// Guard calls f and turns its panic into an error.
+//
+// The simulation signals the errors like staging.ErrIllegalAction
+// by panicking; the returned error wraps the panic value,
+// so it can be inspected with errors.Is.
+func Guard(f func()) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if panicErr, ok := r.(error); ok {
+			err = fmt.Errorf("simulation panic: %w", panicErr)
+		} else {
+			err = fmt.Errorf("simulation panic: %v", r)
+		}
+	}()
+	f()
+	return nil
+}
+
+// Run executes the game simulation until the game is over.
+// A non-positive timeout means that there is no time limit.
 func Run(state *session.State, levelGenChecksum, timeoutSeconds int, controller *staging.Controller) (serverapi.GameResults, error) {
 	var simResult serverapi.GameResults
 
@@ -53,7 +100,7 @@ func Run(state *session.State, levelGenChecksum, timeoutSeconds int, controller
 
 	if levelGenChecksum != 0 {
 		if controller.GetLevelGenChecksum() != levelGenChecksum {
-			return simResult, errors.New("levelgen checksum mismatch")
+			return simResult, ErrLevelGenMismatch
 		}
 	}
 
@@ -70,9 +117,159 @@ OuterLoop:
 				break OuterLoop
 			}
 		}
-		if time.Since(start) >= timeout {
-			return simResult, errTimeout
+		if timeout > 0 && time.Since(start) >= timeout {
+			return simResult, ErrTimeout
 		}
 	}
 	return simResult, nil
 }
+
+// ReplayResult is the RunReplay result.
+type ReplayResult struct {
+	Results serverapi.GameResults
+
+	// Desync is the first state hash mismatch, if any.
+	// It's not an error by itself, but it shows where
+	// the simulation diverged from the recorded game.
+	Desync *staging.StateDesync
+}
+
+// RunReplay executes the replay and returns the simulation results.
+//
+// The replay execution signals the errors like staging.ErrIllegalAction
+// by panicking; RunReplay recovers from these panics and returns them
+// as errors, so they can be inspected with errors.Is.
+// The Desync info is available even if there was an error.
+// Use DescribeFailure to get the error details.
+func RunReplay(state *session.State, replay serverapi.GameReplay, timeoutSeconds int) (result ReplayResult, err error) {
+	// A malformed replay config can make the level setup panic too,
+	// so the guard covers the entire function.
+	var controller *staging.Controller
+	var runErr error
+	err = Guard(func() {
+		config := gamedata.MakeLevelConfig(gamedata.ExecuteSimulation, replay.Config)
+		config.Finalize()
+
+		controller = staging.NewController(state, config, nil)
+		controller.SetReplayActions(replay)
+
+		result.Results, runErr = Run(state, replay.LevelGenChecksum, timeoutSeconds, controller)
+	})
+	if controller != nil {
+		result.Desync = controller.GetStateDesync()
+	}
+	if err != nil {
+		return result, err
+	}
+	return result, runErr
+}
+
+// ExternalBot is a connection to the external bot program.
+// The bot controls the player with the specified ID.
+type ExternalBot struct {
+	PlayerID int
+	R        io.Reader
+	W        io.Writer
+}
+
+// RunExternalBots plays a game where some of the players are
+// controlled by the external bots (see serverapi.BotObservation).
+//
+// All human player slots should be taken by the bots;
+// the bots can also replace the computer players.
+// After the game is over, every bot receives the final observation.
+func RunExternalBots(state *session.State, replayConfig serverapi.ReplayLevelConfig, bots []ExternalBot, timeoutSeconds int) (serverapi.GameResults, error) {
+	results, err := RunMatch(state, Match{
+		Config:         replayConfig,
+		Bots:           bots,
+		TimeoutSeconds: timeoutSeconds,
+	})
+	return results.Results, err
+}
+
+// Match describes a simulated game with the customized players.
+type Match struct {
+	Config serverapi.ReplayLevelConfig
+
+	// Bots are the external bots, see RunExternalBots.
+	Bots []ExternalBot
+
+	// BotProfiles override the computer players profiles.
+	// The map is indexed by the player ID.
+	BotProfiles map[int]*gamedata.BotProfile
+
+	// A non-positive timeout means that there is no time limit.
+	TimeoutSeconds int
+}
+
+type MatchResults struct {
+	Results serverapi.GameResults
+
+	// Players are the final player stats indexed by the player ID.
+	Players []serverapi.BotStats
+}
+
+// RunMatch plays a game described by the match.
+// There should be no human players left in this game:
+// all of them should be replaced by the external bots.
+func RunMatch(state *session.State, m Match) (results MatchResults, err error) {
+	if _, ok := gamedata.GameModeInfoMap[m.Config.RawGameMode]; !ok {
+		return results, fmt.Errorf("unexpected game mode: %q", m.Config.RawGameMode)
+	}
+	if len(m.Bots) != 0 {
+		switch m.Config.RawGameMode {
+		case "tutorial", "blitz":
+			return results, fmt.Errorf("%s mode can't be played by the external bots", m.Config.RawGameMode)
+		}
+	}
+
+	config := gamedata.MakeLevelConfig(gamedata.ExecuteSimulation, m.Config)
+	config.Finalize()
+
+	controller := staging.NewController(state, config, nil)
+	hasBot := make([]bool, len(config.Players))
+	for _, b := range m.Bots {
+		if b.PlayerID < 0 || b.PlayerID >= len(config.Players) {
+			return results, fmt.Errorf("invalid bot player ID: %d", b.PlayerID)
+		}
+		hasBot[b.PlayerID] = true
+		controller.SetExternalPlayer(b.PlayerID, b.R, b.W)
+	}
+	for i, pk := range config.Players {
+		if pk == gamedata.PlayerHuman && !hasBot[i] {
+			return results, fmt.Errorf("player %d needs an external bot", i)
+		}
+	}
+	for playerID, profile := range m.BotProfiles {
+		controller.SetBotProfile(playerID, profile)
+	}
+
+	var runErr error
+	err = Guard(func() {
+		results.Results, runErr = Run(state, 0, m.TimeoutSeconds, controller)
+	})
+	if err != nil {
+		return results, err
+	}
+	if runErr != nil {
+		return results, runErr
+	}
+	results.Players = make([]serverapi.BotStats, len(config.Players))
+	for i := range results.Players {
+		results.Players[i] = controller.GetPlayerStats(i)
+	}
+	for _, b := range m.Bots {
+		final := serverapi.BotObservation{
+			Version:  serverapi.BotProtocolVersion,
+			Tick:     results.Results.Ticks,
+			PlayerID: b.PlayerID,
+			Done:     true,
+			Results:  &results.Results,
+			Stats:    results.Players[b.PlayerID],
+		}
+		if err := json.NewEncoder(b.W).Encode(&final); err != nil {
+			return results, &staging.ExternalBotError{PlayerID: b.PlayerID, Err: fmt.Errorf("send results: %w", err)}
+		}
+	}
+	return results, nil
+}
@@ -7,9 +7,14 @@ import (
 
 	"github.com/ebitenui/ebitenui/widget"
 	"github.com/quasilyte/ge"
+	"github.com/quasilyte/gsignal"
 	"github.com/quasilyte/roboden-game/assets"
+	"github.com/quasilyte/roboden-game/clientkit"
 	"github.com/quasilyte/roboden-game/controls"
+	"github.com/quasilyte/roboden-game/gamedata"
 	"github.com/quasilyte/roboden-game/gameui/eui"
+	"github.com/quasilyte/roboden-game/gtask"
+	"github.com/quasilyte/roboden-game/scenes/staging"
 	"github.com/quasilyte/roboden-game/serverapi"
 	"github.com/quasilyte/roboden-game/session"
 	"github.com/quasilyte/roboden-game/timeutil"
@@ -28,6 +33,11 @@ type LeaderboardBrowserController struct {
 
 	boardData *serverapi.LeaderboardResp
 	fetchErr  error
+
+	replayOwners        []string
+	selectedReplayOwner int
+	replayStatusLabel   *widget.Text
+	replayFetching      bool
 }
 
 func NewLeaderboardBrowserController(state *session.State, season int, gameMode string, boardData *serverapi.LeaderboardResp, fetchErr error) *LeaderboardBrowserController {
@@ -164,17 +174,90 @@ func (c *LeaderboardBrowserController) initUI() {
 		}
 
 		c.rowContainer.AddChild(panel)
+
+		if boardData != nil {
+			for _, e := range boardData.Entries {
+				if e.HasReplay {
+					c.replayOwners = append(c.replayOwners, e.PlayerName)
+				}
+			}
+		}
+	}
+
+	var navWidgets []eui.Widget
+
+	if len(c.replayOwners) != 0 {
+		replayGrid := eui.NewGridContainer(2, widget.GridLayoutOpts.Spacing(8, 4),
+			widget.GridLayoutOpts.Stretch([]bool{true, false}, nil))
+
+		ownerSelect := eui.NewSelectButton(eui.SelectButtonConfig{
+			PlaySound:  true,
+			Resources:  uiResources,
+			Input:      c.state.MenuInput,
+			Value:      &c.selectedReplayOwner,
+			Label:      d.Get("menu.leaderboard.replay_of"),
+			ValueNames: c.replayOwners,
+		})
+		c.scene.AddObject(ownerSelect)
+		replayGrid.AddChild(ownerSelect.Widget)
+
+		watchButton := eui.NewButton(uiResources, c.scene, d.Get("menu.profile.watch_replay"), func() {
+			c.watchReplay(c.replayOwners[c.selectedReplayOwner])
+		})
+		replayGrid.AddChild(watchButton)
+
+		c.rowContainer.AddChild(replayGrid)
+		navWidgets = append(navWidgets, ownerSelect.Widget, watchButton)
+
+		c.replayStatusLabel = eui.NewCenteredLabel("", assets.Font1)
+		c.rowContainer.AddChild(c.replayStatusLabel)
 	}
 
 	backButton := eui.NewButton(uiResources, c.scene, d.Get("menu.back"), func() {
 		c.back()
 	})
 	c.rowContainer.AddChild(backButton)
+	navWidgets = append(navWidgets, backButton)
 
-	navTree := createSimpleNavTree([]eui.Widget{backButton})
+	navTree := createSimpleNavTree(navWidgets)
 	setupUI(c.scene, root, c.state.MenuInput, navTree)
 }
 
+func (c *LeaderboardBrowserController) watchReplay(playerName string) {
+	if c.replayFetching {
+		return
+	}
+	c.replayFetching = true
+
+	d := c.scene.Dict()
+	c.replayStatusLabel.Label = d.Get("menu.leaderboard.placeholder")
+
+	var replay *serverapi.GameReplay
+	var fetchErr error
+	fetchTask := gtask.StartTask(func(ctx *gtask.TaskContext) {
+		replay, fetchErr = clientkit.GetReplay(c.state, c.selectedSeason, c.gameMode, playerName)
+	})
+	fetchTask.EventCompleted.Connect(nil, func(gsignal.Void) {
+		c.replayFetching = false
+		if fetchErr != nil {
+			c.state.Logf("fetch %q replay: %v", playerName, fetchErr)
+			c.replayStatusLabel.Label = d.Get("menu.leaderboard.replay_fetch_error")
+			return
+		}
+		if replay.GameVersion != gamedata.BuildNumber || !gamedata.IsRunnableReplay(*replay) {
+			c.replayStatusLabel.Label = d.Get("menu.leaderboard.replay_fetch_error") + ": " + d.Get("menu.replace.version_mismatch")
+			return
+		}
+		config := gamedata.MakeLevelConfig(gamedata.ExecuteReplay, replay.Config)
+		config.Finalize()
+		back := NewLeaderboardLoadingController(c.state, c.selectedSeason, c.gameMode)
+		controller := staging.NewController(c.state, config, back)
+		controller.SetReplayActions(*replay)
+		c.scene.Context().ChangeScene(controller)
+	})
+	c.scene.AddObject(fetchTask)
+}
+
 func (c *LeaderboardBrowserController) back() {
 	c.scene.Context().ChangeScene(NewLeaderboardMenuController(c.state))
 }
@@ -81,15 +81,16 @@ func (c *LeaderboardLoadingController) initUI() {
 	var boardData *serverapi.LeaderboardResp
 	var fetchErr error
 	fetchTask := gtask.StartTask(func(ctx *gtask.TaskContext) {
-		boardData, fetchErr = clientkit.GetLeaderboard(c.state, c.selectedSeason, c.gameMode)
+		boardData, fetchErr = clientkit.GetLeaderboard(c.state, c.selectedSeason, c.gameMode, c.getBoardCache())
 		if fetchErr != nil {
 			// Try using the cached data.
 			cached := c.getBoardCache()
 			if cached.NumSeasons != 0 {
 				boardData = cached
 			}
-		} else {
+		} else if boardData != c.getBoardCache() {
 			// Save fetched data to the cache.
+			// If it's the cached data itself, the board was not changed.
 			*c.getBoardCache() = *boardData
 			c.state.SaveGameItem("save.json", c.state.Persistent)
 		}
@@ -48,6 +48,7 @@ type LobbyMenuController struct {
 	schemaButton     *widget.Button
 	randSchemaButton *widget.Button
 	backButton       *widget.Button
+	botProfileButton *eui.SelectButton
 
 	colonyTab     *widget.TabBookTab
 	worldTab      *widget.TabBookTab
@@ -492,6 +493,24 @@ func (c *LobbyMenuController) maybeDisableColonyTab(disable bool) {
 	c.colonyTab.Disabled = disable
 }
 
+// updateBotProfileButton shows the bot profile option only
+// for the players modes that have a computer player.
+func (c *LobbyMenuController) updateBotProfileButton() {
+	if c.botProfileButton == nil {
+		return
+	}
+	switch c.config.PlayersMode {
+	case serverapi.PmodeSingleBot, serverapi.PmodePlayerAndBot, serverapi.PmodeTwoBots:
+		c.botProfileButton.Widget.GetWidget().Visibility = widget.Visibility_Show
+		c.botProfileButton.Widget.GetWidget().Disabled = false
+	default:
+		// A hidden option should not leak into the replay config.
+		c.botProfileButton.SetValue(0)
+		c.botProfileButton.Widget.GetWidget().Visibility = widget.Visibility_Hide
+		c.botProfileButton.Widget.GetWidget().Disabled = true
+	}
+}
+
 func (c *LobbyMenuController) createExtraTab(uiResources *eui.Resources) *widget.TabBookTab {
 	d := c.scene.Dict()
 
@@ -538,6 +557,18 @@ func (c *LobbyMenuController) createExtraTab(uiResources *eui.Resources) *widget
 		})
 	}
 
+	{
+		valueNames := make([]string, len(gamedata.BotProfiles))
+		for i, profile := range gamedata.BotProfiles {
+			valueNames[i] = d.Get("menu.lobby.bot_profile", profile.Name)
+		}
+		b := c.newSelectButton(&c.config.BotProfile, "menu.lobby.bot_profile", nil, valueNames)
+		tab.AddChild(b.Widget)
+		verticalButtons = append(verticalButtons, navBlock.NewElem(b.Widget))
+		c.botProfileButton = b
+		c.updateBotProfileButton()
+	}
+
 	if c.config.RawGameMode != "reverse" {
 		disabled := []int{}
 		if c.config.RawGameMode == "arena" || c.config.RawGameMode == "inf_arena" {
@@ -881,6 +912,10 @@ func (c *LobbyMenuController) newToggleItemButton(value *bool, key string, icon
 }
 
 func (c *LobbyMenuController) newOptionButtonWithDisabled(value *int, key string, disabled []int, valueNames []string) *widget.Button {
+	return c.newSelectButton(value, key, disabled, valueNames).Widget
+}
+
+func (c *LobbyMenuController) newSelectButton(value *int, key string, disabled []int, valueNames []string) *eui.SelectButton {
 	b := eui.NewSelectButton(eui.SelectButtonConfig{
 		PlaySound:      true,
 		Resources:      c.state.Resources.UI,
@@ -891,13 +926,14 @@ func (c *LobbyMenuController) newOptionButtonWithDisabled(value *int, key string
 		ValueNames:     valueNames,
 		OnPressed: func() {
 			c.updateDifficultyScore(c.calcDifficultyScore())
+			c.updateBotProfileButton()
 		},
 		OnHover: func() {
 			c.setHelpText(c.optionDescriptionText(key))
 		},
 	})
 	c.scene.AddObject(b)
-	return b.Widget
+	return b
 }
 
 func (c *LobbyMenuController) newOptionButton(value *int, key string, valueNames []string) widget.PreferredSizeLocateableWidget {
@@ -8,6 +8,7 @@ import (
 	"github.com/quasilyte/ge/xslices"
 	"github.com/quasilyte/roboden-game/assets"
 	"github.com/quasilyte/roboden-game/controls"
+	"github.com/quasilyte/roboden-game/descriptions"
 	"github.com/quasilyte/roboden-game/gamedata"
 	"github.com/quasilyte/roboden-game/gameui/eui"
 	"github.com/quasilyte/roboden-game/scenes/staging"
@@ -110,6 +111,24 @@ func (c *PlayMenuController) initUI() {
 
 	var buttons []eui.Widget
 
+	if save, err := c.state.LoadMatchSave(); err == nil {
+		b := eui.NewButtonWithConfig(uiResources, eui.ButtonConfig{
+			Scene: c.scene,
+			Text:  d.Get("menu.play.continue"),
+			OnPressed: func() {
+				back := NewPlayMenuController(c.state)
+				config := gamedata.MakeLevelConfig(gamedata.ExecuteNormal, save.Config)
+				config.Finalize()
+				controller := staging.NewController(c.state, config, back)
+				controller.ResumeMatch(save)
+				c.scene.Context().ChangeScene(controller)
+			},
+			OnHover: func() { c.setHelpText(descriptions.MatchSaveText(d, &save)) },
+		})
+		buttonsContainer.AddChild(b)
+		buttons = append(buttons, b)
+	}
+
 	{
 		b := eui.NewButtonWithConfig(uiResources, eui.ButtonConfig{
 			Scene: c.scene,
@@ -0,0 +1,246 @@
+package menus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/quasilyte/ge"
+	"github.com/quasilyte/gsignal"
+	"github.com/quasilyte/roboden-game/clientkit"
+	"github.com/quasilyte/roboden-game/controls"
+	"github.com/quasilyte/roboden-game/gameui/eui"
+	"github.com/quasilyte/roboden-game/gtask"
+	"github.com/quasilyte/roboden-game/serverapi"
+	"github.com/quasilyte/roboden-game/session"
+	"github.com/quasilyte/roboden-game/timeutil"
+)
+
+// ProfileOnlineMenuController shows the published player results
+// from all seasons next to the local highscores.
+//
+// It's created twice: first to fetch the profile and then
+// to display it (with loaded=true).
+type ProfileOnlineMenuController struct {
+	state *session.State
+
+	scene *ge.Scene
+
+	loaded   bool
+	profile  *serverapi.PlayerProfileResp
+	fetchErr error
+}
+
+func NewProfileOnlineMenuController(state *session.State) *ProfileOnlineMenuController {
+	return &ProfileOnlineMenuController{state: state}
+}
+
+func (c *ProfileOnlineMenuController) Init(scene *ge.Scene) {
+	c.scene = scene
+	c.initUI()
+
+	if !c.loaded && c.state.Persistent.PlayerName != "" {
+		c.fetchProfile()
+	}
+}
+
+func (c *ProfileOnlineMenuController) Update(delta float64) {
+	c.state.MenuInput.Update()
+	if c.state.MenuInput.ActionIsJustPressed(controls.ActionMenuBack) {
+		c.back()
+		return
+	}
+}
+
+func (c *ProfileOnlineMenuController) fetchProfile() {
+	var profile *serverapi.PlayerProfileResp
+	var fetchErr error
+	fetchTask := gtask.StartTask(func(ctx *gtask.TaskContext) {
+		profile, fetchErr = clientkit.GetPlayerProfile(c.state, c.state.Persistent.PlayerName)
+	})
+	fetchTask.EventCompleted.Connect(nil, func(gsignal.Void) {
+		if fetchErr != nil {
+			c.state.Logf("fetch player profile: %v", fetchErr)
+		}
+		c.scene.Context().ChangeScene(&ProfileOnlineMenuController{
+			state:    c.state,
+			loaded:   true,
+			profile:  profile,
+			fetchErr: fetchErr,
+		})
+	})
+	c.scene.AddObject(fetchTask)
+}
+
+func (c *ProfileOnlineMenuController) initUI() {
+	eui.AddBackground(c.state.BackgroundImage, c.scene)
+	uiResources := c.state.Resources.UI
+
+	root := eui.NewAnchorContainer()
+	rowContainer := eui.NewRowLayoutContainerWithMinWidth(540, 10, nil)
+	root.AddChild(rowContainer)
+
+	d := c.scene.Dict()
+
+	titleLabel := eui.NewCenteredLabel(d.Get("menu.main.profile")+" -> "+d.Get("menu.profile.online"), c.state.Resources.Font3)
+	rowContainer.AddChild(titleLabel)
+
+	tinyFont := c.state.Resources.Font1
+
+	panel := eui.NewTextPanel(uiResources, 540, 96)
+	rowContainer.AddChild(panel)
+
+	switch {
+	case c.state.Persistent.PlayerName == "":
+		panel.AddChild(eui.NewCenteredLabel(d.Get("menu.profile.online.no_name"), tinyFont))
+	case !c.loaded:
+		panel.AddChild(eui.NewCenteredLabel(d.Get("menu.leaderboard.placeholder"), tinyFont))
+	case c.fetchErr != nil:
+		panel.AddChild(eui.NewCenteredLabel(d.Get("menu.leaderboard.fetch_error"), tinyFont))
+	default:
+		panel.AddChild(c.createProfileGrid())
+		if droneLines := c.droneLines(); len(droneLines) != 0 {
+			rowContainer.AddChild(eui.NewLabel(strings.Join(droneLines, "\n"), tinyFont))
+		}
+	}
+
+	backButton := eui.NewButton(uiResources, c.scene, d.Get("menu.back"), func() {
+		c.back()
+	})
+	rowContainer.AddChild(backButton)
+
+	navTree := createSimpleNavTree([]eui.Widget{backButton})
+	setupUI(c.scene, root, c.state.MenuInput, navTree)
+}
+
+func (c *ProfileOnlineMenuController) createProfileGrid() *widget.Container {
+	d := c.scene.Dict()
+	tinyFont := c.state.Resources.Font1
+
+	const numColumns = 6
+	grid := widget.NewContainer(
+		widget.ContainerOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+			Stretch: true,
+		})),
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Spacing(24, 4),
+			widget.GridLayoutOpts.Columns(numColumns),
+			widget.GridLayoutOpts.Stretch([]bool{true, false, false, false, false, false}, nil),
+		)))
+
+	grid.AddChild(eui.NewLabel("", tinyFont))
+	grid.AddChild(eui.NewLabel("["+d.Get("menu.profile.online.col_local")+"]", tinyFont))
+	grid.AddChild(eui.NewLabel("["+d.Get("menu.profile.online.col_online")+"]", tinyFont))
+	grid.AddChild(eui.NewLabel("["+d.Get("menu.leaderboard.col_time")+"]", tinyFont))
+	grid.AddChild(eui.NewLabel("["+d.Get("menu.leaderboard.col_rank")+"]", tinyFont))
+	grid.AddChild(eui.NewLabel("["+d.Get("menu.leaderboard.season")+"]", tinyFont))
+	for i := 0; i < numColumns; i++ {
+		grid.AddChild(eui.NewLabel("-", tinyFont))
+	}
+
+	stats := &c.state.Persistent.PlayerStats
+	for _, mode := range profileModes {
+		localScore, localDifficulty := localHighscore(stats, mode)
+		best := c.bestEntry(mode)
+		if localScore == 0 && best == nil {
+			continue
+		}
+
+		grid.AddChild(eui.NewLabel(d.Get("menu.leaderboard", mode), tinyFont))
+		if localScore != 0 {
+			grid.AddChild(eui.NewLabel(fmt.Sprintf("%d (%d%%)", localScore, localDifficulty), tinyFont))
+		} else {
+			grid.AddChild(eui.NewLabel("-", tinyFont))
+		}
+		if best == nil {
+			for i := 0; i < numColumns-2; i++ {
+				grid.AddChild(eui.NewLabel("-", tinyFont))
+			}
+			continue
+		}
+		clr := eui.NormalTextColor
+		if best.Score > localScore {
+			// Probably it was achieved on another device.
+			clr = eui.CaretColor
+		}
+		grid.AddChild(eui.NewColoredLabel(fmt.Sprintf("%d (%d%%)", best.Score, best.Difficulty), tinyFont, clr))
+		if mode != "arena" {
+			d := time.Duration(best.Time) * time.Second
+			grid.AddChild(eui.NewLabel(timeutil.FormatDurationCompact(d), tinyFont))
+		} else {
+			grid.AddChild(eui.NewLabel("-", tinyFont))
+		}
+		grid.AddChild(eui.NewLabel(strconv.Itoa(best.Rank), tinyFont))
+		grid.AddChild(eui.NewLabel(strconv.Itoa(best.Season), tinyFont))
+	}
+
+	return grid
+}
+
+func (c *ProfileOnlineMenuController) droneLines() []string {
+	d := c.scene.Dict()
+
+	var lines []string
+	for _, mode := range profileModes {
+		best := c.bestEntry(mode)
+		if best == nil || best.Drones == "" {
+			continue
+		}
+		droneNames := strings.Split(best.Drones, ",")
+		for i, name := range droneNames {
+			droneNames[i] = d.Get("drone", strings.ToLower(name))
+		}
+		lines = append(lines, d.Get("menu.leaderboard", mode)+": "+strings.Join(droneNames, ", "))
+	}
+	return lines
+}
+
+// bestEntry returns the highest score entry for the mode among all seasons.
+// If there are several of them, the most recent season wins.
+func (c *ProfileOnlineMenuController) bestEntry(mode string) *serverapi.PlayerProfileEntry {
+	if c.profile == nil {
+		return nil
+	}
+	var best *serverapi.PlayerProfileEntry
+	for i := range c.profile.Entries {
+		e := &c.profile.Entries[i]
+		if e.Mode != mode {
+			continue
+		}
+		if best == nil || e.Score > best.Score || (e.Score == best.Score && e.Season > best.Season) {
+			best = e
+		}
+	}
+	return best
+}
+
+func (c *ProfileOnlineMenuController) back() {
+	c.scene.Context().ChangeScene(NewProfileMenuController(c.state))
+}
+
+var profileModes = []string{
+	"classic",
+	"blitz",
+	"arena",
+	"reverse",
+	"inf_arena",
+}
+
+func localHighscore(stats *session.PlayerStats, mode string) (score, difficulty int) {
+	switch mode {
+	case "classic":
+		return stats.HighestClassicScore, stats.HighestClassicScoreDifficulty
+	case "blitz":
+		return stats.HighestBlitzScore, stats.HighestBlitzScoreDifficulty
+	case "arena":
+		return stats.HighestArenaScore, stats.HighestArenaScoreDifficulty
+	case "reverse":
+		return stats.HighestReverseScore, stats.HighestReverseScoreDifficulty
+	case "inf_arena":
+		return stats.HighestInfArenaScore, stats.HighestInfArenaScoreDifficulty
+	default:
+		return 0, 0
+	}
+}
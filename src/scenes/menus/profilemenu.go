@@ -50,6 +50,9 @@ func (c *ProfileMenuController) initUI() {
 		eui.NewButton(uiResources, c.scene, d.Get("menu.profile.stats"), func() {
 			c.scene.Context().ChangeScene(NewProfileStatsMenuController(c.state))
 		}),
+		eui.NewButton(uiResources, c.scene, d.Get("menu.profile.online"), func() {
+			c.scene.Context().ChangeScene(NewProfileOnlineMenuController(c.state))
+		}),
 		eui.NewButton(uiResources, c.scene, d.Get("menu.profile.progress"), func() {
 			c.scene.Context().ChangeScene(NewProfileProgressMenuController(c.state))
 		}),
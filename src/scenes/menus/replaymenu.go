@@ -2,6 +2,7 @@ package menus
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/ebitenui/ebitenui/widget"
 	"github.com/quasilyte/ge"
@@ -9,16 +10,47 @@ import (
 	"github.com/quasilyte/roboden-game/controls"
 	"github.com/quasilyte/roboden-game/descriptions"
 	"github.com/quasilyte/roboden-game/gamedata"
-	"github.com/quasilyte/roboden-game/gameui"
 	"github.com/quasilyte/roboden-game/gameui/eui"
 	"github.com/quasilyte/roboden-game/scenes/staging"
 	"github.com/quasilyte/roboden-game/session"
 	"github.com/quasilyte/roboden-game/timeutil"
 )
 
+const replaysPerPage = 10
+
+const (
+	replaySortByDate int = iota
+	replaySortByScore
+	replaySortByTime
+	replaySortByMode
+)
+
+const (
+	replayShowAll int = iota
+	replayShowFavourites
+	replayShowVictories
+)
+
+// replayModeFilters are the replay menu mode filter values.
+// An empty string stands for "any mode".
+var replayModeFilters = []string{"", "classic", "arena", "inf_arena", "reverse", "blitz"}
+
+// replayMenuOptions are preserved when the menu is re-created
+// after the library changes.
+type replayMenuOptions struct {
+	sortBy     int
+	modeFilter int
+	show       int
+	page       int
+	selectedID int
+	status     string
+}
+
 type ReplayMenuController struct {
 	state *session.State
 
+	options replayMenuOptions
+
 	helpLabel *widget.Text
 
 	scene *ge.Scene
@@ -46,32 +78,95 @@ func (c *ReplayMenuController) initUI() {
 	uiResources := c.state.Resources.UI
 
 	root := eui.NewAnchorContainer()
-	rowContainer := eui.NewRowLayoutContainer(10, nil)
+	rowContainer := eui.NewRowLayoutContainer(8, nil)
 	root.AddChild(rowContainer)
 
 	d := c.scene.Dict()
 
 	smallFont := assets.Font1
 
-	helpLabel := eui.NewLabel("", smallFont)
-	helpLabel.MaxWidth = 268
-	c.helpLabel = helpLabel
+	var navWidgets []eui.Widget
 
-	backButton := eui.NewButton(uiResources, c.scene, d.Get("menu.back"), func() {
-		c.back()
-	})
+	titleLabel := eui.NewCenteredLabel(d.Get("menu.main.profile")+" -> "+d.Get("menu.profile.watch_replay"), c.state.Resources.Font3)
+	rowContainer.AddChild(titleLabel)
 
-	navTree := gameui.NewNavTree()
-	bottomNavBlock := navTree.NewBlock()
-	leftNavBlock := navTree.NewBlock()
-	rightNavBlock := navTree.NewBlock()
-	var leftButtonElems []*gameui.NavElem
-	var rightButtonElems []*gameui.NavElem
+	entries := c.filteredEntries()
+	numPages := (len(entries) + replaysPerPage - 1) / replaysPerPage
+	if numPages == 0 {
+		numPages = 1
+	}
+	if c.options.page >= numPages {
+		c.options.page = numPages - 1
+	}
 
-	bottomNavBlock.NewElem(backButton)
+	{
+		modeNames := make([]string, len(replayModeFilters))
+		for i, m := range replayModeFilters {
+			if m == "" {
+				modeNames[i] = d.Get("menu.replay.any_mode")
+			} else {
+				modeNames[i] = d.Get("menu.leaderboard", m)
+			}
+		}
+		pageNames := make([]string, numPages)
+		for i := range pageNames {
+			pageNames[i] = fmt.Sprintf("%d/%d", i+1, numPages)
+		}
+		selects := []eui.SelectButtonConfig{
+			{
+				Value: &c.options.sortBy,
+				Label: d.Get("menu.replay.sort_by"),
+				ValueNames: []string{
+					d.Get("menu.replay.sort_by.date"),
+					d.Get("menu.results.score"),
+					d.Get("menu.results.time_played"),
+					d.Get("menu.replay.sort_by.mode"),
+				},
+			},
+			{
+				Value:      &c.options.modeFilter,
+				Label:      d.Get("menu.replay.mode"),
+				ValueNames: modeNames,
+			},
+			{
+				Value: &c.options.show,
+				Label: d.Get("menu.replay.show"),
+				ValueNames: []string{
+					d.Get("menu.replay.show.all"),
+					d.Get("menu.replay.show.favourites"),
+					d.Get("menu.replay.show.victories"),
+				},
+			},
+			{
+				Value:      &c.options.page,
+				Label:      d.Get("menu.replay.page"),
+				ValueNames: pageNames,
+			},
+		}
+		filtersGrid := eui.NewGridContainer(len(selects), widget.GridLayoutOpts.Spacing(8, 4))
+		for i := range selects {
+			config := selects[i]
+			config.Resources = uiResources
+			config.Input = c.state.MenuInput
+			config.PlaySound = true
+			isPageSelect := i == len(selects)-1
+			config.OnPressed = func() {
+				if !isPageSelect {
+					c.options.page = 0
+				}
+				c.reload()
+			}
+			b := eui.NewSelectButton(config)
+			c.scene.AddObject(b)
+			filtersGrid.AddChild(b.Widget)
+			navWidgets = append(navWidgets, b.Widget)
+		}
+		rowContainer.AddChild(filtersGrid)
+	}
 
-	titleLabel := eui.NewCenteredLabel(d.Get("menu.main.profile")+" -> "+d.Get("menu.profile.watch_replay"), c.state.Resources.Font3)
-	rowContainer.AddChild(titleLabel)
+	helpLabel := eui.NewLabel("", smallFont)
+	helpLabel.MaxWidth = 268
+	c.helpLabel = helpLabel
 
 	rootGrid := widget.NewContainer(
 		widget.ContainerOpts.Layout(widget.NewGridLayout(
@@ -81,46 +176,48 @@ func (c *ReplayMenuController) initUI() {
 	leftGrid := eui.NewGridContainer(2, widget.GridLayoutOpts.Spacing(8, 4),
 		widget.GridLayoutOpts.Stretch([]bool{true, false}, nil))
 
-	for i := 0; i < 10; i++ {
-		key := c.state.ReplayDataKey(i)
-		replayExists := c.state.CheckGameItem(key)
-		var r session.SavedReplay
-		if replayExists {
-			if err := c.state.LoadGameItem(key, &r); err != nil {
-				replayExists = false
-			}
-			if !gamedata.IsRunnableReplay(r.Replay) {
-				replayExists = false
+	var selected *session.ReplayIndexEntry
+	var selectedReplay session.SavedReplay
+	if c.options.selectedID != 0 {
+		for i := range entries {
+			if entries[i].ID == c.options.selectedID {
+				selected = &entries[i]
 			}
 		}
-		label := d.Get("menu.replay.empty")
-		if replayExists {
-			if i == 0 {
-				label = d.Get("menu.replay.last_played")
+		if selected != nil {
+			r, err := c.state.LoadLibraryReplay(selected.ID)
+			if err != nil {
+				c.state.Logf("load library replay %d: %v", selected.ID, err)
+				selected = nil
 			} else {
-				label = fmt.Sprintf("[%d] %s", i, timeutil.FormatDateISO8601(r.Date, true))
+				selectedReplay = r
+				helpLabel.Label = descriptions.ReplayText(d, &r)
 			}
 		}
+	}
+
+	pageStart := c.options.page * replaysPerPage
+	pageEntries := entries[pageStart:]
+	if len(pageEntries) > replaysPerPage {
+		pageEntries = pageEntries[:replaysPerPage]
+	}
+	for i := range pageEntries {
+		e := pageEntries[i]
+		label := timeutil.FormatDateISO8601(e.Date, true)
+		if e.Favourite {
+			label = "* " + label
+		}
 		b := eui.NewSmallButton(uiResources, c.scene, label, func() {
-			config := gamedata.MakeLevelConfig(gamedata.ExecuteReplay, r.Replay.Config)
-			config.Finalize()
-			controller := staging.NewController(c.state, config, NewReplayMenuController(c.state))
-			controller.SetReplayActions(r.Replay)
-			c.scene.Context().ChangeScene(controller)
+			c.options.selectedID = e.ID
+			c.options.status = ""
+			c.reload()
 		})
-		if replayExists {
-			b.GetWidget().CursorEnterEvent.AddHandler(func(args interface{}) {
-				c.helpLabel.Label = descriptions.ReplayText(d, &r)
-			})
-		}
-		b.GetWidget().Disabled = !replayExists || r.Replay.GameVersion != gamedata.BuildNumber
 		b.GetWidget().MinWidth = 220
 		leftGrid.AddChild(b)
-		if i%2 == 0 {
-			leftButtonElems = append(leftButtonElems, leftNavBlock.NewElem(b))
-		} else {
-			rightButtonElems = append(rightButtonElems, rightNavBlock.NewElem(b))
-		}
+		navWidgets = append(navWidgets, b)
+	}
+	if len(pageEntries) == 0 {
+		leftGrid.AddChild(eui.NewLabel(d.Get("menu.replay.empty"), smallFont))
 	}
 
 	rightPanel := eui.NewTextPanel(uiResources, 320, 0)
@@ -131,23 +228,174 @@ func (c *ReplayMenuController) initUI() {
 
 	rowContainer.AddChild(rootGrid)
 
-	rowContainer.AddChild(eui.NewCenteredLabel(d.Get("menu.replay.notice"), smallFont))
+	{
+		actionsGrid := eui.NewGridContainer(6, widget.GridLayoutOpts.Spacing(8, 4))
 
-	rowContainer.AddChild(eui.NewTransparentSeparator())
+		lastPlayed, lastPlayedExists := c.loadLastPlayedReplay()
+		lastPlayedButton := eui.NewSmallButton(uiResources, c.scene, d.Get("menu.replay.last_played"), func() {
+			c.watchReplay(lastPlayed)
+		})
+		lastPlayedButton.GetWidget().Disabled = !lastPlayedExists
+		if lastPlayedExists {
+			lastPlayedButton.GetWidget().CursorEnterEvent.AddHandler(func(args interface{}) {
+				c.helpLabel.Label = descriptions.ReplayText(d, &lastPlayed)
+			})
+		}
 
-	rowContainer.AddChild(backButton)
+		watchButton := eui.NewSmallButton(uiResources, c.scene, d.Get("menu.replay.watch"), func() {
+			c.watchReplay(selectedReplay)
+		})
+		watchButton.GetWidget().Disabled = selected == nil || !c.isWatchable(selectedReplay)
+
+		favouriteLabel := d.Get("menu.replay.favourite")
+		if selected != nil && selected.Favourite {
+			favouriteLabel = d.Get("menu.replay.unfavourite")
+		}
+		favouriteButton := eui.NewSmallButton(uiResources, c.scene, favouriteLabel, func() {
+			c.state.SetLibraryReplayFavourite(selected.ID, !selected.Favourite)
+			c.reload()
+		})
+		favouriteButton.GetWidget().Disabled = selected == nil
+
+		exportButton := eui.NewSmallButton(uiResources, c.scene, d.Get("menu.replay.export"), func() {
+			filePath, err := c.state.ExportLibraryReplay(selected.ID)
+			if err != nil {
+				c.state.Logf("export replay %d: %v", selected.ID, err)
+				c.options.status = d.Get("menu.replay.export_error")
+			} else {
+				c.options.status = d.Get("menu.replay.exported") + ": " + filePath
+			}
+			c.reload()
+		})
+		exportButton.GetWidget().Disabled = selected == nil || c.state.ReplayExchangeDir == ""
+
+		deleteButton := eui.NewSmallButton(uiResources, c.scene, d.Get("menu.replay.delete"), func() {
+			c.state.DeleteLibraryReplay(selected.ID)
+			c.options.selectedID = 0
+			c.options.status = ""
+			c.reload()
+		})
+		deleteButton.GetWidget().Disabled = selected == nil
+
+		importButton := eui.NewSmallButton(uiResources, c.scene, d.Get("menu.replay.import"), func() {
+			numImported, err := c.state.ImportReplays()
+			if err != nil {
+				c.state.Logf("import replays: %v", err)
+				c.options.status = d.Get("menu.replay.import_error") + ": " + c.state.ReplayExchangeDir
+			} else {
+				c.options.status = fmt.Sprintf("%s: %d", d.Get("menu.replay.imported"), numImported)
+			}
+			c.reload()
+		})
+		importButton.GetWidget().Disabled = c.state.ReplayExchangeDir == ""
 
-	bindNavListNoWrap(leftButtonElems, gameui.NavUp, gameui.NavDown)
-	bindNavListNoWrap(rightButtonElems, gameui.NavUp, gameui.NavDown)
-	bottomNavBlock.Edges[gameui.NavUp] = leftNavBlock
-	leftNavBlock.Edges[gameui.NavDown] = bottomNavBlock
-	leftNavBlock.Edges[gameui.NavRight] = rightNavBlock
-	rightNavBlock.Edges[gameui.NavDown] = bottomNavBlock
-	rightNavBlock.Edges[gameui.NavLeft] = leftNavBlock
+		buttons := []*widget.Button{
+			lastPlayedButton,
+			watchButton,
+			favouriteButton,
+			exportButton,
+			deleteButton,
+			importButton,
+		}
+		for _, b := range buttons {
+			actionsGrid.AddChild(b)
+			navWidgets = append(navWidgets, b)
+		}
+		rowContainer.AddChild(actionsGrid)
+	}
+
+	if c.options.status != "" {
+		rowContainer.AddChild(eui.NewCenteredLabel(c.options.status, smallFont))
+	}
+	rowContainer.AddChild(eui.NewCenteredLabel(d.Get("menu.replay.notice"), smallFont))
 
+	backButton := eui.NewButton(uiResources, c.scene, d.Get("menu.back"), func() {
+		c.back()
+	})
+	rowContainer.AddChild(backButton)
+	navWidgets = append(navWidgets, backButton)
+
+	navTree := createSimpleNavTree(navWidgets)
 	setupUI(c.scene, root, c.state.MenuInput, navTree)
 }
 
+// filteredEntries returns the library index entries that
+// match the current filters in the selected order.
+func (c *ReplayMenuController) filteredEntries() []session.ReplayIndexEntry {
+	index := c.state.LoadReplayIndex()
+	modeFilter := replayModeFilters[c.options.modeFilter]
+
+	entries := make([]session.ReplayIndexEntry, 0, len(index.Entries))
+	for _, e := range index.Entries {
+		if modeFilter != "" && e.Mode != modeFilter {
+			continue
+		}
+		switch c.options.show {
+		case replayShowFavourites:
+			if !e.Favourite {
+				continue
+			}
+		case replayShowVictories:
+			if !e.Victory {
+				continue
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	// The most recent replays go first unless some other order is requested.
+	sort.SliceStable(entries, func(i, j int) bool {
+		x := &entries[i]
+		y := &entries[j]
+		switch c.options.sortBy {
+		case replaySortByScore:
+			if x.Score != y.Score {
+				return x.Score > y.Score
+			}
+		case replaySortByTime:
+			if x.Time != y.Time {
+				return x.Time > y.Time
+			}
+		case replaySortByMode:
+			if x.Mode != y.Mode {
+				return x.Mode < y.Mode
+			}
+		}
+		return x.Date.After(y.Date)
+	})
+
+	return entries
+}
+
+func (c *ReplayMenuController) loadLastPlayedReplay() (session.SavedReplay, bool) {
+	var r session.SavedReplay
+	key := c.state.ReplayDataKey(0)
+	if !c.state.CheckGameItem(key) {
+		return r, false
+	}
+	if err := c.state.LoadGameItem(key, &r); err != nil {
+		return r, false
+	}
+	return r, c.isWatchable(r)
+}
+
+func (c *ReplayMenuController) isWatchable(r session.SavedReplay) bool {
+	return gamedata.IsRunnableReplay(r.Replay) && r.Replay.GameVersion == gamedata.BuildNumber
+}
+
+func (c *ReplayMenuController) watchReplay(r session.SavedReplay) {
+	config := gamedata.MakeLevelConfig(gamedata.ExecuteReplay, r.Replay.Config)
+	config.Finalize()
+	back := &ReplayMenuController{state: c.state, options: c.options}
+	controller := staging.NewController(c.state, config, back)
+	controller.SetReplayActions(r.Replay)
+	c.scene.Context().ChangeScene(controller)
+}
+
+func (c *ReplayMenuController) reload() {
+	c.scene.Context().ChangeScene(&ReplayMenuController{state: c.state, options: c.options})
+}
+
 func (c *ReplayMenuController) back() {
 	c.scene.Context().ChangeScene(NewProfileMenuController(c.state))
 }
@@ -209,6 +209,7 @@ func (m *arenaManager) Update(delta float64) {
 			m.EventVictory.Emit(gsignal.Void{})
 			return
 		}
+		m.world.matchEvents.OnArenaWave(m.level)
 		m.spawnCreeps()
 		m.level++
 		m.prepareWave()
@@ -174,6 +174,9 @@ type colonyAgentNode struct {
 	dist          float64
 	waypointsLeft int
 
+	// factory is set for the units produced by a drone factory (see attachMerc).
+	factory *colonyAgentNode
+
 	EventDestroyed gsignal.Event[*colonyAgentNode]
 }
 
@@ -1138,6 +1141,9 @@ func (a *colonyAgentNode) OnDamage(damage gamedata.DamageValue, source targetabl
 		multiplier := 1.0 - a.damageReduction()
 		healthDamage := damage.Health * multiplier
 		a.health -= healthDamage
+		if a.colonyCore != nil {
+			a.world().telemetry.OnColonyDamaged(a.colonyCore.player, healthDamage, source)
+		}
 
 		if a.health < 0 {
 			a.explode()
@@ -2243,17 +2249,11 @@ func (a *colonyAgentNode) updateRelictDroneFactory(delta float64) {
 			unit.target = a
 			unit.dist = a.scene.Rand().FloatRange(20, 80)
 			unit.dist *= unit.dist
-			world := a.world()
-			world.nodeRunner.AddObject(unit)
-			world.mercs = append(world.mercs, unit)
+			a.world().nodeRunner.AddObject(unit)
+			a.attachMerc(unit)
 			unit.setWaypoint(unit.pos.Sub(gmath.Vec{Y: agentFlightHeight}))
 			unit.SetHeight(0)
 			unit.shadowComponent.SetVisibility(false)
-			unit.EventDestroyed.Connect(nil, func(*colonyAgentNode) {
-				a.extraLevel--
-				a.specialDelay += 5
-				world.mercs = xslices.Remove(world.mercs, unit)
-			})
 			hatch := a.target.(*ge.Sprite)
 			hatch.Visible = false
 			a.lifetime = 2
@@ -2272,6 +2272,18 @@ func (a *colonyAgentNode) updateRelictDroneFactory(delta float64) {
 	}
 }
 
+// attachMerc registers a unit produced by this drone factory.
+func (a *colonyAgentNode) attachMerc(unit *colonyAgentNode) {
+	world := a.world()
+	unit.factory = a
+	world.mercs = append(world.mercs, unit)
+	unit.EventDestroyed.Connect(nil, func(*colonyAgentNode) {
+		a.extraLevel--
+		a.specialDelay += 5
+		world.mercs = xslices.Remove(world.mercs, unit)
+	})
+}
+
 func (a *colonyAgentNode) updateHarvester(delta float64) {
 	var target *essenceSourceNode
 	if a.target != nil {
@@ -2836,6 +2848,9 @@ func (a *colonyAgentNode) updateMerging(delta float64) {
 		} else {
 			newAgent = a.colonyCore.NewColonyAgentNode(newStats, target.pos)
 		}
+		if newStats.Tier == 3 {
+			a.world().matchEvents.OnTier3Drone(a.colonyCore, newAgent)
+		}
 		var newFaction gamedata.FactionTag
 		rankScore := a.rank + target.rank
 		switch rankScore {
@@ -388,6 +388,9 @@ func (c *colonyCoreNode) OnDamage(damage gamedata.DamageValue, source targetable
 	multiplier := 1.0 - c.damageReduction()
 	healthDamage := damage.Health * multiplier
 	c.health -= healthDamage
+	if healthDamage > 0 {
+		c.world.telemetry.OnColonyDamaged(c.player, healthDamage, source)
+	}
 	if c.health < 0 {
 		if c.shadowComponent.height == 0 {
 			createAreaExplosion(c.world, spriteRect(c.pos, c.sprite), normalEffectLayer)
@@ -506,6 +509,8 @@ func (c *colonyCoreNode) AcceptRoomba(roomba *colonyAgentNode) {
 func (c *colonyCoreNode) AddGatheredResources(value float64) {
 	c.resources += value
 	c.world.result.ResourcesGathered += value
+	c.world.matchEvents.OnResourcesGathered(c.player, value)
+	c.world.telemetry.OnResourcesGathered(c.player, value)
 }
 
 func (c *colonyCoreNode) AcceptTurret(turret *colonyAgentNode) {
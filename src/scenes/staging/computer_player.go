@@ -18,6 +18,8 @@ type computerPlayer struct {
 	choiceGen       *choiceGenerator
 	choiceSelection choiceSelection
 
+	profile *gamedata.BotProfile
+
 	colonies    []*computerColony
 	attackGroup []*computerColony
 
@@ -74,7 +76,7 @@ type computerColony struct {
 	howitzerAttacker *creepNode
 }
 
-func newComputerPlayer(world *worldState, state *playerState, choiceGen *choiceGenerator) *computerPlayer {
+func newComputerPlayer(world *worldState, state *playerState, choiceGen *choiceGenerator, profile *gamedata.BotProfile) *computerPlayer {
 	p := &computerPlayer{
 		world:     world,
 		state:     state,
@@ -86,9 +88,12 @@ func newComputerPlayer(world *worldState, state *playerState, choiceGen *choiceG
 		evolutionCards: make([]int, 0, 4),
 		securityCards:  make([]int, 0, 4),
 
+		profile: profile,
+
 		buildColonyDelay: world.rand.FloatRange(60, 3*60),
 		isHive:           world.coreDesign == gamedata.HiveCoreStats,
 	}
+	p.buildColonyDelay *= p.profile.BuildColonyDelay
 
 	switch p.world.turretDesign {
 	case gamedata.GunpointAgentStats:
@@ -140,6 +145,7 @@ func newComputerPlayer(world *worldState, state *playerState, choiceGen *choiceG
 		panic("bot can't play on this core design")
 	}
 	p.maxColonies = numColoniesPicker.Pick()
+	p.maxColonies = gmath.ClampMin(p.maxColonies+p.profile.ExtraColonies, 1)
 
 	if p.world.config.WorldSize >= 3 && p.isHive {
 		p.maxColonies += 2
@@ -206,6 +212,7 @@ func newComputerPlayer(world *worldState, state *playerState, choiceGen *choiceG
 		if p.isHive {
 			wrapped.maxTurrets++
 		}
+		wrapped.maxTurrets = gmath.ClampMin(wrapped.maxTurrets+p.profile.ExtraTurrets, 0)
 		colony.EventDestroyed.Connect(p, func(_ *colonyCoreNode) {
 			p.colonies = xslices.Remove(p.colonies, wrapped)
 		})
@@ -307,6 +314,7 @@ func (p *computerPlayer) handleInput() {
 	} else {
 		p.actionDelay = p.world.rand.FloatRange(0.75, 2.0)
 	}
+	p.actionDelay *= p.profile.ActionDelay
 }
 
 func (p *computerPlayer) findGoodComebackSpot(leaderColony *computerColony, colonyPower int, dist float64) (gmath.Vec, int) {
@@ -430,6 +438,7 @@ func (p *computerPlayer) maybeDoColonyAction(colony *computerColony) bool {
 		} else {
 			colony.attackBaseDelay = p.world.rand.FloatRange(20, 35)
 		}
+		colony.attackBaseDelay *= p.profile.AttackBaseDelay
 	}
 
 	// If bot is attacking the dreadnought with this colony,
@@ -452,10 +461,10 @@ func (p *computerPlayer) maybeDoColonyAction(colony *computerColony) bool {
 
 	if colony.defendDelay == 0 {
 		if delay := p.maybeDoDefensiveAction(colony); delay != 0 {
-			colony.defendDelay = delay * p.world.rand.FloatRange(0.5, 2)
+			colony.defendDelay = delay * p.world.rand.FloatRange(0.5, 2) * p.profile.ReactionDelay
 			return true
 		}
-		colony.defendDelay = p.world.rand.FloatRange(3, 6)
+		colony.defendDelay = p.world.rand.FloatRange(3, 6) * p.profile.ReactionDelay
 	}
 
 	if colony.moveDelay == 0 {
@@ -468,9 +477,10 @@ func (p *computerPlayer) maybeDoColonyAction(colony *computerColony) bool {
 			default:
 				colony.moveDelay = delay * p.world.rand.FloatRange(0.8, 1.4)
 			}
+			colony.moveDelay *= p.profile.ReactionDelay
 			return true
 		}
-		colony.moveDelay = p.world.rand.FloatRange(5, 10)
+		colony.moveDelay = p.world.rand.FloatRange(5, 10) * p.profile.ReactionDelay
 	}
 
 	// Only defensive actions (like relocation) are allowed
@@ -492,6 +502,7 @@ func (p *computerPlayer) maybeDoColonyAction(colony *computerColony) bool {
 			} else {
 				p.buildColonyDelay = p.world.rand.FloatRange(80, 6*60)
 			}
+			p.buildColonyDelay *= p.profile.BuildColonyDelay
 			return true
 		}
 		if p.isHive {
@@ -499,6 +510,7 @@ func (p *computerPlayer) maybeDoColonyAction(colony *computerColony) bool {
 		} else {
 			p.buildColonyDelay = p.world.rand.FloatRange(30, 60)
 		}
+		p.buildColonyDelay *= p.profile.BuildColonyDelay
 	}
 
 	if p.buildTurretDelay == 0 && p.choiceSelection.special.special == specialBuildGunpoint && colony.node.numTurretsBuilt < colony.maxTurrets {
@@ -674,10 +686,10 @@ func (p *computerPlayer) maybeAttackCreepBase(colony *computerColony) float64 {
 	if !p.world.gameStarted {
 		return 0
 	}
-	if colony.node.agents.TotalNum() < 30 {
+	if colony.node.agents.TotalNum() < p.profile.MinAttackDrones {
 		return 0
 	}
-	if colony.node.agents.TotalNum() < 45 {
+	if colony.node.agents.TotalNum() < int(1.5*float64(p.profile.MinAttackDrones)) {
 		if colony.node.resources < 0.4*colony.node.maxVisualResources() {
 			return 0
 		}
@@ -691,7 +703,7 @@ func (p *computerPlayer) maybeAttackCreepBase(colony *computerColony) float64 {
 	}
 
 	power := p.selectedColonyPower(gamedata.TargetAny)
-	if power < 70 {
+	if power < p.profile.MinAttackPower {
 		return 0
 	}
 
@@ -930,7 +942,7 @@ func (p *computerPlayer) maybeRetreatFromBoss(colony *computerColony) bool {
 		return false
 	}
 
-	if p.world.rand.Chance(0.65) {
+	if p.world.rand.Chance(p.profile.BossRetreatChance) {
 		// Try to get out of the boss movement trajectory.
 		bossDir := boss.waypoint.DirectionTo(boss.pos)
 		probe1 := bossDir.Rotated(gmath.Rad(p.world.rand.FloatRange(0.45, 1.1))).Mulf(colony.node.MaxFlyDistance()).Add(colony.node.pos)
@@ -1127,7 +1139,7 @@ func (p *computerPlayer) maybeBuildColony(colony *computerColony) bool {
 		return false
 	}
 
-	if colony.node.NumAgents() < 20 || colony.node.realRadius < p.minRadiusBeforeColony {
+	if colony.node.NumAgents() < p.profile.MinColonyDrones || colony.node.realRadius < p.minRadiusBeforeColony {
 		return false
 	}
 
@@ -252,6 +252,7 @@ func (c *constructionNode) done(builder *colonyCoreNode) {
 
 	case constructBase:
 		c.world.result.ColoniesBuilt++
+		c.world.matchEvents.OnColonyBuilt(c.player, c.pos)
 		core := c.world.NewColonyCoreNode(colonyConfig{
 			World:  c.world,
 			Radius: 128,
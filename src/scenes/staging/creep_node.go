@@ -66,6 +66,9 @@ type creepNode struct {
 	bossStage int
 	fragScore int
 
+	// spawner is a creep that produced this one (see trackSpawned).
+	spawner *creepNode
+
 	EventDestroyed    gsignal.Event[*creepNode]
 	EventBuildingStop gsignal.Event[gsignal.Void]
 }
@@ -431,7 +434,7 @@ func (c *creepNode) explode() {
 	}
 }
 
-func (c *creepNode) onHealthDamage(damage gamedata.DamageValue) bool {
+func (c *creepNode) onHealthDamage(damage gamedata.DamageValue, source targetable) bool {
 	healthDamage := damage.Health
 	if healthDamage <= 0 {
 		return false
@@ -448,6 +451,7 @@ func (c *creepNode) onHealthDamage(damage gamedata.DamageValue) bool {
 	}
 
 	c.health -= healthDamage
+	c.world.telemetry.OnCreepDamaged(c, healthDamage, source)
 	if c.health < 0 {
 		c.explode()
 		c.Destroy()
@@ -477,7 +481,7 @@ func (c *creepNode) OnDamage(damage gamedata.DamageValue, source targetable) {
 		return
 	}
 
-	if c.onHealthDamage(damage) {
+	if c.onHealthDamage(damage, source) {
 		return
 	}
 
@@ -1353,9 +1357,7 @@ func (c *creepNode) updateFortress(delta float64) {
 	creep.spawnedFromBase = true
 	c.world.nodeRunner.AddObject(creep)
 	creep.SetHeight(0)
-	creep.EventDestroyed.Connect(c, func(arg *creepNode) {
-		c.specialModifier--
-	})
+	c.trackSpawned(creep)
 
 	createEffect(c.world, effectConfig{
 		Pos:   spawnPos,
@@ -1591,9 +1593,7 @@ func (c *creepNode) updateWispLair(delta float64) {
 
 	wisp := c.world.NewCreepNode(spawnPos, gamedata.WispCreepStats)
 	c.world.nodeRunner.AddObject(wisp)
-	wisp.EventDestroyed.Connect(c, func(arg *creepNode) {
-		c.specialModifier--
-	})
+	c.trackSpawned(wisp)
 	playSound(c.world, assets.AudioOrganicRestored, spawnPos)
 	createEffect(c.world, effectConfig{
 		Pos:     spawnPos,
@@ -1603,6 +1603,15 @@ func (c *creepNode) updateWispLair(delta float64) {
 	})
 }
 
+// trackSpawned makes the spawned creep count towards the units limit
+// of this creep; the limit is tracked by the specialModifier field.
+func (c *creepNode) trackSpawned(spawned *creepNode) {
+	spawned.spawner = c
+	spawned.EventDestroyed.Connect(c, func(arg *creepNode) {
+		c.specialModifier--
+	})
+}
+
 func (c *creepNode) updateCreepCrawlerBase(delta float64) {
 	if c.attackDelay != 0 {
 		return
@@ -1631,9 +1640,7 @@ func (c *creepNode) updateCreepCrawlerBase(delta float64) {
 	crawler.SendTo(dstPos)
 	crawler.waypoint = crawler.pos
 	c.world.nodeRunner.AddObject(crawler)
-	crawler.EventDestroyed.Connect(c, func(arg *creepNode) {
-		c.specialModifier--
-	})
+	c.trackSpawned(crawler)
 
 	createEffect(c.world, effectConfig{
 		Pos:   spawnPos,
@@ -1,10 +1,73 @@
 package staging
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/quasilyte/roboden-game/serverapi"
+)
 
 var (
-	errIllegalAction      = errors.New("illegal action")
 	errInvalidColonyIndex = errors.New("invalid colony index")
 	errExcessiveAcions    = errors.New("excessive actions")
-	errBadCheckpoint      = errors.New("mismatching checkpoint value")
+	errMissedActionTick   = errors.New("action tick is in the past")
+	errActionRejected     = errors.New("action can't be executed")
+)
+
+// These errors are used as panic values during the replay execution.
+// They're exported so the replay verifiers can tell them apart.
+// The actual panic values are IllegalActionError and BadCheckpointError
+// that wrap these errors and provide the details.
+var (
+	ErrIllegalAction = errors.New("illegal action")
+	ErrBadCheckpoint = errors.New("mismatching checkpoint value")
 )
+
+// IllegalActionError is a replay action that can't be executed.
+type IllegalActionError struct {
+	Tick     int
+	PlayerID int
+
+	// ActionIndex is the action index inside the player replay actions.
+	ActionIndex int
+	Action      serverapi.PlayerAction
+
+	Reason error
+}
+
+func (e *IllegalActionError) Error() string {
+	return fmt.Sprintf("player %d action #%d (kind=%d, tick=%d) at tick %d: %v",
+		e.PlayerID, e.ActionIndex, e.Action.Kind, e.Action.Tick, e.Tick, e.Reason)
+}
+
+func (e *IllegalActionError) Unwrap() error { return ErrIllegalAction }
+
+// BadCheckpointError is a checkpoint value mismatch.
+type BadCheckpointError struct {
+	Tick int
+
+	// Index is the checkpoint index inside the replay checkpoints.
+	Index int
+
+	Expected int
+	Actual   int
+}
+
+func (e *BadCheckpointError) Error() string {
+	return fmt.Sprintf("checkpoint #%d at tick %d: expected %d, got %d", e.Index, e.Tick, e.Expected, e.Actual)
+}
+
+func (e *BadCheckpointError) Unwrap() error { return ErrBadCheckpoint }
+
+// ExternalBotError is an external bot connection or protocol error.
+// It's used as a panic value, see SetExternalPlayer.
+type ExternalBotError struct {
+	PlayerID int
+	Err      error
+}
+
+func (e *ExternalBotError) Error() string {
+	return fmt.Sprintf("external bot %d: %v", e.PlayerID, e.Err)
+}
+
+func (e *ExternalBotError) Unwrap() error { return e.Err }
@@ -247,6 +247,9 @@ type essenceSourceNode struct {
 	rotation gmath.Rad
 	pos      gmath.Vec
 
+	// lavaPuddle is set for the magma rocks spawned by the lava.
+	lavaPuddle *lavaPuddleNode
+
 	EventDestroyed gsignal.Event[*essenceSourceNode]
 }
 
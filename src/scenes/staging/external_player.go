@@ -0,0 +1,255 @@
+package staging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+// externalDecisionTicks is an interval between the bot observations (~0.5 seconds).
+const externalDecisionTicks = 30
+
+var colonyModeNames = [...]string{
+	colonyModeNormal:      "normal",
+	colonyModeTakeoff:     "takeoff",
+	colonyModeRelocating:  "relocating",
+	colonyModeLanding:     "landing",
+	colonyModeTeleporting: "teleporting",
+}
+
+// externalBotConn is a connection to the external bot.
+// The direct connection has no encoder and decoder:
+// its actions are executed via ExecuteExternalAction.
+type externalBotConn struct {
+	enc *json.Encoder
+	dec *json.Decoder
+}
+
+// SetExternalPlayer makes the player controlled by the external bot.
+// See serverapi.BotObservation for the protocol description.
+//
+// The connection and protocol errors are reported by panicking
+// with ExternalBotError, just like the replay execution errors.
+func (c *Controller) SetExternalPlayer(playerID int, r io.Reader, w io.Writer) {
+	if c.externalBots == nil {
+		c.externalBots = make(map[int]*externalBotConn)
+	}
+	c.externalBots[playerID] = &externalBotConn{
+		enc: json.NewEncoder(w),
+		dec: json.NewDecoder(r),
+	}
+}
+
+// SetDirectExternalPlayer makes the player controlled by the caller.
+// The player does nothing on its own; use ExecuteExternalAction
+// and ObserveExternalPlayer between the simulation updates.
+//
+// This is useful for the in-process bots, like the training environments.
+func (c *Controller) SetDirectExternalPlayer(playerID int) {
+	if c.externalBots == nil {
+		c.externalBots = make(map[int]*externalBotConn)
+	}
+	c.externalBots[playerID] = &externalBotConn{}
+}
+
+// ExecuteExternalAction executes the action on behalf of the direct external player.
+// It reports whether the action was accepted; the wait actions are always accepted.
+func (c *Controller) ExecuteExternalAction(playerID int, a serverapi.BotAction) bool {
+	p := c.getExternalPlayer(playerID)
+	p.lastAction = ""
+	p.execute(a)
+	return p.lastAction != "rejected"
+}
+
+// ObserveExternalPlayer returns the current external player observation.
+func (c *Controller) ObserveExternalPlayer(playerID int) *serverapi.BotObservation {
+	return c.getExternalPlayer(playerID).observe()
+}
+
+func (c *Controller) getExternalPlayer(playerID int) *externalPlayer {
+	if playerID >= 0 && playerID < len(c.world.players) {
+		if p, ok := c.world.players[playerID].(*externalPlayer); ok {
+			return p
+		}
+	}
+	panic(fmt.Errorf("player %d is not an external player", playerID))
+}
+
+// externalPlayer is a player controlled by the external bot program.
+//
+// Every decision tick it sends the observation and blocks until
+// the bot responds; this keeps the simulation deterministic
+// regardless of the bot response time.
+type externalPlayer struct {
+	world *worldState
+	state *playerState
+
+	choiceGen *choiceGenerator
+
+	conn *externalBotConn
+
+	nextDecisionTick int
+	lastAction       string
+}
+
+func newExternalPlayer(world *worldState, state *playerState, choiceGen *choiceGenerator, conn *externalBotConn) *externalPlayer {
+	return &externalPlayer{
+		world:     world,
+		state:     state,
+		choiceGen: choiceGen,
+		conn:      conn,
+	}
+}
+
+func (p *externalPlayer) Init() {
+	if p.choiceGen.creepsState == nil {
+		p.state.selectedColony = p.state.colonies[0]
+	}
+}
+
+func (p *externalPlayer) GetState() *playerState { return p.state }
+
+func (p *externalPlayer) Update(computedDelta, delta float64) {
+	if p.conn.enc == nil {
+		// Direct connection, nothing to do.
+		return
+	}
+	if p.world.nodeRunner.ticks < p.nextDecisionTick {
+		return
+	}
+	p.nextDecisionTick = p.world.nodeRunner.ticks + externalDecisionTicks
+
+	if err := p.conn.enc.Encode(p.observe()); err != nil {
+		panic(&ExternalBotError{PlayerID: p.state.id, Err: fmt.Errorf("send observation: %w", err)})
+	}
+	var a serverapi.BotAction
+	if err := p.conn.dec.Decode(&a); err != nil {
+		panic(&ExternalBotError{PlayerID: p.state.id, Err: fmt.Errorf("read action: %w", err)})
+	}
+	switch a.Kind {
+	case "", serverapi.BotActionWait, serverapi.BotActionCard, serverapi.BotActionMove:
+		p.execute(a)
+	default:
+		panic(&ExternalBotError{PlayerID: p.state.id, Err: fmt.Errorf("unknown action kind %q", a.Kind)})
+	}
+}
+
+func (p *externalPlayer) execute(a serverapi.BotAction) {
+	switch a.Kind {
+	case "", serverapi.BotActionWait:
+		return
+	case serverapi.BotActionCard, serverapi.BotActionMove:
+		// OK.
+	default:
+		p.lastAction = "rejected"
+		return
+	}
+
+	p.lastAction = "rejected"
+	colony := p.state.selectedColony
+	if p.choiceGen.creepsState == nil {
+		if a.Colony < 0 || a.Colony >= len(p.state.colonies) {
+			return
+		}
+		colony = p.state.colonies[a.Colony]
+		p.state.selectedColony = colony
+	}
+
+	ok := false
+	if a.Kind == serverapi.BotActionMove {
+		ok = p.choiceGen.TryExecute(colony, -1, gmath.Vec{X: a.Pos[0], Y: a.Pos[1]})
+	} else if a.Card >= 0 && a.Card <= 4 {
+		ok = p.choiceGen.TryExecute(colony, a.Card, gmath.Vec{})
+	}
+	if ok {
+		p.lastAction = "ok"
+	}
+}
+
+func (p *externalPlayer) observe() *serverapi.BotObservation {
+	o := &serverapi.BotObservation{
+		Version:    serverapi.BotProtocolVersion,
+		Tick:       p.world.nodeRunner.ticks,
+		PlayerID:   p.state.id,
+		LastAction: p.lastAction,
+		Resources:  p.state.resourceStash,
+		CardsReady: p.choiceGen.IsReady(),
+		Colonies:   make([]serverapi.BotColony, 0, len(p.state.colonies)),
+		Creeps:     []serverapi.BotCreep{},
+	}
+	p.lastAction = ""
+
+	o.Stats = p.world.telemetry.BotStats(p.state.id)
+
+	if o.CardsReady {
+		choices := p.choiceGen.GetChoices()
+		for i, opt := range choices.cards {
+			o.Cards = append(o.Cards, makeBotCard(i, opt))
+		}
+		o.Cards = append(o.Cards, makeBotCard(len(choices.cards), choices.special))
+	}
+
+	for i, colony := range p.state.colonies {
+		o.Colonies = append(o.Colonies, serverapi.BotColony{
+			Index:        i,
+			Pos:          [2]float64{colony.pos.X, colony.pos.Y},
+			Selected:     colony == p.state.selectedColony,
+			Mode:         colonyModeNames[colony.mode],
+			Health:       colony.health,
+			MaxHealth:    colony.maxHealth,
+			Resources:    colony.resources,
+			PatrolRadius: colony.PatrolRadius(),
+			Drones:       colony.NumAgents(),
+			Priorities: map[string]float64{
+				priorityResources.String(): colony.GetResourcePriority(),
+				priorityGrowth.String():    colony.GetGrowthPriority(),
+				priorityEvolution.String(): colony.GetEvolutionPriority(),
+				prioritySecurity.String():  colony.GetSecurityPriority(),
+			},
+		})
+	}
+
+	visionRadiusSqr := p.world.visionRadius * p.world.visionRadius
+	for _, creep := range p.world.creeps {
+		// The creeps player can see all of its units.
+		visible := p.choiceGen.creepsState != nil
+		for _, colony := range p.state.colonies {
+			if visible {
+				break
+			}
+			visible = colony.pos.DistanceSquaredTo(creep.pos) <= visionRadiusSqr
+		}
+		if !visible {
+			continue
+		}
+		o.Creeps = append(o.Creeps, serverapi.BotCreep{
+			Kind:   creep.stats.Kind.String(),
+			Pos:    [2]float64{creep.pos.X, creep.pos.Y},
+			Health: creep.health,
+			Flying: creep.IsFlying(),
+		})
+	}
+
+	return o
+}
+
+func makeBotCard(index int, opt choiceOption) serverapi.BotCard {
+	card := serverapi.BotCard{
+		Index:     index,
+		Direction: opt.direction,
+		Cost:      opt.cost,
+	}
+	if opt.special != specialChoiceNone {
+		card.Special = opt.special.String()
+	}
+	if len(opt.effects) != 0 {
+		card.Effects = make(map[string]float64, len(opt.effects))
+		for _, e := range opt.effects {
+			card.Effects[e.priority.String()] = e.value
+		}
+	}
+	return card
+}
@@ -158,20 +158,31 @@ func (lava *lavaPuddleNode) Update(delta float64) {
 	createEffect(p.world, effectConfig{Pos: spawnPos, Image: assets.ImageFireBurst})
 
 	if target == nil && lava.numResourceSpawns < lava.maxResourceSpawns && lava.world.rand.Chance(0.3) {
-		p.EventDetonated.Connect(nil, func(pos gmath.Vec) {
-			if !posIsFree(lava.world, nil, attackPos, 20) {
-				return
-			}
-			res := lava.world.NewEssenceSourceNode(magmaRockSource, pos)
-			lava.world.nodeRunner.AddObject(res)
-			lava.numResourceSpawns++
-			res.EventDestroyed.Connect(nil, func(*essenceSourceNode) {
-				lava.numResourceSpawns--
-			})
-		})
+		lava.spawnRockOnDetonation(p, attackPos)
 	}
 }
 
+func (lava *lavaPuddleNode) spawnRockOnDetonation(p *projectileNode, attackPos gmath.Vec) {
+	p.rockSpawner = lava
+	p.rockSpawnPos = attackPos
+	p.EventDetonated.Connect(nil, func(pos gmath.Vec) {
+		if !posIsFree(lava.world, nil, attackPos, 20) {
+			return
+		}
+		res := lava.world.NewEssenceSourceNode(magmaRockSource, pos)
+		lava.world.nodeRunner.AddObject(res)
+		lava.numResourceSpawns++
+		lava.trackRock(res)
+	})
+}
+
+func (lava *lavaPuddleNode) trackRock(res *essenceSourceNode) {
+	res.lavaPuddle = lava
+	res.EventDestroyed.Connect(nil, func(*essenceSourceNode) {
+		lava.numResourceSpawns--
+	})
+}
+
 func (lava *lavaPuddleNode) CollidesWith(pos gmath.Vec, r float64) bool {
 	offset := gmath.Vec{X: r*0.5 + 12, Y: r*0.5 + 12}
 	objectRect := gmath.Rect{
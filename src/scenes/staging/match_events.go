@@ -0,0 +1,103 @@
+package staging
+
+import (
+	"math"
+
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+// matchEventsReportTicks is a resources gathered report interval (~1 minute).
+const matchEventsReportTicks = 60 * 60
+
+// matchEventLog collects the typed match events that are attached to the replay.
+//
+// The recording should never affect the simulation:
+// it only observes the world state.
+type matchEventLog struct {
+	world *worldState
+
+	events []serverapi.MatchEvent
+
+	nextReportTick    int
+	resourcesGathered []float64
+
+	bossStage int
+}
+
+func newMatchEventLog(world *worldState) *matchEventLog {
+	return &matchEventLog{
+		world:          world,
+		nextReportTick: matchEventsReportTicks,
+	}
+}
+
+func (l *matchEventLog) Events() []serverapi.MatchEvent { return l.events }
+
+// Update emits the periodic events.
+// It's called once per simulation step.
+func (l *matchEventLog) Update() {
+	if boss := l.world.boss; boss != nil && l.bossStage < 3 {
+		stage := gmath.Clamp(int((1-boss.health/boss.maxHealth)*4), 0, 3)
+		if stage > l.bossStage {
+			l.bossStage = stage
+			l.add(serverapi.EventBossStage, -1, stage, "", boss.pos)
+		}
+	}
+
+	if l.world.nodeRunner.ticks < l.nextReportTick {
+		return
+	}
+	l.nextReportTick += matchEventsReportTicks
+	for playerID, value := range l.resourcesGathered {
+		l.add(serverapi.EventResourcesGathered, playerID, int(math.Round(value)), "", gmath.Vec{})
+		l.resourcesGathered[playerID] = 0
+	}
+}
+
+func (l *matchEventLog) OnResourcesGathered(p player, value float64) {
+	id := p.GetState().id
+	for len(l.resourcesGathered) <= id {
+		l.resourcesGathered = append(l.resourcesGathered, 0)
+	}
+	l.resourcesGathered[id] += value
+}
+
+func (l *matchEventLog) OnColonyBuilt(p player, pos gmath.Vec) {
+	l.add(serverapi.EventColonyBuilt, p.GetState().id, 0, "", pos)
+}
+
+func (l *matchEventLog) OnColonyDestroyed(colony *colonyCoreNode) {
+	l.add(serverapi.EventColonyDestroyed, colony.player.GetState().id, 0, "", colony.pos)
+}
+
+func (l *matchEventLog) OnTier3Drone(colony *colonyCoreNode, drone *colonyAgentNode) {
+	l.add(serverapi.EventTier3Drone, colony.player.GetState().id, 0, drone.stats.Kind.String(), drone.pos)
+}
+
+func (l *matchEventLog) OnCreepBaseDestroyed(creep *creepNode) {
+	l.add(serverapi.EventCreepBaseDestroyed, -1, 0, creep.stats.Kind.String(), creep.pos)
+}
+
+func (l *matchEventLog) OnBossDefeated(boss *creepNode) {
+	l.bossStage = 4
+	l.add(serverapi.EventBossStage, -1, 4, "", boss.pos)
+}
+
+func (l *matchEventLog) OnArenaWave(level int) {
+	l.add(serverapi.EventArenaWave, -1, level, "", gmath.Vec{})
+}
+
+func (l *matchEventLog) add(kind serverapi.MatchEventKind, playerID, value int, name string, pos gmath.Vec) {
+	if len(l.events) >= serverapi.MaxMatchEvents {
+		return
+	}
+	l.events = append(l.events, serverapi.MatchEvent{
+		Tick:   l.world.nodeRunner.ticks,
+		Kind:   kind,
+		Player: playerID,
+		Value:  value,
+		Name:   name,
+		Pos:    [2]float64{pos.X, pos.Y},
+	})
+}
@@ -0,0 +1,70 @@
+package staging
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/serverapi"
+	"github.com/quasilyte/roboden-game/session"
+)
+
+// ResumeMatch configures the controller to continue the saved game.
+//
+// The level is generated from the saved config as usual,
+// then its dynamic part is replaced by the saved world snapshot.
+// A save that can't be decoded is removed and the game starts from scratch.
+func (c *Controller) ResumeMatch(save session.MatchSave) {
+	var world worldSnapshot
+	if err := json.Unmarshal(save.World, &world); err != nil {
+		c.state.Logf("can't decode the saved game: %v", err)
+		c.state.DeleteMatchSave()
+		return
+	}
+	c.resumed = true
+	c.resumeWorld = &world
+}
+
+func (c *Controller) canSaveMatch() bool {
+	if c.config.ExecMode != gamedata.ExecuteNormal || c.config.PlayersMode != serverapi.PmodeSinglePlayer {
+		return false
+	}
+	switch c.config.GameMode {
+	case gamedata.ModeClassic, gamedata.ModeArena, gamedata.ModeInfArena:
+		// These modes state can be saved.
+	default:
+		return false
+	}
+	if c.transitionQueued || c.gameFinished || c.replaySeekTick != 0 {
+		return false
+	}
+	if len(c.world.humanPlayers) == 0 {
+		return false
+	}
+	return len(c.world.humanPlayers[0].GetState().colonies) != 0
+}
+
+func (c *Controller) saveMatch() {
+	world, err := c.captureWorld()
+	if err != nil {
+		c.state.Logf("can't save the game: %v", err)
+		return
+	}
+	data, err := json.Marshal(world)
+	if err != nil {
+		c.state.Logf("can't save the game: %v", err)
+		return
+	}
+	save := session.MatchSave{
+		Date:   time.Now(),
+		Config: c.config.ReplayLevelConfig,
+		World:  data,
+		Time:   int(math.Floor(c.nodeRunner.timePlayed)),
+	}
+	for _, colony := range c.world.humanPlayers[0].GetState().colonies {
+		save.Colonies++
+		save.Drones += colony.NumAgents()
+	}
+	c.state.SaveMatch(save)
+}
@@ -0,0 +1,197 @@
+package staging
+
+import (
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+const (
+	// matchTelemetrySampleTicks is an initial sampling interval (~10 seconds).
+	matchTelemetrySampleTicks = 60 * 10
+
+	// maxMatchTelemetrySamples is a per-player samples limit.
+	// When it's reached, every second sample is removed and
+	// the sampling interval is doubled.
+	maxMatchTelemetrySamples = 240
+
+	numCreepKinds = int(gamedata.CreepGrenadier) + 1
+)
+
+// matchTelemetry samples the players stats during the game.
+// The samples are used to draw the post-match statistics graphs.
+//
+// Just like the match events, telemetry only observes the world state.
+type matchTelemetry struct {
+	world *worldState
+
+	interval       int
+	nextSampleTick int
+
+	players []*playerTelemetry
+}
+
+type playerTelemetry struct {
+	current telemetrySample
+	samples []telemetrySample
+}
+
+// telemetrySample is a player stats snapshot.
+// The resources gathered, creeps killed and damage values are cumulative.
+type telemetrySample struct {
+	Tick int
+
+	Resources         float64
+	ResourcesGathered float64
+
+	Colonies     int
+	DronesByTier [3]int
+	DronesByKind [gamedata.AgentKindNum]int
+
+	CreepsKilled [numCreepKinds]int
+
+	DamageDealt float64
+	DamageTaken float64
+}
+
+func newMatchTelemetry(world *worldState) *matchTelemetry {
+	return &matchTelemetry{
+		world:          world,
+		interval:       matchTelemetrySampleTicks,
+		nextSampleTick: matchTelemetrySampleTicks,
+	}
+}
+
+// Results returns the per-player timelines.
+// The last sample always describes the final game state.
+func (t *matchTelemetry) Results() [][]telemetrySample {
+	t.sample()
+	results := make([][]telemetrySample, len(t.players))
+	for i, p := range t.players {
+		results[i] = p.samples
+	}
+	return results
+}
+
+// BotStats returns the current player stats in the bot protocol format.
+func (t *matchTelemetry) BotStats(playerID int) serverapi.BotStats {
+	var stats serverapi.BotStats
+	pt := t.getPlayer(playerID)
+	if pt == nil {
+		return stats
+	}
+	stats.DamageDealt = pt.current.DamageDealt
+	stats.DamageTaken = pt.current.DamageTaken
+	stats.ResourcesGathered = pt.current.ResourcesGathered
+	for _, n := range pt.current.CreepsKilled {
+		stats.CreepsKilled += n
+	}
+	return stats
+}
+
+func (t *matchTelemetry) Update() {
+	if t.world.nodeRunner.ticks < t.nextSampleTick {
+		return
+	}
+	t.nextSampleTick += t.interval
+	t.sample()
+}
+
+func (t *matchTelemetry) OnResourcesGathered(p player, value float64) {
+	if pt := t.getPlayer(p.GetState().id); pt != nil {
+		pt.current.ResourcesGathered += value
+	}
+}
+
+func (t *matchTelemetry) OnCreepDamaged(creep *creepNode, healthDamage float64, source targetable) {
+	pt := t.getPlayer(t.sourcePlayerID(source))
+	if pt == nil {
+		return
+	}
+	if creep.health < 0 {
+		// Don't count the overkill damage.
+		healthDamage += creep.health
+		pt.current.CreepsKilled[creep.stats.Kind]++
+	}
+	pt.current.DamageDealt += healthDamage
+}
+
+func (t *matchTelemetry) OnColonyDamaged(p player, healthDamage float64, source targetable) {
+	if pt := t.getPlayer(p.GetState().id); pt != nil {
+		pt.current.DamageTaken += healthDamage
+	}
+	if pt := t.getPlayer(t.sourcePlayerID(source)); pt != nil {
+		pt.current.DamageDealt += healthDamage
+	}
+}
+
+func (t *matchTelemetry) sourcePlayerID(source targetable) int {
+	switch source := source.(type) {
+	case *colonyAgentNode:
+		if source != nil && source.colonyCore != nil {
+			return source.colonyCore.player.GetState().id
+		}
+	case *colonyCoreNode:
+		if source != nil {
+			return source.player.GetState().id
+		}
+	case *creepNode:
+		// In the reverse mode, the first player controls the creeps.
+		if t.world.creepsPlayerState != nil {
+			return 0
+		}
+	}
+	return -1
+}
+
+func (t *matchTelemetry) getPlayer(id int) *playerTelemetry {
+	if id < 0 || id >= len(t.world.players) {
+		return nil
+	}
+	for len(t.players) <= id {
+		t.players = append(t.players, &playerTelemetry{})
+	}
+	return t.players[id]
+}
+
+func (t *matchTelemetry) sample() {
+	tick := t.world.nodeRunner.ticks
+
+	// All players are sampled at the same ticks,
+	// so it's enough to check only one of them.
+	if len(t.players) != 0 && len(t.players[0].samples) == maxMatchTelemetrySamples {
+		t.interval *= 2
+		for _, pt := range t.players {
+			filtered := pt.samples[:0]
+			for i, s := range pt.samples {
+				if i%2 == 1 {
+					filtered = append(filtered, s)
+				}
+			}
+			pt.samples = filtered
+		}
+	}
+
+	for i, p := range t.world.players {
+		pt := t.getPlayer(i)
+		if n := len(pt.samples); n != 0 && pt.samples[n-1].Tick == tick {
+			continue
+		}
+
+		pstate := p.GetState()
+		s := pt.current
+		s.Tick = tick
+		s.Resources = pstate.resourceStash
+		s.Colonies = len(pstate.colonies)
+		for _, colony := range pstate.colonies {
+			s.Resources += colony.resources
+			colony.agents.Each(func(a *colonyAgentNode) {
+				s.DronesByTier[gmath.Clamp(a.stats.Tier, 1, 3)-1]++
+				if a.stats.Kind < gamedata.AgentKindNum {
+					s.DronesByKind[a.stats.Kind]++
+				}
+			})
+		}
+		pt.samples = append(pt.samples, s)
+	}
+}
@@ -89,6 +89,26 @@ func (r *nodeRunner) AddObject(o ge.SceneObject) {
 	o.Init(r.scene)
 }
 
+// walkObjects calls f for every object, including the ones added during this tick.
+func (r *nodeRunner) walkObjects(f func(o ge.SceneObject)) {
+	for _, o := range r.objects {
+		f(o)
+	}
+	for _, o := range r.addedObjects {
+		f(o)
+	}
+}
+
+// walkProjectiles is like walkObjects, but for the projectiles.
+func (r *nodeRunner) walkProjectiles(f func(p *projectileNode)) {
+	for _, p := range r.projectiles {
+		f(p)
+	}
+	for _, p := range r.addedProjectiles {
+		f(p)
+	}
+}
+
 func (r *nodeRunner) ComputeDelta(delta float64) float64 {
 	return delta * r.speedMultiplier
 }
@@ -134,12 +154,20 @@ func (r *nodeRunner) NumSteps() int {
 	return r.numSteps
 }
 
+// TicksPerUpdate returns the number of ticks executed by a single Update call.
+func (r *nodeRunner) TicksPerUpdate() int {
+	if r.speedMultiplier == 2 {
+		return 2
+	}
+	return 1
+}
+
 func (r *nodeRunner) Update(delta float64) {
 	if r.paused {
 		return
 	}
 
-	if r.speedMultiplier == 2 {
+	if r.TicksPerUpdate() == 2 {
 		// Run two ticks at x1 speed.
 		r.runTick(delta)
 		r.runTick(delta)
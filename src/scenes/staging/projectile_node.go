@@ -34,6 +34,10 @@ type projectileNode struct {
 	disposed bool
 	sprite   *ge.Sprite
 
+	// A magma projectile may spawn a rock on detonation.
+	rockSpawner  *lavaPuddleNode
+	rockSpawnPos gmath.Vec
+
 	EventDetonated gsignal.Event[gmath.Vec]
 }
 
@@ -1,9 +1,6 @@
 package staging
 
 import (
-	"fmt"
-	"time"
-
 	"github.com/quasilyte/gmath"
 	"github.com/quasilyte/roboden-game/serverapi"
 )
@@ -14,13 +11,20 @@ type replayPlayer struct {
 	choiceGen *choiceGenerator
 
 	state *playerState
+
+	// actions are the replay actions that are not executed yet.
+	actions []serverapi.PlayerAction
+
+	// actionIndex is the index of the next replay action.
+	actionIndex int
 }
 
-func newReplayPlayer(world *worldState, state *playerState, choiceGen *choiceGenerator) *replayPlayer {
+func newReplayPlayer(world *worldState, state *playerState, choiceGen *choiceGenerator, actions []serverapi.PlayerAction) *replayPlayer {
 	return &replayPlayer{
 		world:     world,
 		state:     state,
 		choiceGen: choiceGen,
+		actions:   actions,
 	}
 }
 
@@ -31,19 +35,19 @@ func (p *replayPlayer) Init() {
 }
 
 func (p *replayPlayer) Update(computedDelta, delta float64) {
-	for len(p.state.replay) > 0 {
-		a := p.state.replay[0]
+	for len(p.actions) > 0 {
+		a := p.actions[0]
 		if p.world.nodeRunner.ticks > a.Tick {
-			panic(errIllegalAction)
+			panic(p.illegalActionError(a, errMissedActionTick))
 		}
 		if a.Tick != p.world.nodeRunner.ticks {
 			return
 		}
-		p.state.replay = p.state.replay[1:]
+		p.actions = p.actions[1:]
 
 		if p.choiceGen.creepsState == nil {
 			if a.SelectedColony < 0 || a.SelectedColony >= len(p.state.colonies) {
-				panic(errInvalidColonyIndex)
+				panic(p.illegalActionError(a, errInvalidColonyIndex))
 			}
 			if p.world.GetColonyIndex(p.state.selectedColony) != a.SelectedColony {
 				p.state.selectedColony = p.state.colonies[a.SelectedColony]
@@ -57,10 +61,24 @@ func (p *replayPlayer) Update(computedDelta, delta float64) {
 			ok = p.choiceGen.TryExecute(p.state.selectedColony, int(a.Kind)-1, gmath.Vec{})
 		}
 		if !ok {
-			fmt.Println("fail at", a.Tick, time.Second*time.Duration(p.world.nodeRunner.timePlayed), "player=", p.state.id, "action=", a.Kind)
-			panic(errIllegalAction)
+			panic(p.illegalActionError(a, errActionRejected))
 		}
+		p.actionIndex++
+	}
+}
+
+func (p *replayPlayer) illegalActionError(a serverapi.PlayerAction, reason error) *IllegalActionError {
+	err := &IllegalActionError{
+		Tick:        p.world.nodeRunner.ticks,
+		PlayerID:    p.state.id,
+		ActionIndex: p.actionIndex,
+		Action:      a,
+		Reason:      reason,
+	}
+	if p.world.debugLogs {
+		p.world.sessionState.Logf("replay failure: %v", err)
 	}
+	return err
 }
 
 func (p *replayPlayer) GetState() *playerState { return p.state }
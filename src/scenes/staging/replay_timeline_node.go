@@ -0,0 +1,162 @@
+package staging
+
+import (
+	"image/color"
+	"time"
+
+	"github.com/quasilyte/ge"
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/assets"
+	"github.com/quasilyte/roboden-game/serverapi"
+	"github.com/quasilyte/roboden-game/timeutil"
+	"github.com/quasilyte/roboden-game/viewport"
+)
+
+var (
+	replayTimelineBackgroundColor = color.RGBA{R: 0x12, G: 0x14, B: 0x1a, A: 200}
+	replayTimelineProgressColor   = ge.RGB(0x9dd793)
+	replayTimelineScrubColor      = ge.RGB(0xe8e8e8)
+
+	// Every player has its own markers color.
+	replayTimelineMarkerColors = [...]color.RGBA{
+		ge.RGB(0x6e8ebd),
+		ge.RGB(0xd46a6a),
+	}
+)
+
+// replayTimelineNode is a replay viewer progress bar.
+//
+// The player actions are displayed as markers above the bar.
+// The user can click the bar (or drag along it) to select the tick to seek to;
+// the controller does the actual seeking.
+type replayTimelineNode struct {
+	cam *viewport.Camera
+
+	rect gmath.Rect
+
+	actions   [][]serverapi.PlayerAction
+	numTicks  int
+	totalTime time.Duration
+
+	progress *ge.Rect
+	scrub    *ge.Rect
+	label    *ge.Label
+}
+
+func newReplayTimelineNode(cam *viewport.Camera, replay *serverapi.GameReplay) *replayTimelineNode {
+	numTicks := replay.Results.Ticks
+	if numTicks == 0 {
+		// Should never happen for the valid replays,
+		// but let's make sure that all markers fit the timeline.
+		for _, actions := range replay.Actions {
+			if len(actions) != 0 {
+				numTicks = gmath.ClampMin(numTicks, actions[len(actions)-1].Tick)
+			}
+		}
+	}
+	return &replayTimelineNode{
+		cam:       cam,
+		actions:   replay.Actions,
+		numTicks:  gmath.ClampMin(numTicks, 1),
+		totalTime: time.Second * time.Duration(replay.Results.Time),
+	}
+}
+
+func (n *replayTimelineNode) Init(scene *ge.Scene) {
+	const barHeight = 6
+	const markerHeight = 5
+	const margin = 200
+
+	width := n.cam.Rect.Width()
+	n.rect = gmath.Rect{
+		Min: gmath.Vec{X: margin, Y: 14},
+		Max: gmath.Vec{X: width - margin, Y: 14 + barHeight},
+	}
+
+	bg := ge.NewRect(scene.Context(), n.rect.Width(), n.rect.Height())
+	bg.Centered = false
+	bg.Pos.Offset = n.rect.Min
+	bg.FillColorScale.SetColor(replayTimelineBackgroundColor)
+	n.cam.UI.AddGraphics(bg)
+
+	n.progress = ge.NewRect(scene.Context(), 0, n.rect.Height())
+	n.progress.Centered = false
+	n.progress.Pos.Offset = n.rect.Min
+	n.progress.FillColorScale.SetColor(replayTimelineProgressColor)
+	n.cam.UI.AddGraphics(n.progress)
+
+	// Several actions can be mapped to the same pixel column;
+	// there is no need to create a separate marker for each of them.
+	for playerID, actions := range n.actions {
+		clr := replayTimelineMarkerColors[playerID%len(replayTimelineMarkerColors)]
+		lastColumn := -1
+		for _, a := range actions {
+			column := int(n.tickToX(a.Tick))
+			if column == lastColumn {
+				continue
+			}
+			lastColumn = column
+			marker := ge.NewRect(scene.Context(), 1, markerHeight)
+			marker.Centered = false
+			marker.Pos.Offset = gmath.Vec{
+				X: float64(column),
+				Y: n.rect.Min.Y - markerHeight - 1 - float64(playerID*(markerHeight+1)),
+			}
+			marker.FillColorScale.SetColor(clr)
+			n.cam.UI.AddGraphics(marker)
+		}
+	}
+
+	n.scrub = ge.NewRect(scene.Context(), 2, n.rect.Height()+6)
+	n.scrub.Centered = false
+	n.scrub.FillColorScale.SetColor(replayTimelineScrubColor)
+	n.scrub.Visible = false
+	n.cam.UI.AddGraphicsAbove(n.scrub)
+
+	n.label = ge.NewLabel(assets.Font1)
+	n.label.SetColorScaleRGBA(0x9d, 0xd7, 0x93, 0xff)
+	n.label.Pos.Offset = gmath.Vec{X: n.rect.Max.X + 8, Y: n.rect.Min.Y - 6}
+	n.cam.UI.AddGraphics(n.label)
+}
+
+func (n *replayTimelineNode) ContainsPos(pos gmath.Vec) bool {
+	// Make the clickable area a bit bigger than the bar itself.
+	return resizedRect(n.rect, 6).Contains(pos)
+}
+
+// TickAt returns the tick that corresponds to the screen pos.
+// The pos X is clamped to the timeline bounds.
+func (n *replayTimelineNode) TickAt(pos gmath.Vec) int {
+	x := gmath.Clamp(pos.X, n.rect.Min.X, n.rect.Max.X)
+	return int(((x - n.rect.Min.X) / n.rect.Width()) * float64(n.numTicks))
+}
+
+func (n *replayTimelineNode) NumTicks() int { return n.numTicks }
+
+// SetProgress updates the bar to the current replay position.
+func (n *replayTimelineNode) SetProgress(tick int, timePlayed time.Duration) {
+	n.progress.Width = n.tickToX(tick) - n.rect.Min.X
+	if !n.scrub.Visible {
+		n.label.Text = timeutil.FormatDurationCompact(timePlayed) + " / " + timeutil.FormatDurationCompact(n.totalTime)
+	}
+}
+
+// SetScrubTick shows the selected, but not yet reached, tick.
+// A negative tick hides the scrub marker.
+func (n *replayTimelineNode) SetScrubTick(tick int) {
+	if tick < 0 {
+		n.scrub.Visible = false
+		return
+	}
+	n.scrub.Visible = true
+	n.scrub.Pos.Offset = gmath.Vec{X: n.tickToX(tick) - 1, Y: n.rect.Min.Y - 3}
+	// The exact time is unknown until the tick is simulated,
+	// but the replay time is roughly proportional to its ticks.
+	t := time.Duration(float64(n.totalTime) * (float64(tick) / float64(n.numTicks))).Truncate(time.Second)
+	n.label.Text = timeutil.FormatDurationCompact(t) + " / " + timeutil.FormatDurationCompact(n.totalTime)
+}
+
+func (n *replayTimelineNode) tickToX(tick int) float64 {
+	t := gmath.Clamp(float64(tick)/float64(n.numTicks), 0, 1)
+	return n.rect.Min.X + t*n.rect.Width()
+}
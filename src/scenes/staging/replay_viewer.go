@@ -0,0 +1,231 @@
+package staging
+
+import (
+	"time"
+
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/controls"
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+// replaySeekTicksPerFrame limits the amount of work done during a single frame
+// while seeking the replay; this way the game doesn't freeze for too long.
+// It's ~10 seconds of the game time per frame.
+const replaySeekTicksPerFrame = 600
+
+// replaySeekStepTicks is a number of ticks to skip
+// with the seek forward/backward keys.
+const replaySeekStepTicks = 60 * 10
+
+// The replay viewer seeking works by re-simulation:
+// a restored world snapshot is not bit-identical to the original world
+// (see worldSnapshot), but the simulation is deterministic and the replay
+// actions are known, so any tick can be reached by running the simulation from the start.
+//
+// Seeking forward continues the current simulation without rendering.
+// Seeking backward re-creates the controller and then seeks forward.
+//
+// The replay take over works in a similar way: a new normal mode controller
+// seeks to the current tick while executing the replay actions
+// on behalf of the human players. These actions are recorded as usual,
+// so the resulting replay contains the original prefix and can be verified.
+
+func (c *Controller) initReplayViewer() {
+	if c.replay == nil || len(c.world.humanPlayers) == 0 {
+		return
+	}
+
+	spectator := c.world.humanPlayers[0]
+	cam := spectator.GetState().camera
+	c.replayTimeline = newReplayTimelineNode(cam.Camera, c.replay)
+	c.replayTimeline.Init(c.scene)
+
+	if !c.replayCameraPos.IsZero() {
+		cam.CenterOn(c.replayCameraPos)
+	}
+	if c.replayPaused {
+		c.onPausePressed()
+	}
+}
+
+// SeekReplay makes the replay viewer jump to the specified tick.
+// It can be called before the scene is initialized to start
+// the replay from the given tick.
+//
+// Only ExecuteReplay mode controllers can seek.
+func (c *Controller) SeekReplay(tick int) {
+	if c.config.ExecMode != gamedata.ExecuteReplay || c.replay == nil {
+		return
+	}
+	if c.world == nil {
+		c.replaySeekTick = tick
+		return
+	}
+	if c.transitionQueued {
+		return
+	}
+
+	tick = gmath.Clamp(tick, 0, c.replayTimeline.NumTicks())
+	if tick >= c.nodeRunner.ticks {
+		c.replaySeekTick = tick
+		return
+	}
+
+	// Going back in time requires a fresh simulation.
+	cam := c.world.humanPlayers[0].GetState().camera
+	controller := NewController(c.state, c.config, c.backController)
+	controller.SetReplayActions(*c.replay)
+	controller.replaySeekTick = tick
+	controller.replayCameraPos = cam.AbsPos(cam.Rect.Center())
+	controller.replayPaused = c.nodeRunner.IsPaused() && len(c.exitNotices) == 0
+	c.scene.Context().ChangeScene(controller)
+}
+
+func (c *Controller) runReplaySeek() {
+	c.setAudioMuted(true)
+	paused := c.nodeRunner.paused
+	c.nodeRunner.paused = false
+
+	delta := 1.0 / 60.0
+	computedDelta := c.nodeRunner.ComputeDelta(delta)
+	for i := 0; i < replaySeekTicksPerFrame; i++ {
+		if c.transitionQueued || c.nodeRunner.ticks >= c.replaySeekTick {
+			c.replaySeekTick = 0
+			break
+		}
+		c.runUpdateStep(computedDelta, delta)
+	}
+
+	c.nodeRunner.paused = paused
+	c.setAudioMuted(false)
+
+	if c.replayTimeline != nil {
+		c.replayTimeline.SetProgress(c.nodeRunner.ticks, time.Second*time.Duration(c.nodeRunner.timePlayed))
+	}
+	if c.replaySeekTick != 0 {
+		return
+	}
+
+	// The seeking is finished.
+	if c.replayTimeline != nil {
+		c.replayTimeline.SetScrubTick(-1)
+	}
+	if c.fogOfWar != nil {
+		for _, colony := range c.world.allColonies {
+			c.updateFogOfWar(colony.pos)
+		}
+	}
+	if c.takeoverPlayers != nil {
+		// The player is in control now.
+		// Give them some time to look around.
+		c.takeoverPlayers = nil
+		c.onPausePressed()
+	}
+}
+
+// canTakeOver reports whether the watched replay can be continued by the user.
+// Only the replays with a single human player are supported.
+func (c *Controller) canTakeOver() bool {
+	if c.transitionQueued {
+		return false
+	}
+	switch c.config.PlayersMode {
+	case serverapi.PmodeSinglePlayer, serverapi.PmodePlayerAndBot:
+		return true
+	default:
+		return false
+	}
+}
+
+// takeOverReplay starts a normal game session that continues
+// the watched replay from the current tick.
+func (c *Controller) takeOverReplay() {
+	tick := c.nodeRunner.ticks
+
+	// The actions with the current tick are already executed.
+	prefix := make([][]serverapi.PlayerAction, len(c.replay.Actions))
+	for i, actions := range c.replay.Actions {
+		n := 0
+		for n < len(actions) && actions[n].Tick <= tick {
+			n++
+		}
+		prefix[i] = actions[:n:n]
+	}
+
+	config := gamedata.MakeLevelConfig(gamedata.ExecuteNormal, c.replay.Config)
+	config.Finalize()
+	cam := c.world.humanPlayers[0].GetState().camera
+	controller := NewController(c.state, config, c.backController)
+	controller.takeoverActions = prefix
+	controller.replaySeekTick = tick
+	controller.replayCameraPos = cam.AbsPos(cam.Rect.Center())
+	c.leaveScene(controller)
+}
+
+func (c *Controller) initTakeover() {
+	c.takeoverPlayers = make([]*replayPlayer, len(c.world.players))
+	for i, p := range c.world.players {
+		human, ok := p.(*humanPlayer)
+		if !ok || i >= len(c.takeoverActions) {
+			continue
+		}
+		c.takeoverPlayers[i] = newReplayPlayer(c.world, human.state, human.choiceGen, c.takeoverActions[i])
+	}
+
+	if !c.replayCameraPos.IsZero() && len(c.world.humanPlayers) != 0 {
+		c.world.humanPlayers[0].GetState().camera.CenterOn(c.replayCameraPos)
+	}
+}
+
+// stepReplay executes exactly one simulation step.
+// It's only useful while the game is paused.
+func (c *Controller) stepReplay() {
+	if c.transitionQueued {
+		return
+	}
+	c.nodeRunner.paused = false
+	delta := 1.0 / 60.0
+	c.runUpdateStep(c.nodeRunner.ComputeDelta(delta), delta)
+	c.nodeRunner.paused = true
+}
+
+func (c *Controller) handleReplayInput() {
+	if c.replayTimeline == nil || c.nodeRunner.exitPrompt {
+		return
+	}
+
+	h := c.world.humanPlayers[0].input
+
+	if c.replayScrubbing {
+		if h.ActionIsPressed(controls.ActionClick) {
+			c.replayScrubTick = c.replayTimeline.TickAt(h.AnyCursorPos())
+			c.replayTimeline.SetScrubTick(c.replayScrubTick)
+			return
+		}
+		c.replayScrubbing = false
+		c.SeekReplay(c.replayScrubTick)
+		return
+	}
+	if clickPos, ok := h.ClickPos(controls.ActionClick); ok && c.replayTimeline.ContainsPos(clickPos) {
+		c.replayScrubbing = true
+		c.replayScrubTick = c.replayTimeline.TickAt(clickPos)
+		c.replayTimeline.SetScrubTick(c.replayScrubTick)
+		return
+	}
+
+	switch {
+	case h.ActionIsJustPressed(controls.ActionReplayTakeOver):
+		if c.canTakeOver() {
+			c.takeOverReplay()
+		}
+	case h.ActionIsJustPressed(controls.ActionReplaySeekForward):
+		c.SeekReplay(c.nodeRunner.ticks + replaySeekStepTicks)
+	case h.ActionIsJustPressed(controls.ActionReplaySeekBackward):
+		c.SeekReplay(c.nodeRunner.ticks - replaySeekStepTicks)
+	case c.nodeRunner.IsPaused() && h.ActionIsJustPressed(controls.ActionReplayStep):
+		c.stepReplay()
+	case c.nodeRunner.IsPaused() && h.ActionIsJustPressed(controls.ActionReplayStepBack):
+		c.SeekReplay(c.nodeRunner.ticks - c.nodeRunner.TicksPerUpdate())
+	}
+}
@@ -26,13 +26,22 @@ type resultsController struct {
 
 	hasPlayers bool
 
+	// initialized is set after the first Init call.
+	// The stats screen returns to this controller,
+	// so the progress must not be updated twice.
+	initialized bool
+
 	scene          *ge.Scene
 	backController ge.SceneController
 
 	resultTag string
+	victory   bool
 	highScore bool
 	rewards   *gameRewards
 
+	replay      serverapi.GameReplay
+	savedReplay session.SavedReplay
+
 	results battleResults
 }
 
@@ -92,6 +101,17 @@ type battleResults struct {
 	NumPauses        int
 	NumFastForwards  int
 	DebugCheckpoints []int
+
+	StateHashInterval int
+	StateHashes       []serverapi.StateHash
+
+	Events []serverapi.MatchEvent
+
+	// Telemetry is a per-player stats timeline.
+	Telemetry [][]telemetrySample
+
+	// Resumed is set if the game was continued from a mid-match save.
+	Resumed bool
 }
 
 func newResultsController(state *session.State, config *gamedata.LevelConfig, backController ge.SceneController, results battleResults) *resultsController {
@@ -107,22 +127,47 @@ func (c *resultsController) Init(scene *ge.Scene) {
 	c.scene = scene
 	eui.AddBackground(c.state.BackgroundImage, scene)
 
-	switch c.config.PlayersMode {
-	case serverapi.PmodeSinglePlayer, serverapi.PmodeTwoPlayers:
-		c.hasPlayers = true
-	}
+	if !c.initialized {
+		c.initialized = true
 
-	c.rewards = &gameRewards{}
+		switch c.config.PlayersMode {
+		case serverapi.PmodeSinglePlayer, serverapi.PmodeTwoPlayers:
+			c.hasPlayers = true
+		}
 
-	victory := c.results.Victory || c.config.GameMode == gamedata.ModeInfArena
-	if victory {
-		c.updateProgress()
-		c.state.SaveGameItem("save.json", c.state.Persistent)
+		c.rewards = &gameRewards{}
+
+		victory := c.results.Victory || c.config.GameMode == gamedata.ModeInfArena
+		if victory {
+			c.updateProgress()
+			c.state.SaveGameItem("save.json", c.state.Persistent)
+		}
+
+		c.resultTag, c.victory = c.calcResultTag()
+		c.saveReplay()
 	}
 
 	c.initUI()
 }
 
+func (c *resultsController) saveReplay() {
+	c.replay = c.makeGameReplay()
+	if c.config.GameMode != gamedata.ModeTutorial && c.highScore && !c.results.Resumed {
+		c.state.SentHighscores = false
+		key := c.config.RawGameMode + "_highscore.json"
+		c.state.SaveGameItem(key, c.replay)
+	}
+	if gamedata.IsRunnableReplay(c.replay) {
+		c.savedReplay = session.SavedReplay{
+			Date:      time.Now(),
+			ResultTag: c.resultTag,
+			Replay:    c.replay,
+		}
+		recentReplayKey := c.state.ReplayDataKey(0)
+		c.state.SaveGameItem(recentReplayKey, c.savedReplay)
+	}
+}
+
 func (c *resultsController) makeGameReplay() serverapi.GameReplay {
 	var replay serverapi.GameReplay
 	replay.Date = timeutil.FormatDateISO8601(time.Now(), false)
@@ -142,10 +187,31 @@ func (c *resultsController) makeGameReplay() serverapi.GameReplay {
 	replay.Debug.NumFastForward = c.results.NumFastForwards
 	replay.Debug.GOARCH = runtime.GOARCH
 	replay.Debug.GOOS = runtime.GOOS
+	replay.Debug.Resumed = c.results.Resumed
 
 	replay.Debug.Checkpoints = make([]int, len(c.results.DebugCheckpoints))
 	copy(replay.Debug.Checkpoints, c.results.DebugCheckpoints)
 
+	if len(c.results.StateHashes) != 0 {
+		replay.Debug.StateHashInterval = c.results.StateHashInterval
+		replay.Debug.StateHashes = make([]serverapi.StateHash, len(c.results.StateHashes))
+		copy(replay.Debug.StateHashes, c.results.StateHashes)
+	}
+
+	// The events are only kept in the local replays,
+	// see serverapi.SubmissionReplay.
+	replay.Events = append([]serverapi.MatchEvent{}, c.results.Events...)
+	// The achievements are checked after the game is over.
+	for _, achievements := range [][]string{c.rewards.newAchievements, c.rewards.upgradedAchievements} {
+		for _, name := range achievements {
+			replay.Events = append(replay.Events, serverapi.MatchEvent{
+				Tick: c.results.Ticks,
+				Kind: serverapi.EventAchievement,
+				Name: name,
+			})
+		}
+	}
+
 	return replay
 }
 
@@ -417,10 +483,8 @@ func (c *resultsController) initUI() {
 
 	d := c.scene.Dict()
 
-	var victory bool
-	c.resultTag, victory = c.calcResultTag()
 	titleString := d.Get(c.resultTag)
-	if victory {
+	if c.victory {
 		titleString += "!"
 	}
 
@@ -465,21 +529,14 @@ func (c *resultsController) initUI() {
 
 	rowContainer.AddChild(panel)
 
-	replay := c.makeGameReplay()
-	if c.config.GameMode != gamedata.ModeTutorial && c.highScore {
-		c.state.SentHighscores = false
-		key := c.config.RawGameMode + "_highscore.json"
-		c.state.SaveGameItem(key, replay)
+	if len(c.results.Telemetry) != 0 {
+		rowContainer.AddChild(eui.NewButton(uiResources, c.scene, d.Get("menu.results.stats"), func() {
+			c.scene.Context().ChangeScene(newStatsController(c.state, c.config, c, &c.results, statsGraphResources))
+		}))
 	}
-	if gamedata.IsRunnableReplay(replay) {
-		r := session.SavedReplay{
-			Date:      time.Now(),
-			ResultTag: c.resultTag,
-			Replay:    replay,
-		}
-		recentReplayKey := c.state.ReplayDataKey(0)
-		c.state.SaveGameItem(recentReplayKey, r)
 
+	replay := c.replay
+	if gamedata.IsRunnableReplay(replay) {
 		saved := false
 		rowContainer.AddChild(eui.NewButton(uiResources, c.scene, d.Get("menu.save_replay"), func() {
 			// Let the user press button several times, but don't do any extra
@@ -488,8 +545,7 @@ func (c *resultsController) initUI() {
 				return
 			}
 			saved = true
-			k := c.state.ReplayDataKey(c.state.FindNextReplayIndex())
-			c.state.SaveGameItem(k, r)
+			c.state.AddLibraryReplay(c.savedReplay)
 		}))
 	}
 	if gamedata.IsSendableReplay(replay) {
@@ -62,8 +62,9 @@ type Controller struct {
 
 	tutorialManager *tutorialManager
 
-	arenaManager *arenaManager
-	nodeRunner   *nodeRunner
+	arenaManager   *arenaManager
+	classicManager *classicManager
+	nodeRunner     *nodeRunner
 
 	debugInfo        *ge.Label
 	debugUpdateDelay float64
@@ -72,9 +73,40 @@ type Controller struct {
 	weatherPower  float64
 	weatherTicker float64
 
-	controllerTick    int
-	replayActions     [][]serverapi.PlayerAction
-	replayCheckpoints []int
+	controllerTick          int
+	replay                  *serverapi.GameReplay
+	replayActions           [][]serverapi.PlayerAction
+	replayCheckpoints       []int
+	replayStateHashes       []serverapi.StateHash
+	replayStateHashInterval int
+	stateDesync             *StateDesync
+
+	// These fields are only used by the replay viewer (ExecuteReplay mode).
+	replayTimeline  *replayTimelineNode
+	replaySeekTick  int
+	replayScrubbing bool
+	replayScrubTick int
+	replayCameraPos gmath.Vec
+	replayPaused    bool
+
+	// takeoverActions are the replay actions that were executed
+	// before the replay take over; they're executed by the takeoverPlayers
+	// on behalf of the human players.
+	// The takeoverPlayers are indexed by the player ID; nil for the computer players.
+	takeoverActions [][]serverapi.PlayerAction
+	takeoverPlayers []*replayPlayer
+
+	// resumed is set for the games continued from a mid-match save.
+	// The resumeWorld is applied right after the level generation.
+	resumed     bool
+	resumeWorld *worldSnapshot
+
+	// externalBots are indexed by the player ID.
+	// These players are controlled by the external bot programs.
+	externalBots map[int]*externalBotConn
+
+	// botProfiles override the config bot profile for some of the computer players.
+	botProfiles map[int]*gamedata.BotProfile
 
 	EventBeforeLeaveScene gsignal.Event[gsignal.Void]
 }
@@ -94,8 +126,11 @@ func NewController(state *session.State, config gamedata.LevelConfig, back ge.Sc
 }
 
 func (c *Controller) SetReplayActions(replay serverapi.GameReplay) {
+	c.replay = &replay
 	c.replayActions = replay.Actions
 	c.replayCheckpoints = replay.Debug.Checkpoints
+	c.replayStateHashes = replay.Debug.StateHashes
+	c.replayStateHashInterval = replay.Debug.StateHashInterval
 }
 
 func (c *Controller) CenterDemoCamera(pos gmath.Vec) {
@@ -276,6 +311,33 @@ func (c *Controller) IsExcitingDemoFrame() (gmath.Vec, bool) {
 	return gmath.Vec{}, false
 }
 
+// SetBotProfile overrides the computer player profile.
+// Unlike the config BotProfile, it's not recorded in the replays,
+// so it should only be used for the simulations, like the bot tournaments.
+func (c *Controller) SetBotProfile(playerID int, profile *gamedata.BotProfile) {
+	if c.botProfiles == nil {
+		c.botProfiles = make(map[int]*gamedata.BotProfile)
+	}
+	c.botProfiles[playerID] = profile
+}
+
+func (c *Controller) getBotProfile(playerID int) *gamedata.BotProfile {
+	if profile := c.botProfiles[playerID]; profile != nil {
+		return profile
+	}
+	return gamedata.BotProfiles[c.config.BotProfile]
+}
+
+// GetPlayerStats returns the player stats collected so far.
+func (c *Controller) GetPlayerStats(playerID int) serverapi.BotStats {
+	return c.world.telemetry.BotStats(playerID)
+}
+
+// GetTicks returns the number of the simulated world ticks.
+func (c *Controller) GetTicks() int {
+	return c.nodeRunner.ticks
+}
+
 func (c *Controller) GetSimulationResult() (serverapi.GameResults, bool) {
 	var result serverapi.GameResults
 	if !c.gameFinished {
@@ -283,7 +345,7 @@ func (c *Controller) GetSimulationResult() (serverapi.GameResults, bool) {
 	}
 	for _, p := range c.world.players {
 		p, ok := p.(*replayPlayer)
-		if ok && len(p.state.replay) != 0 {
+		if ok && len(p.actions) != 0 {
 			panic(errExcessiveAcions)
 		}
 	}
@@ -406,10 +468,24 @@ func (c *Controller) doInit(scene *ge.Scene) {
 		envKind:      gamedata.EnvironmentKind(c.config.Environment),
 		mapShape:     gamedata.WorldShape(c.config.WorldShape),
 	}
+	world.matchEvents = newMatchEventLog(world)
+	world.telemetry = newMatchTelemetry(world)
 	if world.seedKind != gamedata.SeedNormal {
 		scene.Audio().PlaySound(assets.AudioWaveStart)
 	}
 
+	switch c.config.ExecMode {
+	case gamedata.ExecuteReplay, gamedata.ExecuteSimulation:
+		// The hashes are compared, so the recorded interval is used.
+		// The older replays have no hashes; they're not computed at all.
+		world.result.StateHashInterval = c.replayStateHashInterval
+	default:
+		world.result.StateHashInterval = c.config.StateHashInterval
+		if world.result.StateHashInterval == 0 {
+			world.result.StateHashInterval = defaultStateHashInterval
+		}
+	}
+
 	switch world.mapShape {
 	case gamedata.WorldSquare:
 		world.innerRect = resizedRect(world.rect, -180)
@@ -560,8 +636,8 @@ func (c *Controller) doInit(scene *ge.Scene) {
 		c.nodeRunner.AddObject(c.arenaManager)
 		c.arenaManager.EventVictory.Connect(c, c.onVictoryTrigger)
 	case gamedata.ModeClassic:
-		classicManager := newClassicManager(world)
-		c.nodeRunner.AddObject(classicManager)
+		c.classicManager = newClassicManager(world)
+		c.nodeRunner.AddObject(c.classicManager)
 		// TODO: victory trigger should go to the classic manager
 	case gamedata.ModeBlitz:
 		blitz = newBlitzManager(world)
@@ -575,6 +651,16 @@ func (c *Controller) doInit(scene *ge.Scene) {
 		g.Generate()
 	}
 
+	if c.resumeWorld != nil {
+		if err := c.restoreWorld(c.resumeWorld); err != nil {
+			// This save is unusable, so it's removed.
+			c.state.Logf("can't resume the saved game: %v", err)
+			c.state.DeleteMatchSave()
+			c.resumeWorld = nil
+			c.leaveScene(c.backController)
+		}
+	}
+
 	for _, p := range c.world.players {
 		p.Init()
 	}
@@ -642,13 +728,24 @@ func (c *Controller) doInit(scene *ge.Scene) {
 	if c.arenaManager != nil {
 		c.arenaManager.LateInit()
 	}
+
+	if c.resumeWorld != nil {
+		c.lateRestoreWorld(c.resumeWorld)
+		c.resumeWorld = nil
+	}
+
+	switch {
+	case c.config.ExecMode == gamedata.ExecuteReplay:
+		c.initReplayViewer()
+	case c.takeoverActions != nil:
+		c.initTakeover()
+	}
 }
 
 func (c *Controller) runBlitzSetup(blitz *blitzManager) {
 	// TODO: move this method to a Blitz manager?
 
-	c.scene.Audio().SetGroupVolume(assets.SoundGroupMusic, 0)
-	c.scene.Audio().SetGroupVolume(assets.SoundGroupEffect, 0)
+	c.setAudioMuted(true)
 
 	timeSimulated := gamedata.BlitzModeSetupTime(c.world.numPlayers)
 	numFrames := int(timeSimulated * 60 * (1 / c.nodeRunner.speedMultiplier))
@@ -691,6 +788,17 @@ func (c *Controller) runBlitzSetup(blitz *blitzManager) {
 
 	blitz.SpawnInitialCreeps()
 
+	c.setAudioMuted(false)
+}
+
+// setAudioMuted is used during the fast simulation steps,
+// like the blitz setup or the replay seeking.
+func (c *Controller) setAudioMuted(muted bool) {
+	if muted {
+		c.scene.Audio().SetGroupVolume(assets.SoundGroupMusic, 0)
+		c.scene.Audio().SetGroupVolume(assets.SoundGroupEffect, 0)
+		return
+	}
 	c.scene.Audio().SetGroupVolume(assets.SoundGroupMusic, assets.VolumeMultiplier(c.state.Persistent.Settings.MusicVolumeLevel))
 	c.scene.Audio().SetGroupVolume(assets.SoundGroupEffect, assets.VolumeMultiplier(c.state.Persistent.Settings.EffectsVolumeLevel))
 }
@@ -783,9 +891,7 @@ func (c *Controller) createHumanPlayer(pstate *playerState, choiceGen *choiceGen
 		c.world.config.ExecMode == gamedata.ExecuteSimulation
 
 	if isSimulation {
-		p := newReplayPlayer(c.world, pstate, choiceGen)
-		pstate.replay = c.replayActions[i]
-		return p
+		return newReplayPlayer(c.world, pstate, choiceGen, c.replayActions[i])
 	}
 
 	var creepsState *creepsPlayerState
@@ -866,8 +972,11 @@ func (c *Controller) createPlayers() {
 		pstate.Init(c.world)
 
 		var p player
-		switch pk {
-		case gamedata.PlayerHuman:
+		switch {
+		case c.externalBots[i] != nil:
+			p = newExternalPlayer(c.world, pstate, choiceGen, c.externalBots[i])
+
+		case pk == gamedata.PlayerHuman:
 			hasPlayers = true
 			if !isSimulation {
 				hasPlayerWithCamera = true
@@ -877,13 +986,13 @@ func (c *Controller) createPlayers() {
 				}
 			}
 			if c.config.GameMode == gamedata.ModeBlitz {
-				p = newComputerPlayer(c.world, pstate, choiceGen)
+				p = newComputerPlayer(c.world, pstate, choiceGen, c.getBotProfile(i))
 			} else {
 				p = c.createHumanPlayer(pstate, choiceGen)
 			}
 
-		case gamedata.PlayerComputer:
-			p = newComputerPlayer(c.world, pstate, choiceGen)
+		case pk == gamedata.PlayerComputer:
+			p = newComputerPlayer(c.world, pstate, choiceGen, c.getBotProfile(i))
 		default:
 			panic(fmt.Sprintf("unexpected player kind: %d", pk))
 		}
@@ -947,6 +1056,9 @@ func (c *Controller) onExitButtonClicked() {
 	c.removePauseNotices()
 
 	if len(c.exitNotices) != 0 {
+		if c.canSaveMatch() {
+			c.saveMatch()
+		}
 		c.leaveScene(c.backController)
 		return
 	}
@@ -1476,6 +1588,14 @@ func (c *Controller) prepareBattleResults() {
 	}
 
 	c.world.result.LevelGenChecksum = c.world.levelGenChecksum
+	c.world.result.Events = c.world.matchEvents.Events()
+	c.world.result.Telemetry = c.world.telemetry.Results()
+
+	if c.resumed {
+		// The saved game is over.
+		c.world.result.Resumed = true
+		c.state.DeleteMatchSave()
+	}
 
 	c.world.result.BossDefeated = c.world.boss == nil
 	c.world.result.SeedKind = c.world.seedKind
@@ -1631,6 +1751,7 @@ func (c *Controller) handleInput() bool {
 	}
 
 	if c.config.ExecMode == gamedata.ExecuteReplay {
+		c.handleReplayInput()
 		return true
 	}
 
@@ -1827,6 +1948,11 @@ func (c *Controller) updateCameraWeather(cam *viewport.Camera, shaderDelta float
 }
 
 func (c *Controller) Update(delta float64) {
+	if c.replaySeekTick != 0 {
+		c.runReplaySeek()
+		return
+	}
+
 	c.updateWeather(delta)
 
 	c.world.stage.Update()
@@ -1861,6 +1987,10 @@ func (c *Controller) Update(delta float64) {
 		p.AfterUpdateStep()
 	}
 
+	if c.replayTimeline != nil {
+		c.replayTimeline.SetProgress(c.nodeRunner.ticks, time.Second*time.Duration(c.nodeRunner.timePlayed))
+	}
+
 	if c.debugInfo != nil {
 		c.updateDebug(delta)
 	}
@@ -1884,14 +2014,33 @@ func (c *Controller) runUpdateStep(computedDelta, delta float64) {
 	if c.world.simulation && checkpoint {
 		i := len(c.world.result.DebugCheckpoints) - 1
 		if i < len(c.replayCheckpoints) && c.replayCheckpoints[i] != c.world.result.DebugCheckpoints[i] {
-			fmt.Printf("invalid checkpoint: %d vs %d\n", c.replayCheckpoints[i], c.world.result.DebugCheckpoints[i])
-			panic(errBadCheckpoint)
+			err := &BadCheckpointError{
+				Tick:     c.controllerTick,
+				Index:    i,
+				Expected: c.replayCheckpoints[i],
+				Actual:   c.world.result.DebugCheckpoints[i],
+			}
+			if c.world.debugLogs {
+				c.world.sessionState.Logf("replay failure: %v", err)
+			}
+			panic(err)
 		}
 		if c.world.debugLogs {
 			c.world.sessionState.Logf("checkpoint#%d: verified", i+1)
 		}
 	}
 
+	if interval := c.world.result.StateHashInterval; interval != 0 && c.controllerTick != 0 && c.controllerTick%interval == 0 {
+		if c.replayStateHashes != nil {
+			c.verifyStateHash()
+		} else if c.replayActions == nil {
+			c.recordStateHash()
+		}
+	}
+
+	c.world.matchEvents.Update()
+	c.world.telemetry.Update()
+
 	c.controllerTick++
 
 	if !c.transitionQueued {
@@ -1902,7 +2051,12 @@ func (c *Controller) runUpdateStep(computedDelta, delta float64) {
 		}
 	}
 
-	for _, p := range c.world.players {
+	for i, p := range c.world.players {
+		if c.takeoverPlayers != nil && c.takeoverPlayers[i] != nil {
+			// The prefix actions should be executed at exactly the same
+			// moment as they were executed by the replay player.
+			c.takeoverPlayers[i].Update(computedDelta, delta)
+		}
 		p.Update(computedDelta, delta)
 	}
 }
@@ -0,0 +1,187 @@
+package staging
+
+import (
+	"math"
+
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+// defaultStateHashInterval is used when the level config doesn't
+// specify the hashing interval. It's 10 seconds of the game time.
+const defaultStateHashInterval = 600
+
+// StateDesync describes the first state hash mismatch
+// between the replay and its simulation.
+type StateDesync struct {
+	Tick      int    `json:"tick"`
+	Subsystem string `json:"subsystem"`
+	Expected  uint32 `json:"expected"`
+	Actual    uint32 `json:"actual"`
+}
+
+// stateHasher is a FNV-1a hash function that accepts the simulation values.
+//
+// Only the deterministic data should be hashed: no map iteration,
+// no pointers, no graphics-related state.
+type stateHasher struct {
+	h uint64
+}
+
+func (h *stateHasher) Reset() {
+	h.h = 14695981039346656037
+}
+
+func (h *stateHasher) Sum32() uint32 {
+	return uint32(h.h) ^ uint32(h.h>>32)
+}
+
+func (h *stateHasher) AddUint64(v uint64) {
+	for i := 0; i < 8; i++ {
+		h.h ^= v & 0xff
+		h.h *= 1099511628211
+		v >>= 8
+	}
+}
+
+func (h *stateHasher) AddInt(v int) {
+	h.AddUint64(uint64(v))
+}
+
+func (h *stateHasher) AddFloat(v float64) {
+	h.AddUint64(math.Float64bits(v))
+}
+
+func (h *stateHasher) AddVec(v gmath.Vec) {
+	h.AddFloat(v.X)
+	h.AddFloat(v.Y)
+}
+
+func (c *Controller) computeStateHash() serverapi.StateHash {
+	w := c.world
+	result := serverapi.StateHash{Tick: c.controllerTick}
+
+	var h stateHasher
+
+	h.Reset()
+	for _, colony := range w.allColonies {
+		h.AddInt(colony.id)
+		h.AddInt(int(colony.mode))
+		h.AddVec(colony.pos)
+		h.AddFloat(colony.health)
+		h.AddInt(colony.agents.TotalNum())
+	}
+	result.Colonies = h.Sum32()
+
+	h.Reset()
+	hashAgent := func(a *colonyAgentNode) {
+		h.AddInt(int(a.stats.Kind))
+		h.AddInt(int(a.mode))
+		h.AddVec(a.pos)
+		h.AddFloat(a.health)
+		h.AddFloat(a.energy)
+	}
+	for _, colony := range w.allColonies {
+		colony.agents.Each(hashAgent)
+	}
+	for _, turret := range w.turrets {
+		hashAgent(turret)
+	}
+	for _, merc := range w.mercs {
+		hashAgent(merc)
+	}
+	result.Agents = h.Sum32()
+
+	h.Reset()
+	for _, creep := range w.creeps {
+		h.AddInt(int(creep.stats.Kind))
+		h.AddVec(creep.pos)
+		h.AddFloat(creep.health)
+	}
+	result.Creeps = h.Sum32()
+
+	h.Reset()
+	for _, colony := range w.allColonies {
+		h.AddFloat(colony.resources)
+		h.AddFloat(colony.eliteResources)
+		h.AddFloat(colony.evoPoints)
+	}
+	for _, source := range w.essenceSources {
+		h.AddVec(source.pos)
+		h.AddInt(source.resource)
+	}
+	result.Resources = h.Sum32()
+
+	h.Reset()
+	for _, p := range c.nodeRunner.projectiles {
+		h.AddVec(p.pos)
+		h.AddVec(p.toPos)
+	}
+	result.Projectiles = h.Sum32()
+
+	return result
+}
+
+// recordStateHash adds a new state hash to the results.
+// When there are too many of them, every other hash is discarded
+// and the interval is doubled; this way it's possible to cover
+// an arbitrary long game with a bounded number of hashes.
+func (c *Controller) recordStateHash() {
+	result := &c.world.result
+
+	if len(result.StateHashes) == serverapi.MaxStateHashes {
+		result.StateHashInterval *= 2
+		filtered := result.StateHashes[:0]
+		for _, h := range result.StateHashes {
+			if h.Tick%result.StateHashInterval == 0 {
+				filtered = append(filtered, h)
+			}
+		}
+		result.StateHashes = filtered
+		if c.controllerTick%result.StateHashInterval != 0 {
+			return
+		}
+	}
+
+	result.StateHashes = append(result.StateHashes, c.computeStateHash())
+}
+
+// verifyStateHash compares the current state with the recorded one.
+// Only the first mismatch is remembered: after that, the simulation
+// is already different and all other hashes are likely to mismatch too.
+//
+// The mismatch is not an error by itself, as the floating-point
+// results could differ between the platforms while the game
+// outcome remains the same.
+func (c *Controller) verifyStateHash() {
+	if c.stateDesync != nil {
+		return
+	}
+	i := c.controllerTick/c.world.result.StateHashInterval - 1
+	if i >= len(c.replayStateHashes) {
+		return
+	}
+	expected := &c.replayStateHashes[i]
+	if expected.Tick != c.controllerTick {
+		return
+	}
+	actual := c.computeStateHash()
+	subsystem, x, y := expected.Diff(&actual)
+	if subsystem == "" {
+		return
+	}
+	c.stateDesync = &StateDesync{
+		Tick:      c.controllerTick,
+		Subsystem: subsystem,
+		Expected:  x,
+		Actual:    y,
+	}
+	if c.world.debugLogs {
+		c.world.sessionState.Logf("state desync at tick %d: %s (%d vs %d)", c.controllerTick, subsystem, x, y)
+	}
+}
+
+// GetStateDesync returns the first state hash mismatch found during the replay execution.
+func (c *Controller) GetStateDesync() *StateDesync {
+	return c.stateDesync
+}
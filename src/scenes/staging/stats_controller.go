@@ -0,0 +1,364 @@
+package staging
+
+import (
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/quasilyte/ge"
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/controls"
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/gameui/eui"
+	"github.com/quasilyte/roboden-game/session"
+	"github.com/quasilyte/roboden-game/timeutil"
+)
+
+type statsGraphKind int
+
+const (
+	statsGraphResources statsGraphKind = iota
+	statsGraphDronesByTier
+	statsGraphDronesByKind
+	statsGraphColonies
+	statsGraphCreepsKilled
+	statsGraphDamage
+	numStatsGraphKinds
+)
+
+// maxStatsGraphSeries is a max number of lines per graph.
+// For the per-kind graphs only the most notable kinds are displayed.
+const maxStatsGraphSeries = 6
+
+var (
+	statsGraphBackgroundColor = color.RGBA{R: 0x12, G: 0x14, B: 0x1a, A: 220}
+	statsGraphGridColor       = color.RGBA{R: 0x3a, G: 0x40, B: 0x4c, A: 255}
+
+	statsGraphSeriesColors = [maxStatsGraphSeries]color.RGBA{
+		ge.RGB(0x9dd793),
+		ge.RGB(0x6e8ebd),
+		ge.RGB(0xd46a6a),
+		ge.RGB(0xe0c35a),
+		ge.RGB(0xb77fd1),
+		ge.RGB(0x5ec7c2),
+	}
+)
+
+var creepKindNameKeys = map[gamedata.CreepKind]string{
+	gamedata.CreepPrimitiveWanderer:       "creep.rogue",
+	gamedata.CreepStunner:                 "creep.discharger",
+	gamedata.CreepAssault:                 "creep.vanguard",
+	gamedata.CreepDominator:               "creep.dominator",
+	gamedata.CreepBuilder:                 "creep.builder",
+	gamedata.CreepTurret:                  "creep.turret",
+	gamedata.CreepTurretConstruction:      "creep.turret",
+	gamedata.CreepCrawlerBaseConstruction: "creep.hint.crawler_base",
+	gamedata.CreepBase:                    "creep.hint.base",
+	gamedata.CreepCrawlerBase:             "creep.hint.crawler_base",
+	gamedata.CreepCrawler:                 "creep.crawler",
+	gamedata.CreepHowitzer:                "creep.howitzer",
+	gamedata.CreepServant:                 "creep.servant",
+	gamedata.CreepUberBoss:                "creep.boss",
+	gamedata.CreepWisp:                    "creep.wisp",
+	gamedata.CreepWispLair:                "game.hint.wisp_lair",
+	gamedata.CreepFortress:                "creep.fortress",
+	gamedata.CreepTemplar:                 "creep.stunner",
+	gamedata.CreepCenturion:               "creep.coordinator",
+	gamedata.CreepGrenadier:               "creep.grenadier",
+}
+
+// statsController is a post-match statistics screen.
+//
+// It draws the telemetry timelines collected during the game.
+// In two-player modes, both players are displayed side by side
+// using the same scale, so the graphs can be compared.
+type statsController struct {
+	state  *session.State
+	config *gamedata.LevelConfig
+
+	scene          *ge.Scene
+	backController ge.SceneController
+
+	results *battleResults
+
+	graph statsGraphKind
+}
+
+type statsGraphSeries struct {
+	name  string
+	value func(s *telemetrySample) float64
+}
+
+func newStatsController(state *session.State, config *gamedata.LevelConfig, backController ge.SceneController, results *battleResults, graph statsGraphKind) *statsController {
+	return &statsController{
+		state:          state,
+		config:         config,
+		backController: backController,
+		results:        results,
+		graph:          graph,
+	}
+}
+
+func (c *statsController) Init(scene *ge.Scene) {
+	c.scene = scene
+	eui.AddBackground(c.state.BackgroundImage, scene)
+	c.initUI()
+}
+
+func (c *statsController) Update(delta float64) {
+	c.state.MenuInput.Update()
+	if c.state.MenuInput.ActionIsJustPressed(controls.ActionMenuBack) {
+		c.back()
+		return
+	}
+}
+
+func (c *statsController) initUI() {
+	uiResources := c.state.Resources.UI
+	smallFont := c.state.Resources.Font1
+
+	root := eui.NewAnchorContainer()
+	rowContainer := eui.NewRowLayoutContainer(10, nil)
+	root.AddChild(rowContainer)
+
+	d := c.scene.Dict()
+
+	rowContainer.AddChild(eui.NewCenteredLabel(d.Get("menu.results.stats"), c.state.Resources.Font3))
+
+	graphIndex := int(c.graph)
+	graphSelect := eui.NewSelectButton(eui.SelectButtonConfig{
+		Resources: uiResources,
+		Input:     c.state.MenuInput,
+		Value:     &graphIndex,
+		Label:     d.Get("menu.stats.graph"),
+		ValueNames: []string{
+			d.Get("menu.stats.resources"),
+			d.Get("menu.stats.drones_by_tier"),
+			d.Get("menu.stats.drones_by_kind"),
+			d.Get("menu.stats.colonies"),
+			d.Get("menu.stats.creeps_killed"),
+			d.Get("menu.stats.damage"),
+		},
+		PlaySound: true,
+		OnPressed: func() {
+			c.scene.Context().ChangeScene(newStatsController(c.state, c.config, c.backController, c.results, statsGraphKind(graphIndex)))
+		},
+	})
+	c.scene.AddObject(graphSelect)
+	rowContainer.AddChild(graphSelect.Widget)
+
+	timelines := c.results.Telemetry
+	series := c.makeSeries(timelines)
+
+	if len(timelines) == 0 || len(series) == 0 {
+		rowContainer.AddChild(eui.NewCenteredLabel(d.Get("menu.stats.no_data"), smallFont))
+	} else {
+		// The same scale is used for all players.
+		maxValue := 0.0
+		for _, samples := range timelines {
+			for i := range samples {
+				for _, s := range series {
+					maxValue = math.Max(maxValue, s.value(&samples[i]))
+				}
+			}
+		}
+		maxValue = statsGraphCeil(maxValue)
+
+		graphWidth := 480
+		graphHeight := 200
+		if len(timelines) > 1 {
+			graphWidth = 360
+			graphHeight = 160
+		}
+
+		playersGrid := eui.NewGridContainer(len(timelines), widget.GridLayoutOpts.Spacing(16, 0))
+		for playerID, samples := range timelines {
+			panel := eui.NewTextPanel(uiResources, 0, 0)
+			panelRows := eui.NewRowLayoutContainer(4, nil)
+			panel.AddChild(panelRows)
+
+			panelRows.AddChild(eui.NewCenteredLabel(c.playerLabel(playerID), smallFont))
+			panelRows.AddChild(eui.NewLabel(strconv.Itoa(int(maxValue)), smallFont))
+			img := c.drawGraph(graphWidth, graphHeight, samples, series, maxValue)
+			panelRows.AddChild(widget.NewGraphic(widget.GraphicOpts.Image(img)))
+			panelRows.AddChild(eui.NewLabel(timeutil.FormatDuration(d, c.results.TimePlayed.Round(time.Second)), smallFont,
+				widget.TextOpts.Position(widget.TextPositionEnd, widget.TextPositionCenter)))
+
+			legendGrid := eui.NewGridContainer(3, widget.GridLayoutOpts.Spacing(12, 2))
+			for i, s := range series {
+				legendGrid.AddChild(eui.NewColoredLabel(s.name, smallFont, statsGraphSeriesColors[i]))
+			}
+			panelRows.AddChild(legendGrid)
+
+			playersGrid.AddChild(panel)
+		}
+		rowContainer.AddChild(playersGrid)
+	}
+
+	rowContainer.AddChild(eui.NewButton(uiResources, c.scene, d.Get("menu.back"), func() {
+		c.back()
+	}))
+
+	uiObject := eui.NewSceneObject(root)
+	c.scene.AddGraphics(uiObject)
+	c.scene.AddObject(uiObject)
+}
+
+func (c *statsController) playerLabel(playerID int) string {
+	d := c.scene.Dict()
+	if playerID < len(c.config.Players) && c.config.Players[playerID] == gamedata.PlayerComputer {
+		return d.Get("menu.stats.computer")
+	}
+	return d.Get("menu.stats.player") + " " + strconv.Itoa(playerID+1)
+}
+
+func (c *statsController) makeSeries(timelines [][]telemetrySample) []statsGraphSeries {
+	d := c.scene.Dict()
+
+	switch c.graph {
+	case statsGraphResources:
+		return []statsGraphSeries{
+			{name: d.Get("menu.stats.resources_stored"), value: func(s *telemetrySample) float64 { return s.Resources }},
+			{name: d.Get("menu.stats.resources_gathered"), value: func(s *telemetrySample) float64 { return s.ResourcesGathered }},
+		}
+
+	case statsGraphDronesByTier:
+		series := make([]statsGraphSeries, 3)
+		for i := range series {
+			tier := i
+			series[i] = statsGraphSeries{
+				name:  d.Get("menu.stats.tier") + " " + strconv.Itoa(tier+1),
+				value: func(s *telemetrySample) float64 { return float64(s.DronesByTier[tier]) },
+			}
+		}
+		return series
+
+	case statsGraphColonies:
+		return []statsGraphSeries{
+			{name: d.Get("menu.stats.colonies"), value: func(s *telemetrySample) float64 { return float64(s.Colonies) }},
+		}
+
+	case statsGraphDamage:
+		return []statsGraphSeries{
+			{name: d.Get("menu.stats.damage_dealt"), value: func(s *telemetrySample) float64 { return s.DamageDealt }},
+			{name: d.Get("menu.stats.damage_taken"), value: func(s *telemetrySample) float64 { return s.DamageTaken }},
+		}
+
+	case statsGraphDronesByKind:
+		kinds := statsTopKinds(timelines, int(gamedata.AgentKindNum), func(s *telemetrySample, i int) int {
+			return s.DronesByKind[i]
+		})
+		series := make([]statsGraphSeries, len(kinds))
+		for i, k := range kinds {
+			kind := k
+			series[i] = statsGraphSeries{
+				name:  d.Get("drone", strings.ToLower(gamedata.ColonyAgentKind(kind).String())),
+				value: func(s *telemetrySample) float64 { return float64(s.DronesByKind[kind]) },
+			}
+		}
+		return series
+
+	case statsGraphCreepsKilled:
+		kinds := statsTopKinds(timelines, numCreepKinds, func(s *telemetrySample, i int) int {
+			return s.CreepsKilled[i]
+		})
+		series := make([]statsGraphSeries, len(kinds))
+		for i, k := range kinds {
+			kind := k
+			series[i] = statsGraphSeries{
+				name:  d.Get(creepKindNameKeys[gamedata.CreepKind(kind)]),
+				value: func(s *telemetrySample) float64 { return float64(s.CreepsKilled[kind]) },
+			}
+		}
+		return series
+
+	default:
+		return nil
+	}
+}
+
+func (c *statsController) drawGraph(width, height int, samples []telemetrySample, series []statsGraphSeries, maxValue float64) *ebiten.Image {
+	img := ebiten.NewImage(width, height)
+	img.Fill(statsGraphBackgroundColor)
+
+	const numGridLines = 4
+	for i := 1; i < numGridLines; i++ {
+		y := float32(height) * float32(i) / numGridLines
+		vector.StrokeLine(img, 0, y, float32(width), y, 1, statsGraphGridColor, false)
+	}
+
+	if len(samples) == 0 || maxValue == 0 {
+		return img
+	}
+
+	numTicks := gmath.ClampMin(c.results.Ticks, samples[len(samples)-1].Tick)
+	numTicks = gmath.ClampMin(numTicks, 1)
+	toPoint := func(tick int, value float64) (float32, float32) {
+		x := float32(width-1) * float32(tick) / float32(numTicks)
+		y := float32(height-1) - float32(height-2)*float32(value/maxValue)
+		return x, y
+	}
+
+	for i, s := range series {
+		clr := statsGraphSeriesColors[i]
+		prevX, prevY := toPoint(0, 0)
+		for j := range samples {
+			x, y := toPoint(samples[j].Tick, s.value(&samples[j]))
+			vector.StrokeLine(img, prevX, prevY, x, y, 1.5, clr, true)
+			prevX, prevY = x, y
+		}
+	}
+
+	return img
+}
+
+func (c *statsController) back() {
+	c.scene.Context().ChangeScene(c.backController)
+}
+
+// statsTopKinds returns up to maxStatsGraphSeries kind indexes with the highest values.
+// The kinds that were never above zero are not included.
+func statsTopKinds(timelines [][]telemetrySample, numKinds int, get func(s *telemetrySample, i int) int) []int {
+	peaks := make([]int, numKinds)
+	for _, samples := range timelines {
+		for j := range samples {
+			for i := range peaks {
+				peaks[i] = gmath.ClampMin(peaks[i], get(&samples[j], i))
+			}
+		}
+	}
+	var kinds []int
+	for i, v := range peaks {
+		if v > 0 {
+			kinds = append(kinds, i)
+		}
+	}
+	sort.SliceStable(kinds, func(i, j int) bool {
+		return peaks[kinds[i]] > peaks[kinds[j]]
+	})
+	if len(kinds) > maxStatsGraphSeries {
+		kinds = kinds[:maxStatsGraphSeries]
+	}
+	return kinds
+}
+
+// statsGraphCeil rounds the graph max value up to a "nice" number,
+// so the grid lines are easier to read.
+func statsGraphCeil(v float64) float64 {
+	if v <= 4 {
+		return 4
+	}
+	step := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 4, 5, 10} {
+		if v <= step*m {
+			return step * m
+		}
+	}
+	return step * 10
+}
@@ -0,0 +1,835 @@
+package staging
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/quasilyte/ge"
+	"github.com/quasilyte/roboden-game/assets"
+	"github.com/quasilyte/roboden-game/gamedata"
+)
+
+// worldSnapshotDecoder re-creates the captured world state.
+//
+// The level generator output is used as a base: the static level
+// parts are kept as is, while all dynamic objects are replaced.
+type worldSnapshotDecoder struct {
+	c      *Controller
+	world  *worldState
+	tables *snapshotTables
+
+	snapshot *worldSnapshot
+
+	colonies      []*colonyCoreNode
+	agents        []*colonyAgentNode
+	creeps        []*creepNode
+	essences      []*essenceSourceNode
+	constructions []*constructionNode
+	tethers       []*tetherNode
+	stubs         []any
+
+	// playerColonies are the disposed colonies used by the agent stubs.
+	playerColonies []*colonyCoreNode
+	disposedSprite *ge.Sprite
+}
+
+func (c *Controller) restoreWorld(s *worldSnapshot) (err error) {
+	d := &worldSnapshotDecoder{
+		c:        c,
+		world:    c.world,
+		tables:   worldSnapshotTables,
+		snapshot: s,
+	}
+
+	// The snapshot indices are not trusted: a corrupted save
+	// would cause an out of range panic somewhere.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("restore world: %v", r)
+		}
+	}()
+
+	if len(s.Players) != len(c.world.players) {
+		return errors.New("restore world: players number mismatch")
+	}
+	if len(s.LavaPuddles) != len(c.world.lavaPuddles) || len(s.LavaGeysers) != len(c.world.lavaGeysers) {
+		return errors.New("restore world: level mismatch")
+	}
+	if len(s.NeutralBuildings) != len(c.world.neutralBuildings) {
+		return errors.New("restore world: level mismatch")
+	}
+
+	// Some of the objects play sounds during the Init.
+	c.setAudioMuted(true)
+	defer c.setAudioMuted(false)
+
+	d.decode()
+	return nil
+}
+
+func (d *worldSnapshotDecoder) decode() {
+	w := d.world
+	s := d.snapshot
+
+	d.disposeGenerated()
+
+	d.disposedSprite = ge.NewSprite(d.c.scene.Context())
+	d.disposedSprite.Dispose()
+	for i := range s.Stubs {
+		d.stubs = append(d.stubs, d.newStub(&s.Stubs[i]))
+	}
+
+	for i := range s.Colonies {
+		d.colonies = append(d.colonies, d.newColony(&s.Colonies[i]))
+	}
+	for i := range s.Essences {
+		d.essences = append(d.essences, d.newEssence(&s.Essences[i]))
+	}
+	for i := range s.Constructions {
+		d.constructions = append(d.constructions, d.newConstruction(&s.Constructions[i]))
+	}
+	for i := range s.Creeps {
+		d.creeps = append(d.creeps, d.newCreep(&s.Creeps[i]))
+	}
+	for i := range s.Agents {
+		d.agents = append(d.agents, d.newAgent(&s.Agents[i]))
+	}
+	for i := range s.Tethers {
+		tether := &s.Tethers[i]
+		n := newTetherNode(w, d.resolve(tether.Source).(*colonyAgentNode), d.targetable(tether.Target))
+		w.nodeRunner.AddObject(n)
+		n.lifespan = tether.Lifespan
+		n.shaderTime = tether.ShaderTime
+		d.tethers = append(d.tethers, n)
+	}
+
+	// All objects are created, now the references can be resolved.
+	for i, creep := range d.creeps {
+		d.restoreCreepRefs(creep, &s.Creeps[i])
+	}
+	for i, a := range d.agents {
+		d.restoreAgentRefs(a, &s.Agents[i])
+	}
+	for i, colony := range d.colonies {
+		cs := &s.Colonies[i]
+		colony.failedResource, _ = d.resolve(cs.FailedResource).(*essenceSourceNode)
+		if cs.ActivatedTeleport != -1 {
+			colony.activatedTeleport = w.teleporters[cs.ActivatedTeleport]
+		}
+		colony.numTurretsBuilt = cs.NumTurretsBuilt
+		colony.agents.Update()
+	}
+	for i, b := range w.neutralBuildings {
+		bs := &s.NeutralBuildings[i]
+		if b.agent != nil {
+			b.AssignAgent(nil)
+		}
+		b.pos = bs.Pos
+		if bs.Agent != -1 {
+			b.AssignAgent(d.agents[bs.Agent])
+		}
+	}
+	d.restoreSulfurMining()
+
+	for i := range s.Projectiles {
+		d.newProjectile(&s.Projectiles[i])
+	}
+	for _, spawner := range s.CreepSpawners {
+		n := newCreepSpawnerNode(w, spawner.Delay, spawner.Pos, spawner.Dest, d.tables.creeps[spawner.Stats])
+		n.fragScore = spawner.FragScore
+		n.super = spawner.Super
+		w.nodeRunner.AddObject(n)
+	}
+	for _, spawner := range s.ServantSpawners {
+		target, _ := d.resolve(spawner.Target).(*colonyCoreNode)
+		n := newServantSpawnerNode(w, spawner.Pos, spawner.Dir, target)
+		n.super = spawner.Super
+		w.nodeRunner.AddObject(n)
+		n.rotation = spawner.Rotation
+		n.rotateClockwise = spawner.RotateClockwise
+	}
+
+	// The creation order doesn't match the original one,
+	// so the lists that were appended to are overwritten.
+	w.turrets = w.turrets[:0]
+	for _, i := range s.Turrets {
+		w.turrets = append(w.turrets, d.agents[i])
+	}
+	w.artifacts = w.artifacts[:0]
+	for _, i := range s.Artifacts {
+		w.artifacts = append(w.artifacts, d.essences[i])
+	}
+	w.centurions = w.centurions[:0]
+	for _, i := range s.Centurions {
+		w.centurions = append(w.centurions, d.creeps[i])
+	}
+	w.creepCoordinator.crawlers = w.creepCoordinator.crawlers[:0]
+	for _, i := range s.Crawlers {
+		w.creepCoordinator.crawlers = append(w.creepCoordinator.crawlers, d.creeps[i])
+	}
+	w.boss = d.creepOrNil(s.Boss)
+	w.wispLair = d.creepOrNil(s.WispLair)
+	w.fortress = d.creepOrNil(s.Fortress)
+	w.centurionRallyPoint = s.CenturionRallyPoint
+	switch s.CenturionRallyPointPtr {
+	case rallyPointNone:
+		w.centurionRallyPointPtr = nil
+	case rallyPointBoss:
+		w.centurionRallyPointPtr = &w.boss.pos
+	case rallyPointCustom:
+		w.centurionRallyPointPtr = &w.centurionRallyPoint
+	}
+
+	for i, lava := range w.lavaPuddles {
+		ls := &s.LavaPuddles[i]
+		lava.fireDelay = ls.FireDelay
+		lava.attacker.pos = ls.AttackerPos
+		lava.numResourceSpawns = ls.NumResourceSpawns
+		lava.maxResourceSpawns = ls.MaxResourceSpawns
+	}
+	for i, geyser := range w.lavaGeysers {
+		geyser.fireDelay = s.LavaGeysers[i]
+	}
+
+	d.restoreManagers()
+
+	for i, p := range w.players {
+		pstate := p.GetState()
+		pstate.colonySeq = s.Players[i].ColonySeq
+		pstate.resourceStash = s.Players[i].ResourceStash
+		pstate.hasRoombas = s.Players[i].HasRoombas
+		if human, ok := p.(*humanPlayer); ok && s.Players[i].Choices != nil {
+			d.restoreChoices(human.choiceGen, s.Players[i].Choices)
+		}
+	}
+
+	d.c.nodeRunner.timePlayed = s.TimePlayed
+	d.c.nodeRunner.ticks = s.Ticks
+	d.c.nodeRunner.fastforwardTicks = s.FastforwardTicks
+	d.c.controllerTick = s.ControllerTick
+	d.c.victoryCheckDelay = s.VictoryCheckDelay
+
+	// The objects creation above marks and unmarks the grid cells
+	// in a different order, so the grid state is restored as the last step.
+	w.gridCounters = s.GridCounters
+	if w.gridCounters == nil {
+		w.gridCounters = make(map[int]uint8)
+	}
+	if err := w.pathgrid.UnmarshalBinary(s.Pathgrid); err != nil {
+		panic(err)
+	}
+
+	w.rand.SetSeed(s.Seed)
+}
+
+// disposeGenerated removes the dynamic objects created by the level generator.
+//
+// Nothing is destroyed here (no events are emitted),
+// the objects are disposed and forgotten.
+func (d *worldSnapshotDecoder) disposeGenerated() {
+	w := d.world
+
+	disposeAgent := func(a *colonyAgentNode) {
+		// These nodes are created by the initExtra.
+		switch target := a.target.(type) {
+		case *siegeTurretNode:
+			target.Dispose()
+		case *sentinelpointTurretNode:
+			target.Dispose()
+		case *ge.Sprite:
+			target.Dispose()
+		}
+		a.dispose()
+	}
+	for _, colony := range w.allColonies {
+		colony.agents.Each(disposeAgent)
+		for _, turret := range colony.turrets {
+			disposeAgent(turret)
+		}
+		for _, roomba := range colony.roombas {
+			disposeAgent(roomba)
+		}
+		colony.Dispose()
+	}
+	for _, merc := range w.mercs {
+		disposeAgent(merc)
+	}
+	for _, essence := range w.essenceSources {
+		essence.Dispose()
+	}
+	for _, creep := range w.creeps {
+		creep.dispose()
+	}
+	for _, construction := range w.constructions {
+		construction.Dispose()
+	}
+
+	w.allColonies = w.allColonies[:0]
+	w.essenceSources = w.essenceSources[:0]
+	w.creeps = w.creeps[:0]
+	w.constructions = w.constructions[:0]
+	w.mercs = w.mercs[:0]
+	for _, p := range w.players {
+		pstate := p.GetState()
+		pstate.colonies = pstate.colonies[:0]
+		pstate.colonySeq = 0
+	}
+}
+
+func (d *worldSnapshotDecoder) newStub(s *snapshotStub) any {
+	w := d.world
+	switch s.Kind {
+	case refColony:
+		return d.newDisposedColony(s.Player, s)
+	case refAgent:
+		return &colonyAgentNode{
+			colonyCore: d.playerColony(s.Player),
+			stats:      d.tables.agents[s.Stats],
+			pos:        s.Pos,
+			disposed:   true,
+		}
+	case refCreep:
+		return &creepNode{
+			world:    w,
+			stats:    d.tables.creeps[s.Stats],
+			pos:      s.Pos,
+			disposed: true,
+		}
+	case refEssence:
+		return &essenceSourceNode{
+			world:  w,
+			stats:  d.tables.essences[s.Stats],
+			pos:    s.Pos,
+			sprite: d.disposedSprite,
+		}
+	case refConstruction:
+		return &constructionNode{
+			world:            w,
+			stats:            d.tables.constructions[s.Stats],
+			player:           w.players[s.Player],
+			pos:              s.Pos,
+			constructPosBase: s.Pos,
+			sprite:           d.disposedSprite,
+		}
+	}
+	panic(fmt.Sprintf("unexpected stub kind %d", s.Kind))
+}
+
+func (d *worldSnapshotDecoder) newDisposedColony(playerIndex int, s *snapshotStub) *colonyCoreNode {
+	colony := &colonyCoreNode{
+		world:  d.world,
+		player: d.world.players[playerIndex],
+		stats:  d.world.coreDesign,
+		sprite: d.disposedSprite,
+	}
+	if s != nil {
+		colony.pos = s.Pos
+	}
+	return colony
+}
+
+// playerColony returns a disposed colony that is used as an owner of the agent stubs.
+func (d *worldSnapshotDecoder) playerColony(playerIndex int) *colonyCoreNode {
+	if d.playerColonies == nil {
+		d.playerColonies = make([]*colonyCoreNode, len(d.world.players))
+	}
+	if d.playerColonies[playerIndex] == nil {
+		d.playerColonies[playerIndex] = d.newDisposedColony(playerIndex, nil)
+	}
+	return d.playerColonies[playerIndex]
+}
+
+func (d *worldSnapshotDecoder) newColony(s *colonySnapshot) *colonyCoreNode {
+	w := d.world
+	colony := w.NewColonyCoreNode(colonyConfig{
+		World:  w,
+		Radius: s.RealRadius,
+		Pos:    s.Pos,
+		Player: w.players[s.Player],
+	})
+	colony.id = s.ID
+	w.nodeRunner.AddObject(colony)
+
+	colony.mode = s.Mode
+	colony.health = s.Health
+	colony.maxHealth = s.MaxHealth
+	colony.teleportDelay = s.TeleportDelay
+	colony.maxSpeed = s.MaxSpeed
+	colony.maxJumpDist = s.MaxJumpDist
+	colony.tether = s.Tether
+	colony.heavyDamageWarningCooldown = s.HeavyDamageWarningCooldown
+	colony.rallyPoint = s.RallyPoint
+	colony.waypoint = s.Waypoint
+	colony.relocationPoint = s.RelocationPoint
+	colony.plannedRelocationPoint = s.PlannedRelocationPoint
+	colony.path = decodeGridPath(s.Path)
+	colony.resourceShortage = s.ResourceShortage
+	colony.resources = s.Resources
+	colony.eliteResources = s.EliteResources
+	colony.evoPoints = s.EvoPoints
+	colony.acceleration = s.Acceleration
+	colony.realRadius = s.RealRadius
+	colony.realRadiusSqr = s.RealRadius * s.RealRadius
+	colony.stashTransferDelay = s.StashTransferDelay
+	colony.repairSentinelDelay = s.RepairSentinelDelay
+	colony.freeWorkerDelay = s.FreeWorkerDelay
+	colony.upkeepDelay = s.UpkeepDelay
+	colony.cloningDelay = s.CloningDelay
+	colony.resourceDelay = s.ResourceDelay
+	colony.captureDelay = s.CaptureDelay
+	colony.artifactDelay = s.ArtifactDelay
+	colony.attackDelay = s.AttackDelay
+	colony.actionDelay = s.ActionDelay
+	colony.failedResourceTick = s.FailedResourceTick
+	for i, weight := range s.Priorities {
+		colony.priorities.Elems[i].Weight = weight
+	}
+	for i, weight := range s.FactionWeights {
+		colony.factionWeights.Elems[i].Weight = weight
+	}
+
+	// switchSprite resets the hatch timer.
+	colony.switchSprite(s.Flying)
+	colony.openHatchTime = s.OpenHatchTime
+	colony.drawOrder = s.DrawOrder
+	colony.shadowComponent.SetVisibility(colony.IsFlying())
+	colony.shadowComponent.UpdatePos(colony.pos)
+	colony.shadowComponent.UpdateHeight(colony.pos, s.Height, colony.stats.FlightHeight)
+	if colony.mode == colonyModeTeleporting {
+		colony.otherShader, colony.sprite.Shader = colony.sprite.Shader, colony.otherShader
+		if !colony.sprite.Shader.IsNil() {
+			colony.sprite.Shader.SetFloatValue("Time", 20-(colony.teleportDelay*10))
+		}
+		colony.hatch.Visible = false
+		for _, rect := range colony.resourceRects {
+			rect.Visible = false
+		}
+	}
+	colony.updateEvoDiode()
+	colony.updateResourceRects()
+	colony.updateHealthShader()
+
+	return colony
+}
+
+func (d *worldSnapshotDecoder) newEssence(s *essenceSnapshot) *essenceSourceNode {
+	w := d.world
+	essence := w.NewEssenceSourceNode(d.tables.essences[s.Stats], s.Pos)
+	w.nodeRunner.AddObject(essence)
+	essence.capacity = s.Capacity
+	essence.resource = s.Resource
+	essence.percengage = s.Percengage
+	essence.recoverDelay = s.RecoverDelay
+	essence.recoverDelayTimer = s.RecoverDelayTimer
+	essence.beingHarvested = s.BeingHarvested
+	essence.rotation = s.Rotation
+	essence.sprite.FlipHorizontal = s.FlipHorizontal
+	essence.updateShader()
+	if s.LavaPuddle != -1 {
+		w.lavaPuddles[s.LavaPuddle].trackRock(essence)
+	}
+	return essence
+}
+
+func (d *worldSnapshotDecoder) newConstruction(s *constructionSnapshot) *constructionNode {
+	w := d.world
+	construction := w.NewConstructionNode(w.players[s.Player], s.Pos, d.tables.constructions[s.Stats])
+	w.nodeRunner.AddObject(construction)
+	construction.constructPosBase = s.ConstructPosBase
+	construction.progress = s.Progress
+	construction.attention = s.Attention
+	if !construction.sprite.Shader.IsNil() {
+		construction.sprite.Shader.SetFloatValue("Time", construction.progress)
+	}
+	return construction
+}
+
+func (d *worldSnapshotDecoder) newCreep(s *creepSnapshot) *creepNode {
+	w := d.world
+	creep := w.NewCreepNode(s.Pos, d.tables.creeps[s.Stats])
+	creep.spawnPos = s.SpawnPos
+	creep.super = s.Super
+	creep.spawnedFromBase = s.SpawnedFromBase
+	creep.fragScore = s.FragScore
+	w.nodeRunner.AddObject(creep)
+
+	creep.waypoint = s.Waypoint
+	creep.wasAttacking = s.WasAttacking
+	creep.wasRetreating = s.WasRetreating
+	creep.insideForest = s.InsideForest
+	creep.centurionReady = s.CenturionReady
+	creep.path = decodeGridPath(s.Path)
+	creep.specialDelay = s.SpecialDelay
+	creep.specialModifier = s.SpecialModifier
+	creep.aggro = s.Aggro
+	creep.disarm = s.Disarm
+	creep.slow = s.Slow
+	creep.health = s.Health
+	creep.maxHealth = s.MaxHealth
+	creep.marked = s.Marked
+	creep.shield = s.Shield
+	creep.attackDelay = s.AttackDelay
+	creep.bossStage = s.BossStage
+
+	creep.sprite.Visible = s.SpriteVisible
+	creep.sprite.FrameOffset.X = s.FrameOffsetX
+	if creep.altSprite != nil {
+		creep.altSprite.Visible = s.AltSpriteVisible
+	}
+	if s.Cloaking {
+		creep.cloaking = true
+		creep.sprite.SetAlpha(0.2)
+	}
+	if creep.stats.ShadowImage != assets.ImageNone {
+		creep.shadowComponent.SetVisibility(!s.ShadowHidden)
+		creep.shadowComponent.UpdatePos(creep.pos)
+		creep.shadowComponent.UpdateHeight(creep.pos, s.Height, agentFlightHeight)
+	}
+
+	switch creep.stats.Kind {
+	case gamedata.CreepHowitzer:
+		trunk := creep.specialTarget.(*howitzerTrunkNode)
+		switch creep.specialModifier {
+		case howitzerReady:
+			trunk.SetVisibility(true)
+			trunk.SetRotation(math.Pi * 1.5)
+		case howitzerPreparing:
+			creep.setAnimSprite(creep.altSprite, -1)
+			creep.anim.Rewind()
+		case howitzerFoldTurret:
+			creep.setAnimSprite(creep.altSprite, -1)
+			creep.anim.Rewind()
+			creep.anim.Mode = ge.AnimationBackward
+		}
+	case gamedata.CreepTurretConstruction, gamedata.CreepCrawlerBaseConstruction:
+		if !creep.sprite.Shader.IsNil() {
+			creep.sprite.Shader.SetFloatValue("Time", creep.specialModifier)
+		}
+	}
+	creep.updateHealthShader()
+
+	return creep
+}
+
+func (d *worldSnapshotDecoder) restoreCreepRefs(creep *creepNode, s *creepSnapshot) {
+	creep.aggroTarget = d.targetable(s.AggroTarget)
+	if s.SpecialTarget.Kind != refNone {
+		creep.specialTarget = d.resolve(s.SpecialTarget)
+	}
+	if s.Spawner != -1 {
+		d.creeps[s.Spawner].trackSpawned(creep)
+	}
+	if creep.stats.Kind == gamedata.CreepBuilder && creep.specialTarget != nil {
+		lasers := newBuilderLaserNode(d.world, creep.pos)
+		creep.EventBuildingStop.Connect(lasers, lasers.OnBuildingStop)
+		d.world.nodeRunner.AddObject(lasers)
+	}
+}
+
+func (d *worldSnapshotDecoder) newAgent(s *agentSnapshot) *colonyAgentNode {
+	w := d.world
+	colony := d.resolve(s.Colony).(*colonyCoreNode)
+	a := newColonyAgentNode(colony, d.tables.agents[s.Stats], s.Pos)
+	a.faction = s.Faction
+	a.rank = s.Rank
+	a.cloneGen = s.CloneGen
+	switch s.Role {
+	case agentRoleDrone:
+		colony.AcceptAgent(a)
+	case agentRoleTurret:
+		colony.AcceptTurret(a)
+	case agentRoleRoomba:
+		colony.AcceptRoomba(a)
+	}
+	w.nodeRunner.AddObject(a)
+	if s.Role == agentRoleMerc {
+		d.resolve(s.Factory).(*colonyAgentNode).attachMerc(a)
+	}
+
+	a.traits = s.Traits
+	a.path = decodeGridPath(s.Path)
+	a.mode = s.Mode
+	a.waypoint = s.Waypoint
+	a.dir = s.Dir
+	a.payload = s.Payload
+	a.extraLevel = s.ExtraLevel
+	a.commanderID = s.CommanderID
+	a.cargoValue = s.CargoValue
+	a.cargoEliteValue = s.CargoEliteValue
+	a.reloadRate = s.ReloadRate
+	a.healthRegen = s.HealthRegen
+	a.attackDelay = s.AttackDelay
+	a.supportDelay = s.SupportDelay
+	a.specialDelay = s.SpecialDelay
+	a.maxHealth = s.MaxHealth
+	a.health = s.Health
+	a.maxEnergy = s.MaxEnergy
+	a.energy = s.Energy
+	a.energyRegenRate = s.EnergyRegenRate
+	a.slow = s.Slow
+	a.lifetime = s.Lifetime
+	a.energyTarget = s.EnergyTarget
+	a.insideForest = s.InsideForest
+	a.tether = s.Tether
+	a.resting = s.Resting
+	a.speed = s.Speed
+	a.dist = s.Dist
+	a.waypointsLeft = s.WaypointsLeft
+
+	a.SetHeight(s.Height)
+	a.shadowComponent.SetVisibility(!s.ShadowHidden)
+	if s.SpriteHidden {
+		a.setVisibility(false)
+	}
+	if s.Cloaking != 0 {
+		a.cloaking = s.Cloaking
+		a.sprite.SetAlpha(0.2)
+	}
+	a.updateHealthShader()
+
+	return a
+}
+
+func (d *worldSnapshotDecoder) restoreAgentRefs(a *colonyAgentNode, s *agentSnapshot) {
+	switch target := a.target.(type) {
+	case *siegeTurretNode:
+		target.ammo = s.SiegeAmmo
+		target.target, _ = d.resolve(s.SiegeTarget).(*creepNode)
+	case *sentinelpointTurretNode:
+		target.worker, _ = d.resolve(s.SentinelWorker).(*colonyAgentNode)
+	case *ge.Sprite:
+		target.Visible = s.HatchVisible
+	default:
+		a.target = d.resolve(s.Target)
+	}
+
+	if s.Beam != nil {
+		to := ge.Pos{Offset: s.Beam.Offset}
+		if s.Beam.To.Kind != refNone {
+			to.Base = beamTargetPos(d.resolve(s.Beam.To))
+		}
+		a.cloningBeam = newCloningBeamNode(d.world, s.Beam.Kind, &a.pos, to)
+		d.world.nodeRunner.AddObject(a.cloningBeam)
+	}
+}
+
+// restoreSulfurMining re-creates the mining nodes using the miners modes.
+func (d *worldSnapshotDecoder) restoreSulfurMining() {
+	var nodes []*sulfurMiningNode
+	for _, a := range d.agents {
+		if a.mode != agentModeMineSulfurEssence {
+			continue
+		}
+		source, ok := a.target.(*essenceSourceNode)
+		if !ok || source.IsDisposed() {
+			continue
+		}
+		var n *sulfurMiningNode
+		for _, other := range nodes {
+			if other.source == source {
+				n = other
+				break
+			}
+		}
+		if n == nil {
+			n = newSulfurMiningNode(source)
+			nodes = append(nodes, n)
+		}
+		n.miners = append(n.miners, a)
+	}
+	for _, n := range nodes {
+		d.world.nodeRunner.AddObject(n)
+	}
+}
+
+func (d *worldSnapshotDecoder) newProjectile(s *projectileSnapshot) {
+	if s.Attacker.Kind == refNone {
+		return
+	}
+	w := d.world
+	p := w.newProjectileNode(projectileConfig{
+		World:     w,
+		Weapon:    d.tables.weapons[s.Weapon],
+		Attacker:  d.targetable(s.Attacker),
+		ToPos:     s.ToPos,
+		Target:    d.targetable(s.Target),
+		FireDelay: s.FireDelay,
+		Seq:       s.Seq,
+		Guided:    s.Guided,
+	})
+	w.nodeRunner.AddProjectile(p)
+	p.pos = s.Pos
+	p.toPos = s.ToPos
+	p.trailCounter = s.TrailCounter
+	p.rotation = s.Rotation
+	p.arcProgressionScaling = s.ArcProgressionScaling
+	p.arcProgression = s.ArcProgression
+	p.arcStart = s.ArcStart
+	p.arcFrom = s.ArcFrom
+	p.arcTo = s.ArcTo
+	p.setSpriteVisibility(p.fireDelay <= 0)
+	if s.RockSpawner != -1 {
+		w.lavaPuddles[s.RockSpawner].spawnRockOnDetonation(p, s.RockSpawnPos)
+	}
+}
+
+func (d *worldSnapshotDecoder) restoreManagers() {
+	w := d.world
+	s := d.snapshot
+
+	// These result fields are initialized by the controller.
+	result := s.Result
+	result.Replay = w.result.Replay
+	result.StateHashInterval = w.result.StateHashInterval
+	w.result = result
+
+	w.matchEvents.events = s.MatchEvents.Events
+	w.matchEvents.nextReportTick = s.MatchEvents.NextReportTick
+	w.matchEvents.resourcesGathered = s.MatchEvents.ResourcesGathered
+	w.matchEvents.bossStage = s.MatchEvents.BossStage
+
+	w.telemetry.interval = s.Telemetry.Interval
+	w.telemetry.nextSampleTick = s.Telemetry.NextSampleTick
+	for i, p := range s.Telemetry.Players {
+		w.telemetry.players[i].current = p.Current
+		w.telemetry.players[i].samples = p.Samples
+	}
+
+	w.creepCoordinator.scoutingDelay = s.Coordinator.ScoutingDelay
+	w.creepCoordinator.attackDelay = s.Coordinator.AttackDelay
+	w.creepCoordinator.attackRuinsDelay = s.Coordinator.AttackRuinsDelay
+	w.creepCoordinator.scatterDelay = s.Coordinator.ScatterDelay
+	w.creepCoordinator.relocateDelay = s.Coordinator.RelocateDelay
+
+	if m := d.c.classicManager; m != nil && s.Classic != nil {
+		m.tier3spawnDelay = s.Classic.Tier3spawnDelay
+		m.tier3spawnRate = s.Classic.Tier3spawnRate
+		m.grenadiersDelay = s.Classic.GrenadiersDelay
+		m.grenadierWave = s.Classic.GrenadierWave
+		m.crawlersDelay = s.Classic.CrawlersDelay
+		m.coordinatorsDelay = s.Classic.CoordinatorsDelay
+	}
+
+	if m := d.c.arenaManager; m != nil && s.Arena != nil {
+		m.level = s.Arena.Level
+		m.waveBudget = s.Arena.WaveBudget
+		m.lastLevel = s.Arena.LastLevel
+		m.infoUpdateDelay = s.Arena.InfoUpdateDelay
+		m.levelStartDelay = s.Arena.LevelStartDelay
+		m.grenadiersDelay = s.Arena.GrenadiersDelay
+		m.grenadierWave = s.Arena.GrenadierWave
+		m.attackSides = s.Arena.AttackSides
+		m.waveInfo = arenaWaveInfo{
+			isLast:          s.Arena.IsLast,
+			dominator:       s.Arena.Dominator,
+			howitzer:        s.Arena.Howitzer,
+			taskForce:       s.Arena.TaskForce,
+			builders:        s.Arena.Builders,
+			flyingAttackers: s.Arena.FlyingAttackers,
+			groundAttackers: s.Arena.GroundAttackers,
+			attackSides:     s.Arena.WaveSides,
+		}
+		for _, g := range s.Arena.WaveGroups {
+			group := arenaWaveGroup{
+				totalCost: g.TotalCost,
+				side:      g.Side,
+			}
+			for _, u := range g.Units {
+				group.units = append(group.units, arenaWaveUnit{
+					stats:     d.tables.creeps[u.Stats],
+					super:     u.Super,
+					fragScore: u.FragScore,
+				})
+			}
+			m.waveInfo.groups = append(m.waveInfo.groups, group)
+		}
+		m.overviewText = m.createWaveOverviewText()
+	}
+}
+
+func (d *worldSnapshotDecoder) restoreChoices(g *choiceGenerator, s *choiceSnapshot) {
+	g.targetValue = s.TargetValue
+	g.value = s.Value
+	g.state = s.State
+	for i, index := range s.ShuffledOptions {
+		g.shuffledOptions[i] = choiceOptionList[index]
+	}
+	g.beforeSpecialShuffle = s.BeforeSpecialShuffle
+	g.specialOptionIndex = s.SpecialOptionIndex
+	g.buildTurret = s.BuildTurret
+	g.increaseRadius = s.IncreaseRadius
+	g.spawnCrawlers = s.SpawnCrawlers
+	g.doubleTech = s.DoubleTech
+	g.specialChoiceKinds = s.SpecialChoiceKinds
+	g.forcedSpecialChoice = s.ForcedSpecialChoice
+}
+
+// lateRestoreWorld restores the state that depends on the initialized players.
+func (c *Controller) lateRestoreWorld(s *worldSnapshot) {
+	c.setAudioMuted(true)
+	defer c.setAudioMuted(false)
+
+	for i, p := range c.world.players {
+		human, ok := p.(*humanPlayer)
+		if !ok {
+			continue
+		}
+		ps := &s.Players[i]
+		if ps.Selected != -1 {
+			human.selectColony(c.world.allColonies[ps.Selected])
+		}
+		human.state.camera.CenterOn(ps.Camera)
+
+		if human.choiceWindow == nil || ps.Choices == nil {
+			continue
+		}
+		switch {
+		case human.choiceGen.state == choiceReady:
+			human.choiceWindow.RevealChoices(human.choiceGen.GetChoices())
+		case ps.Choices.SelectedIndex != -1:
+			human.choiceWindow.StartCharging(human.choiceGen.targetValue, ps.Choices.SelectedIndex)
+			human.choiceWindow.value = human.choiceGen.value
+		}
+	}
+}
+
+func (d *worldSnapshotDecoder) resolve(ref snapshotRef) any {
+	switch ref.Kind {
+	case refNone:
+		return nil
+	case refColony:
+		return d.colonies[ref.Index]
+	case refAgent:
+		return d.agents[ref.Index]
+	case refCreep:
+		return d.creeps[ref.Index]
+	case refEssence:
+		return d.essences[ref.Index]
+	case refConstruction:
+		return d.constructions[ref.Index]
+	case refNeutralBuilding:
+		return d.world.neutralBuildings[ref.Index]
+	case refTether:
+		return d.tethers[ref.Index]
+	case refLava:
+		return &d.world.lavaPuddles[ref.Index].attacker
+	case refStub:
+		return d.stubs[ref.Index]
+	}
+	panic(fmt.Sprintf("unexpected ref kind %d", ref.Kind))
+}
+
+func (d *worldSnapshotDecoder) targetable(ref snapshotRef) targetable {
+	if x := d.resolve(ref); x != nil {
+		return x.(targetable)
+	}
+	return nil
+}
+
+func (d *worldSnapshotDecoder) creepOrNil(i int) *creepNode {
+	if i == -1 {
+		return nil
+	}
+	return d.creeps[i]
+}
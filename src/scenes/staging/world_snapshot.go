@@ -0,0 +1,1336 @@
+package staging
+
+import (
+	"fmt"
+
+	"github.com/quasilyte/ge"
+	"github.com/quasilyte/ge/xslices"
+	"github.com/quasilyte/gmath"
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/pathing"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+// worldSnapshot is a serializable world state used by the mid-match saves.
+//
+// The nodes reference each other a lot, so the references are encoded
+// as the indices inside the snapshot lists (see snapshotRef).
+// Game data objects (drone and creep stats, weapons, resource kinds)
+// are encoded as the indices inside the snapshotTables lists;
+// the snapshot can only be loaded by the same game build.
+//
+// The static level parts (walls, forests, teleporters, lava)
+// are re-created by the level generator; only their dynamic
+// state is stored here, matched by their index.
+//
+// Some state is not captured, so the restored world is not bit-identical:
+//   - the objects update order;
+//   - short-living nodes (bombs, falling drones, effects, tether beams delay);
+//   - purely visual state (builder lasers, turret rotation, animations, fog of war history);
+//   - weather, notices and the colony planner caches;
+//   - the replay actions recorded before the save.
+type worldSnapshot struct {
+	Seed int64
+
+	TimePlayed        float64
+	Ticks             int
+	FastforwardTicks  int
+	ControllerTick    int
+	VictoryCheckDelay float64
+
+	Colonies        []colonySnapshot
+	Agents          []agentSnapshot
+	Creeps          []creepSnapshot
+	Essences        []essenceSnapshot
+	Constructions   []constructionSnapshot
+	Projectiles     []projectileSnapshot
+	Tethers         []tetherSnapshot
+	CreepSpawners   []creepSpawnerSnapshot
+	ServantSpawners []servantSpawnerSnapshot
+	Stubs           []snapshotStub
+
+	// These lists contain the Agents, Creeps and Essences indices.
+	Turrets    []int
+	Artifacts  []int
+	Centurions []int
+	Crawlers   []int
+
+	Boss     int
+	WispLair int
+	Fortress int
+
+	CenturionRallyPoint    gmath.Vec
+	CenturionRallyPointPtr rallyPointKind
+
+	LavaPuddles      []lavaPuddleSnapshot
+	LavaGeysers      []float64
+	NeutralBuildings []neutralBuildingSnapshot
+
+	GridCounters map[int]uint8
+	Pathgrid     []byte
+
+	Result      battleResults
+	MatchEvents matchEventsSnapshot
+	Telemetry   telemetrySnapshot
+	Coordinator coordinatorSnapshot
+
+	Classic *classicManagerSnapshot
+	Arena   *arenaManagerSnapshot
+
+	Players []playerSnapshot
+}
+
+type snapshotRefKind uint8
+
+const (
+	refNone snapshotRefKind = iota
+	refColony
+	refAgent
+	refCreep
+	refEssence
+	refConstruction
+	refNeutralBuilding
+	refTether
+	refLava
+	refStub
+)
+
+// snapshotRef is an encoded object reference.
+// The Index is a position inside the snapshot list of the given kind.
+//
+// The objects that are already destroyed, but still referenced
+// by something (like a projectile attacker) are encoded as stubs.
+type snapshotRef struct {
+	Kind  snapshotRefKind
+	Index int
+}
+
+// snapshotStub is a destroyed object.
+// It's restored as a disposed node of the same type.
+// The Player is an owner of the colonies, drones and constructions.
+type snapshotStub struct {
+	Kind   snapshotRefKind
+	Stats  int
+	Player int
+	Pos    gmath.Vec
+}
+
+type rallyPointKind uint8
+
+const (
+	rallyPointNone rallyPointKind = iota
+	rallyPointBoss
+	rallyPointCustom
+)
+
+type colonySnapshot struct {
+	Player    int
+	ID        int
+	Pos       gmath.Vec
+	Mode      colonyCoreMode
+	Flying    bool
+	Height    float64
+	DrawOrder float64
+
+	Health            float64
+	MaxHealth         float64
+	TeleportDelay     float64
+	ActivatedTeleport int
+	MaxSpeed          float64
+	MaxJumpDist       float64
+	Tether            int
+
+	HeavyDamageWarningCooldown float64
+
+	RallyPoint             gmath.Vec
+	Waypoint               gmath.Vec
+	RelocationPoint        gmath.Vec
+	PlannedRelocationPoint gmath.Vec
+	Path                   []byte
+
+	ResourceShortage int
+	Resources        float64
+	EliteResources   float64
+	EvoPoints        float64
+	NumTurretsBuilt  int
+
+	Acceleration  float64
+	OpenHatchTime float64
+	RealRadius    float64
+
+	StashTransferDelay  float64
+	RepairSentinelDelay float64
+	FreeWorkerDelay     float64
+	UpkeepDelay         float64
+	CloningDelay        float64
+	ResourceDelay       float64
+	CaptureDelay        float64
+	ArtifactDelay       float64
+	AttackDelay         float64
+	ActionDelay         float64
+
+	Priorities     []float64
+	FactionWeights []float64
+
+	FailedResource     snapshotRef
+	FailedResourceTick int
+}
+
+type agentRole uint8
+
+const (
+	agentRoleDrone agentRole = iota
+	agentRoleTurret
+	agentRoleRoomba
+	agentRoleMerc
+)
+
+type agentSnapshot struct {
+	Role    agentRole
+	Colony  snapshotRef
+	Factory snapshotRef
+	Stats   int
+	Pos     gmath.Vec
+
+	Traits   agentTraitBits
+	Path     []byte
+	Mode     colonyAgentMode
+	Waypoint gmath.Vec
+	Dir      gmath.Vec
+	Target   snapshotRef
+
+	Payload         int
+	CloneGen        int
+	Rank            int
+	ExtraLevel      int
+	CommanderID     int
+	Faction         gamedata.FactionTag
+	CargoValue      float64
+	CargoEliteValue float64
+	ReloadRate      float64
+	HealthRegen     float64
+
+	AttackDelay  float64
+	SupportDelay float64
+	SpecialDelay float64
+	Cloaking     float64
+
+	MaxHealth       float64
+	Health          float64
+	MaxEnergy       float64
+	Energy          float64
+	EnergyRegenRate float64
+	Slow            float64
+	Lifetime        float64
+	EnergyTarget    float64
+
+	InsideForest  bool
+	Tether        bool
+	Resting       bool
+	Speed         float64
+	Dist          float64
+	WaypointsLeft int
+
+	Height       float64
+	ShadowHidden bool
+	SpriteHidden bool
+
+	// These fields describe the turret-specific state.
+	SiegeAmmo      int
+	SiegeTarget    snapshotRef
+	SentinelWorker snapshotRef
+	HatchVisible   bool
+
+	Beam *beamSnapshot
+}
+
+// beamSnapshot describes the agent cloning beam.
+// The beam end is either attached to an object (To),
+// or it's an absolute position (Offset).
+type beamSnapshot struct {
+	Kind   activeBeamKind
+	To     snapshotRef
+	Offset gmath.Vec
+}
+
+type creepSnapshot struct {
+	Stats    int
+	Pos      gmath.Vec
+	SpawnPos gmath.Vec
+	Waypoint gmath.Vec
+
+	WasAttacking    bool
+	WasRetreating   bool
+	SpawnedFromBase bool
+	Cloaking        bool
+	InsideForest    bool
+	Super           bool
+	CenturionReady  bool
+
+	Path            []byte
+	SpecialTarget   snapshotRef
+	SpecialDelay    float64
+	SpecialModifier float64
+
+	Aggro       float64
+	AggroTarget snapshotRef
+	Disarm      float64
+	Slow        float64
+	Health      float64
+	MaxHealth   float64
+	Marked      float64
+	Shield      float64
+	AttackDelay float64
+
+	BossStage int
+	FragScore int
+	Spawner   int
+
+	SpriteVisible    bool
+	AltSpriteVisible bool
+	FrameOffsetX     float64
+	Height           float64
+	ShadowHidden     bool
+}
+
+type essenceSnapshot struct {
+	Stats int
+	Pos   gmath.Vec
+
+	Capacity          int
+	Resource          int
+	Percengage        float64
+	RecoverDelay      float64
+	RecoverDelayTimer float64
+	BeingHarvested    bool
+
+	Rotation       gmath.Rad
+	FlipHorizontal bool
+
+	LavaPuddle int
+}
+
+type constructionSnapshot struct {
+	Stats            int
+	Player           int
+	Pos              gmath.Vec
+	ConstructPosBase gmath.Vec
+	Progress         float64
+	Attention        float64
+}
+
+type projectileSnapshot struct {
+	Weapon   int
+	Attacker snapshotRef
+	Target   snapshotRef
+
+	Pos       gmath.Vec
+	ToPos     gmath.Vec
+	FireDelay float64
+
+	TrailCounter float64
+	Rotation     gmath.Rad
+
+	ArcProgressionScaling float64
+	ArcProgression        float64
+	ArcStart              gmath.Vec
+	ArcFrom               gmath.Vec
+	ArcTo                 gmath.Vec
+
+	Seq    uint8
+	Guided bool
+
+	RockSpawner  int
+	RockSpawnPos gmath.Vec
+}
+
+type tetherSnapshot struct {
+	Source     snapshotRef
+	Target     snapshotRef
+	Lifespan   float64
+	ShaderTime float64
+}
+
+type creepSpawnerSnapshot struct {
+	Stats     int
+	Delay     float64
+	Pos       gmath.Vec
+	Dest      gmath.Vec
+	FragScore int
+	Super     bool
+}
+
+type servantSpawnerSnapshot struct {
+	Pos             gmath.Vec
+	Dir             gmath.Vec
+	Target          snapshotRef
+	Super           bool
+	Rotation        gmath.Rad
+	RotateClockwise bool
+}
+
+type lavaPuddleSnapshot struct {
+	FireDelay         float64
+	AttackerPos       gmath.Vec
+	NumResourceSpawns int
+	MaxResourceSpawns int
+}
+
+type neutralBuildingSnapshot struct {
+	Pos   gmath.Vec
+	Agent int
+}
+
+type matchEventsSnapshot struct {
+	Events            []serverapi.MatchEvent
+	NextReportTick    int
+	ResourcesGathered []float64
+	BossStage         int
+}
+
+type telemetrySnapshot struct {
+	Interval       int
+	NextSampleTick int
+	Players        []playerTelemetrySnapshot
+}
+
+type playerTelemetrySnapshot struct {
+	Current telemetrySample
+	Samples []telemetrySample
+}
+
+type coordinatorSnapshot struct {
+	ScoutingDelay    float64
+	AttackDelay      float64
+	AttackRuinsDelay float64
+	ScatterDelay     float64
+	RelocateDelay    float64
+}
+
+type classicManagerSnapshot struct {
+	Tier3spawnDelay   float64
+	Tier3spawnRate    float64
+	GrenadiersDelay   float64
+	GrenadierWave     int
+	CrawlersDelay     float64
+	CoordinatorsDelay float64
+}
+
+type arenaManagerSnapshot struct {
+	Level           int
+	WaveBudget      int
+	LastLevel       int
+	InfoUpdateDelay float64
+	LevelStartDelay float64
+	GrenadiersDelay float64
+	GrenadierWave   int
+	AttackSides     []int
+
+	WaveGroups      []arenaWaveGroupSnapshot
+	IsLast          bool
+	Dominator       bool
+	Howitzer        bool
+	TaskForce       bool
+	Builders        bool
+	FlyingAttackers bool
+	GroundAttackers bool
+	WaveSides       [4]bool
+}
+
+type arenaWaveGroupSnapshot struct {
+	Units     []arenaWaveUnitSnapshot
+	TotalCost int
+	Side      int
+}
+
+type arenaWaveUnitSnapshot struct {
+	Stats     int
+	Super     bool
+	FragScore int
+}
+
+type playerSnapshot struct {
+	ColonySeq     int
+	ResourceStash float64
+	HasRoombas    bool
+	Selected      int
+	Camera        gmath.Vec
+
+	Choices *choiceSnapshot
+}
+
+type choiceSnapshot struct {
+	TargetValue float64
+	Value       float64
+	State       choiceState
+
+	ShuffledOptions      []int
+	BeforeSpecialShuffle int
+	SpecialOptionIndex   int
+	BuildTurret          bool
+	IncreaseRadius       bool
+	SpawnCrawlers        bool
+	DoubleTech           bool
+	SpecialChoiceKinds   []specialChoiceKind
+	ForcedSpecialChoice  specialChoiceKind
+
+	// SelectedIndex is a choice window card that is being charged.
+	SelectedIndex int
+}
+
+// snapshotTables are the game data lists used to encode the stats references.
+type snapshotTables struct {
+	agents        []*gamedata.AgentStats
+	creeps        []*gamedata.CreepStats
+	weapons       []*gamedata.WeaponStats
+	essences      []*essenceSourceStats
+	constructions []*constructionStats
+}
+
+var worldSnapshotTables = newSnapshotTables()
+
+func newSnapshotTables() *snapshotTables {
+	t := &snapshotTables{
+		creeps: gamedata.CreepStatsList,
+		essences: []*essenceSourceStats{
+			redCrystalSource,
+			artifactSource,
+			oilSource,
+			redOilSource,
+			goldSource,
+			crystalSource,
+			sulfurSource,
+			ironSource,
+			mineralSource,
+			magmaRockSource,
+			organicSource,
+			smallScrapSource,
+			scrapSource,
+			smallScrapCreepSource,
+			scrapCreepSource,
+			bigScrapCreepSource,
+		},
+		constructions: []*constructionStats{
+			colonyCoreConstructionStats,
+			harvesterConstructionStats,
+			gunpointConstructionStats,
+			siegeConstructionStats,
+			refineryConstructionStats,
+			beamTowerConstructionStats,
+			sentinelpointConstructionStats,
+			tetherBeaconConstructionStats,
+		},
+	}
+
+	t.agents = append(t.agents, gamedata.AllDroneStats()...)
+	t.agents = append(t.agents, gamedata.TurretStatsList...)
+	t.agents = append(t.agents, gamedata.ArtifactsList...)
+	t.agents = append(t.agents, gamedata.RelictAgentStats)
+
+	addWeapon := func(w *gamedata.WeaponStats) {
+		if w != nil && !xslices.Contains(t.weapons, w) {
+			t.weapons = append(t.weapons, w)
+		}
+	}
+	for _, stats := range t.agents {
+		addWeapon(stats.Weapon)
+	}
+	for _, stats := range t.creeps {
+		addWeapon(stats.Weapon)
+		addWeapon(stats.SuperWeapon)
+		addWeapon(stats.SpecialWeapon)
+	}
+	addWeapon(gamedata.SiegeAgentWeapon)
+	addWeapon(gamedata.AtomicBombWeapon)
+	addWeapon(gamedata.MagmaHazardWeapon)
+	addWeapon(gamedata.TankCoreWeapon1)
+	addWeapon(gamedata.HiveMortarWeapon)
+
+	return t
+}
+
+// worldSnapshotEncoder captures the world state.
+//
+// The first encoding error is remembered and reported by the capture;
+// the snapshot is unusable after that.
+type worldSnapshotEncoder struct {
+	c      *Controller
+	world  *worldState
+	tables *snapshotTables
+
+	colonies      map[*colonyCoreNode]int
+	agents        map[*colonyAgentNode]int
+	creeps        map[*creepNode]int
+	essences      map[*essenceSourceNode]int
+	constructions map[*constructionNode]int
+	tethers       map[*tetherNode]int
+	stubs         map[any]int
+
+	snapshot *worldSnapshot
+
+	err error
+}
+
+func (c *Controller) captureWorld() (*worldSnapshot, error) {
+	e := &worldSnapshotEncoder{
+		c:             c,
+		world:         c.world,
+		tables:        worldSnapshotTables,
+		colonies:      make(map[*colonyCoreNode]int),
+		agents:        make(map[*colonyAgentNode]int),
+		creeps:        make(map[*creepNode]int),
+		essences:      make(map[*essenceSourceNode]int),
+		constructions: make(map[*constructionNode]int),
+		tethers:       make(map[*tetherNode]int),
+		stubs:         make(map[any]int),
+		snapshot:      &worldSnapshot{},
+	}
+	e.encode()
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.snapshot, nil
+}
+
+func (e *worldSnapshotEncoder) fail(format string, args ...any) {
+	if e.err == nil {
+		e.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (e *worldSnapshotEncoder) encode() {
+	w := e.world
+	s := e.snapshot
+
+	// The objects are enumerated first, so the references
+	// can be encoded in any order.
+	var agents []*colonyAgentNode
+	var roles []agentRole
+	addAgent := func(a *colonyAgentNode, role agentRole) {
+		e.agents[a] = len(agents)
+		agents = append(agents, a)
+		roles = append(roles, role)
+	}
+	for i, colony := range w.allColonies {
+		e.colonies[colony] = i
+		colony.agents.Each(func(a *colonyAgentNode) {
+			addAgent(a, agentRoleDrone)
+		})
+		for _, turret := range colony.turrets {
+			addAgent(turret, agentRoleTurret)
+		}
+		for _, roomba := range colony.roombas {
+			addAgent(roomba, agentRoleRoomba)
+		}
+	}
+	for _, merc := range w.mercs {
+		addAgent(merc, agentRoleMerc)
+	}
+	for i, creep := range w.creeps {
+		e.creeps[creep] = i
+	}
+	for i, essence := range w.essenceSources {
+		e.essences[essence] = i
+	}
+	for i, construction := range w.constructions {
+		e.constructions[construction] = i
+	}
+	var tethers []*tetherNode
+	var creepSpawners []*creepSpawnerNode
+	var servantSpawners []*servantSpawnerNode
+	e.c.nodeRunner.walkObjects(func(o ge.SceneObject) {
+		if o.IsDisposed() {
+			return
+		}
+		switch o := o.(type) {
+		case *tetherNode:
+			e.tethers[o] = len(tethers)
+			tethers = append(tethers, o)
+		case *creepSpawnerNode:
+			creepSpawners = append(creepSpawners, o)
+		case *servantSpawnerNode:
+			servantSpawners = append(servantSpawners, o)
+		}
+	})
+
+	s.TimePlayed = e.c.nodeRunner.timePlayed
+	s.Ticks = e.c.nodeRunner.ticks
+	s.FastforwardTicks = e.c.nodeRunner.fastforwardTicks
+	s.ControllerTick = e.c.controllerTick
+	s.VictoryCheckDelay = e.c.victoryCheckDelay
+
+	for _, colony := range w.allColonies {
+		s.Colonies = append(s.Colonies, e.encodeColony(colony))
+	}
+	for i, a := range agents {
+		s.Agents = append(s.Agents, e.encodeAgent(a, roles[i]))
+	}
+	for _, creep := range w.creeps {
+		s.Creeps = append(s.Creeps, e.encodeCreep(creep))
+	}
+	for _, essence := range w.essenceSources {
+		s.Essences = append(s.Essences, e.encodeEssence(essence))
+	}
+	for _, construction := range w.constructions {
+		s.Constructions = append(s.Constructions, constructionSnapshot{
+			Stats:            e.constructionStats(construction.stats),
+			Player:           e.playerIndex(construction.player),
+			Pos:              construction.pos,
+			ConstructPosBase: construction.constructPosBase,
+			Progress:         construction.progress,
+			Attention:        construction.attention,
+		})
+	}
+	e.c.nodeRunner.walkProjectiles(func(p *projectileNode) {
+		if p.IsDisposed() {
+			return
+		}
+		s.Projectiles = append(s.Projectiles, e.encodeProjectile(p))
+	})
+	for _, tether := range tethers {
+		s.Tethers = append(s.Tethers, tetherSnapshot{
+			Source:     e.ref(tether.source),
+			Target:     e.ref(tether.target),
+			Lifespan:   tether.lifespan,
+			ShaderTime: tether.shaderTime,
+		})
+	}
+	for _, spawner := range creepSpawners {
+		s.CreepSpawners = append(s.CreepSpawners, creepSpawnerSnapshot{
+			Stats:     e.creepStats(spawner.creepStats),
+			Delay:     spawner.delay,
+			Pos:       spawner.pos,
+			Dest:      spawner.creepDest,
+			FragScore: spawner.fragScore,
+			Super:     spawner.super,
+		})
+	}
+	for _, spawner := range servantSpawners {
+		s.ServantSpawners = append(s.ServantSpawners, servantSpawnerSnapshot{
+			Pos:             spawner.pos,
+			Dir:             spawner.dir,
+			Target:          e.ref(spawner.target),
+			Super:           spawner.super,
+			Rotation:        spawner.rotation,
+			RotateClockwise: spawner.rotateClockwise,
+		})
+	}
+
+	for _, turret := range w.turrets {
+		s.Turrets = append(s.Turrets, e.agentIndex(turret))
+	}
+	for _, artifact := range w.artifacts {
+		s.Artifacts = append(s.Artifacts, e.essenceIndex(artifact))
+	}
+	for _, creep := range w.centurions {
+		s.Centurions = append(s.Centurions, e.creepIndex(creep))
+	}
+	for _, creep := range w.creepCoordinator.crawlers {
+		s.Crawlers = append(s.Crawlers, e.creepIndex(creep))
+	}
+	s.Boss = e.creepIndex(w.boss)
+	s.WispLair = e.creepIndex(w.wispLair)
+	s.Fortress = e.creepIndex(w.fortress)
+
+	s.CenturionRallyPoint = w.centurionRallyPoint
+	switch {
+	case w.centurionRallyPointPtr == nil:
+		s.CenturionRallyPointPtr = rallyPointNone
+	case w.boss != nil && w.centurionRallyPointPtr == &w.boss.pos:
+		s.CenturionRallyPointPtr = rallyPointBoss
+	case w.centurionRallyPointPtr == &w.centurionRallyPoint:
+		s.CenturionRallyPointPtr = rallyPointCustom
+	default:
+		e.fail("unexpected centurion rally point")
+	}
+
+	for _, lava := range w.lavaPuddles {
+		s.LavaPuddles = append(s.LavaPuddles, lavaPuddleSnapshot{
+			FireDelay:         lava.fireDelay,
+			AttackerPos:       lava.attacker.pos,
+			NumResourceSpawns: lava.numResourceSpawns,
+			MaxResourceSpawns: lava.maxResourceSpawns,
+		})
+	}
+	for _, geyser := range w.lavaGeysers {
+		s.LavaGeysers = append(s.LavaGeysers, geyser.fireDelay)
+	}
+	for _, b := range w.neutralBuildings {
+		s.NeutralBuildings = append(s.NeutralBuildings, neutralBuildingSnapshot{
+			Pos:   b.pos,
+			Agent: e.agentIndex(b.agent),
+		})
+	}
+
+	s.GridCounters = w.gridCounters
+	pathgrid, err := w.pathgrid.MarshalBinary()
+	if err != nil {
+		e.fail("encode pathgrid: %v", err)
+	}
+	s.Pathgrid = pathgrid
+
+	s.Result = w.result
+	s.MatchEvents = matchEventsSnapshot{
+		Events:            w.matchEvents.events,
+		NextReportTick:    w.matchEvents.nextReportTick,
+		ResourcesGathered: w.matchEvents.resourcesGathered,
+		BossStage:         w.matchEvents.bossStage,
+	}
+	s.Telemetry = telemetrySnapshot{
+		Interval:       w.telemetry.interval,
+		NextSampleTick: w.telemetry.nextSampleTick,
+	}
+	for _, p := range w.telemetry.players {
+		s.Telemetry.Players = append(s.Telemetry.Players, playerTelemetrySnapshot{
+			Current: p.current,
+			Samples: p.samples,
+		})
+	}
+	s.Coordinator = coordinatorSnapshot{
+		ScoutingDelay:    w.creepCoordinator.scoutingDelay,
+		AttackDelay:      w.creepCoordinator.attackDelay,
+		AttackRuinsDelay: w.creepCoordinator.attackRuinsDelay,
+		ScatterDelay:     w.creepCoordinator.scatterDelay,
+		RelocateDelay:    w.creepCoordinator.relocateDelay,
+	}
+
+	if m := e.c.classicManager; m != nil {
+		s.Classic = &classicManagerSnapshot{
+			Tier3spawnDelay:   m.tier3spawnDelay,
+			Tier3spawnRate:    m.tier3spawnRate,
+			GrenadiersDelay:   m.grenadiersDelay,
+			GrenadierWave:     m.grenadierWave,
+			CrawlersDelay:     m.crawlersDelay,
+			CoordinatorsDelay: m.coordinatorsDelay,
+		}
+	}
+	if m := e.c.arenaManager; m != nil {
+		s.Arena = e.encodeArena(m)
+	}
+
+	for _, p := range w.players {
+		s.Players = append(s.Players, e.encodePlayer(p))
+	}
+
+	// Re-seed the RNG, so its state can be saved as a single number.
+	s.Seed = w.rand.PositiveInt64()
+	w.rand.SetSeed(s.Seed)
+}
+
+func (e *worldSnapshotEncoder) encodeColony(colony *colonyCoreNode) colonySnapshot {
+	s := colonySnapshot{
+		Player:    e.playerIndex(colony.player),
+		ID:        colony.id,
+		Pos:       colony.pos,
+		Mode:      colony.mode,
+		Flying:    colony.flyingSprite.Visible,
+		Height:    colony.shadowComponent.height,
+		DrawOrder: colony.drawOrder,
+
+		Health:            colony.health,
+		MaxHealth:         colony.maxHealth,
+		TeleportDelay:     colony.teleportDelay,
+		ActivatedTeleport: xslices.Index(e.world.teleporters, colony.activatedTeleport),
+		MaxSpeed:          colony.maxSpeed,
+		MaxJumpDist:       colony.maxJumpDist,
+		Tether:            colony.tether,
+
+		HeavyDamageWarningCooldown: colony.heavyDamageWarningCooldown,
+
+		RallyPoint:             colony.rallyPoint,
+		Waypoint:               colony.waypoint,
+		RelocationPoint:        colony.relocationPoint,
+		PlannedRelocationPoint: colony.plannedRelocationPoint,
+		Path:                   encodeGridPath(colony.path),
+
+		ResourceShortage: colony.resourceShortage,
+		Resources:        colony.resources,
+		EliteResources:   colony.eliteResources,
+		EvoPoints:        colony.evoPoints,
+		NumTurretsBuilt:  colony.numTurretsBuilt,
+
+		Acceleration:  colony.acceleration,
+		OpenHatchTime: colony.openHatchTime,
+		RealRadius:    colony.realRadius,
+
+		StashTransferDelay:  colony.stashTransferDelay,
+		RepairSentinelDelay: colony.repairSentinelDelay,
+		FreeWorkerDelay:     colony.freeWorkerDelay,
+		UpkeepDelay:         colony.upkeepDelay,
+		CloningDelay:        colony.cloningDelay,
+		ResourceDelay:       colony.resourceDelay,
+		CaptureDelay:        colony.captureDelay,
+		ArtifactDelay:       colony.artifactDelay,
+		AttackDelay:         colony.attackDelay,
+		ActionDelay:         colony.actionDelay,
+
+		FailedResource:     e.ref(colony.failedResource),
+		FailedResourceTick: colony.failedResourceTick,
+	}
+	for _, elem := range colony.priorities.Elems {
+		s.Priorities = append(s.Priorities, elem.Weight)
+	}
+	for _, elem := range colony.factionWeights.Elems {
+		s.FactionWeights = append(s.FactionWeights, elem.Weight)
+	}
+	return s
+}
+
+func (e *worldSnapshotEncoder) encodeAgent(a *colonyAgentNode, role agentRole) agentSnapshot {
+	s := agentSnapshot{
+		Role:   role,
+		Colony: e.ref(a.colonyCore),
+		Stats:  e.agentStats(a.stats),
+		Pos:    a.pos,
+
+		Traits:   a.traits,
+		Path:     encodeGridPath(a.path),
+		Mode:     a.mode,
+		Waypoint: a.waypoint,
+		Dir:      a.dir,
+
+		Payload:         a.payload,
+		CloneGen:        a.cloneGen,
+		Rank:            a.rank,
+		ExtraLevel:      a.extraLevel,
+		CommanderID:     a.commanderID,
+		Faction:         a.faction,
+		CargoValue:      a.cargoValue,
+		CargoEliteValue: a.cargoEliteValue,
+		ReloadRate:      a.reloadRate,
+		HealthRegen:     a.healthRegen,
+
+		AttackDelay:  a.attackDelay,
+		SupportDelay: a.supportDelay,
+		SpecialDelay: a.specialDelay,
+		Cloaking:     a.cloaking,
+
+		MaxHealth:       a.maxHealth,
+		Health:          a.health,
+		MaxEnergy:       a.maxEnergy,
+		Energy:          a.energy,
+		EnergyRegenRate: a.energyRegenRate,
+		Slow:            a.slow,
+		Lifetime:        a.lifetime,
+		EnergyTarget:    a.energyTarget,
+
+		InsideForest:  a.insideForest,
+		Tether:        a.tether,
+		Resting:       a.resting,
+		Speed:         a.speed,
+		Dist:          a.dist,
+		WaypointsLeft: a.waypointsLeft,
+
+		Height:       a.shadowComponent.height,
+		ShadowHidden: a.shadowComponent.sprite != nil && !a.shadowComponent.sprite.Visible,
+		SpriteHidden: !a.sprite.Visible,
+	}
+	if role == agentRoleMerc {
+		s.Factory = e.ref(a.factory)
+	}
+
+	switch target := a.target.(type) {
+	case *siegeTurretNode:
+		s.SiegeAmmo = target.ammo
+		s.SiegeTarget = e.ref(target.target)
+	case *sentinelpointTurretNode:
+		s.SentinelWorker = e.ref(target.worker)
+	case *ge.Sprite:
+		s.HatchVisible = target.Visible
+	default:
+		s.Target = e.ref(a.target)
+	}
+
+	if beam := a.cloningBeam; beam != nil && !beam.IsDisposed() {
+		s.Beam = &beamSnapshot{
+			Kind:   beam.kind,
+			Offset: beam.to.Offset,
+		}
+		switch {
+		case beam.to.Base == nil:
+			// An absolute position.
+		case a.colonyCore != nil && beam.to.Base == &a.colonyCore.pos:
+			s.Beam.To = e.ref(a.colonyCore)
+		case beam.to.Base == beamTargetPos(a.target):
+			s.Beam.To = e.ref(a.target)
+		default:
+			s.Beam.Offset = beam.to.Resolve()
+		}
+	}
+
+	return s
+}
+
+// beamTargetPos returns the position a cloning beam may be attached to.
+func beamTargetPos(target any) *gmath.Vec {
+	switch target := target.(type) {
+	case *colonyCoreNode:
+		return &target.pos
+	case *colonyAgentNode:
+		return &target.pos
+	case *essenceSourceNode:
+		return &target.pos
+	case *constructionNode:
+		return &target.constructPosBase
+	}
+	return nil
+}
+
+func (e *worldSnapshotEncoder) encodeCreep(creep *creepNode) creepSnapshot {
+	s := creepSnapshot{
+		Stats:    e.creepStats(creep.stats),
+		Pos:      creep.pos,
+		SpawnPos: creep.spawnPos,
+		Waypoint: creep.waypoint,
+
+		WasAttacking:    creep.wasAttacking,
+		WasRetreating:   creep.wasRetreating,
+		SpawnedFromBase: creep.spawnedFromBase,
+		Cloaking:        creep.cloaking,
+		InsideForest:    creep.insideForest,
+		Super:           creep.super,
+		CenturionReady:  creep.centurionReady,
+
+		Path:            encodeGridPath(creep.path),
+		SpecialDelay:    creep.specialDelay,
+		SpecialModifier: creep.specialModifier,
+
+		Aggro:       creep.aggro,
+		AggroTarget: e.ref(creep.aggroTarget),
+		Disarm:      creep.disarm,
+		Slow:        creep.slow,
+		Health:      creep.health,
+		MaxHealth:   creep.maxHealth,
+		Marked:      creep.marked,
+		Shield:      creep.shield,
+		AttackDelay: creep.attackDelay,
+
+		BossStage: creep.bossStage,
+		FragScore: creep.fragScore,
+		Spawner:   e.creepIndex(creep.spawner),
+
+		SpriteVisible: creep.sprite.Visible,
+		FrameOffsetX:  creep.sprite.FrameOffset.X,
+		Height:        creep.shadowComponent.height,
+		ShadowHidden:  creep.shadowComponent.sprite != nil && !creep.shadowComponent.sprite.Visible,
+	}
+	if creep.altSprite != nil {
+		s.AltSpriteVisible = creep.altSprite.Visible
+	}
+	// The howitzer trunk is re-created by the creep itself.
+	if _, ok := creep.specialTarget.(*howitzerTrunkNode); !ok {
+		s.SpecialTarget = e.ref(creep.specialTarget)
+	}
+	return s
+}
+
+func (e *worldSnapshotEncoder) encodeEssence(essence *essenceSourceNode) essenceSnapshot {
+	return essenceSnapshot{
+		Stats: e.essenceStats(essence.stats),
+		Pos:   essence.pos,
+
+		Capacity:          essence.capacity,
+		Resource:          essence.resource,
+		Percengage:        essence.percengage,
+		RecoverDelay:      essence.recoverDelay,
+		RecoverDelayTimer: essence.recoverDelayTimer,
+		BeingHarvested:    essence.beingHarvested,
+
+		Rotation:       essence.rotation,
+		FlipHorizontal: essence.sprite.FlipHorizontal,
+
+		LavaPuddle: xslices.Index(e.world.lavaPuddles, essence.lavaPuddle),
+	}
+}
+
+func (e *worldSnapshotEncoder) encodeProjectile(p *projectileNode) projectileSnapshot {
+	return projectileSnapshot{
+		Weapon:   e.weaponStats(p.weapon),
+		Attacker: e.ref(p.attacker),
+		Target:   e.ref(p.target),
+
+		Pos:       p.pos,
+		ToPos:     p.toPos,
+		FireDelay: p.fireDelay,
+
+		TrailCounter: p.trailCounter,
+		Rotation:     p.rotation,
+
+		ArcProgressionScaling: p.arcProgressionScaling,
+		ArcProgression:        p.arcProgression,
+		ArcStart:              p.arcStart,
+		ArcFrom:               p.arcFrom,
+		ArcTo:                 p.arcTo,
+
+		Seq:    p.seq,
+		Guided: p.guided,
+
+		RockSpawner:  xslices.Index(e.world.lavaPuddles, p.rockSpawner),
+		RockSpawnPos: p.rockSpawnPos,
+	}
+}
+
+func (e *worldSnapshotEncoder) encodeArena(m *arenaManager) *arenaManagerSnapshot {
+	s := &arenaManagerSnapshot{
+		Level:           m.level,
+		WaveBudget:      m.waveBudget,
+		LastLevel:       m.lastLevel,
+		InfoUpdateDelay: m.infoUpdateDelay,
+		LevelStartDelay: m.levelStartDelay,
+		GrenadiersDelay: m.grenadiersDelay,
+		GrenadierWave:   m.grenadierWave,
+		AttackSides:     m.attackSides,
+
+		IsLast:          m.waveInfo.isLast,
+		Dominator:       m.waveInfo.dominator,
+		Howitzer:        m.waveInfo.howitzer,
+		TaskForce:       m.waveInfo.taskForce,
+		Builders:        m.waveInfo.builders,
+		FlyingAttackers: m.waveInfo.flyingAttackers,
+		GroundAttackers: m.waveInfo.groundAttackers,
+		WaveSides:       m.waveInfo.attackSides,
+	}
+	for _, g := range m.waveInfo.groups {
+		group := arenaWaveGroupSnapshot{
+			TotalCost: g.totalCost,
+			Side:      g.side,
+		}
+		for _, u := range g.units {
+			group.Units = append(group.Units, arenaWaveUnitSnapshot{
+				Stats:     e.creepStats(u.stats),
+				Super:     u.super,
+				FragScore: u.fragScore,
+			})
+		}
+		s.WaveGroups = append(s.WaveGroups, group)
+	}
+	return s
+}
+
+func (e *worldSnapshotEncoder) encodePlayer(p player) playerSnapshot {
+	pstate := p.GetState()
+	s := playerSnapshot{
+		ColonySeq:     pstate.colonySeq,
+		ResourceStash: pstate.resourceStash,
+		HasRoombas:    pstate.hasRoombas,
+		Selected:      xslices.Index(e.world.allColonies, pstate.selectedColony),
+	}
+	if pstate.camera != nil {
+		s.Camera = pstate.camera.CenterPos()
+	}
+
+	human, ok := p.(*humanPlayer)
+	if !ok {
+		return s
+	}
+	g := human.choiceGen
+	choices := &choiceSnapshot{
+		TargetValue:          g.targetValue,
+		Value:                g.value,
+		State:                g.state,
+		BeforeSpecialShuffle: g.beforeSpecialShuffle,
+		SpecialOptionIndex:   g.specialOptionIndex,
+		BuildTurret:          g.buildTurret,
+		IncreaseRadius:       g.increaseRadius,
+		SpawnCrawlers:        g.spawnCrawlers,
+		DoubleTech:           g.doubleTech,
+		SpecialChoiceKinds:   g.specialChoiceKinds,
+		ForcedSpecialChoice:  g.forcedSpecialChoice,
+		SelectedIndex:        -1,
+	}
+	for _, o := range g.shuffledOptions {
+		index := xslices.IndexWhere(choiceOptionList, func(x choiceOption) bool {
+			return &x.effects[0] == &o.effects[0]
+		})
+		if index == -1 {
+			e.fail("unexpected choice option")
+		}
+		choices.ShuffledOptions = append(choices.ShuffledOptions, index)
+	}
+	if human.choiceWindow != nil && human.choiceWindow.charging {
+		choices.SelectedIndex = human.choiceWindow.selectedIndex
+	}
+	s.Choices = choices
+	return s
+}
+
+func (e *worldSnapshotEncoder) ref(x any) snapshotRef {
+	switch x := x.(type) {
+	case nil:
+		return snapshotRef{}
+
+	case *colonyCoreNode:
+		if x == nil {
+			return snapshotRef{}
+		}
+		if i, ok := e.colonies[x]; ok {
+			return snapshotRef{Kind: refColony, Index: i}
+		}
+		return e.stub(x, snapshotStub{
+			Kind:   refColony,
+			Player: e.playerIndex(x.player),
+			Pos:    x.pos,
+		})
+
+	case *colonyAgentNode:
+		if x == nil {
+			return snapshotRef{}
+		}
+		if i, ok := e.agents[x]; ok {
+			return snapshotRef{Kind: refAgent, Index: i}
+		}
+		return e.stub(x, snapshotStub{
+			Kind:   refAgent,
+			Stats:  e.agentStats(x.stats),
+			Player: e.playerIndex(x.colonyCore.player),
+			Pos:    x.pos,
+		})
+
+	case *creepNode:
+		if x == nil {
+			return snapshotRef{}
+		}
+		if i, ok := e.creeps[x]; ok {
+			return snapshotRef{Kind: refCreep, Index: i}
+		}
+		return e.stub(x, snapshotStub{
+			Kind:  refCreep,
+			Stats: e.creepStats(x.stats),
+			Pos:   x.pos,
+		})
+
+	case *essenceSourceNode:
+		if x == nil {
+			return snapshotRef{}
+		}
+		if i, ok := e.essences[x]; ok {
+			return snapshotRef{Kind: refEssence, Index: i}
+		}
+		return e.stub(x, snapshotStub{
+			Kind:  refEssence,
+			Stats: e.essenceStats(x.stats),
+			Pos:   x.pos,
+		})
+
+	case *constructionNode:
+		if x == nil {
+			return snapshotRef{}
+		}
+		if i, ok := e.constructions[x]; ok {
+			return snapshotRef{Kind: refConstruction, Index: i}
+		}
+		return e.stub(x, snapshotStub{
+			Kind:   refConstruction,
+			Stats:  e.constructionStats(x.stats),
+			Player: e.playerIndex(x.player),
+			Pos:    x.pos,
+		})
+
+	case *neutralBuildingNode:
+		if i := xslices.Index(e.world.neutralBuildings, x); i != -1 {
+			return snapshotRef{Kind: refNeutralBuilding, Index: i}
+		}
+
+	case *tetherNode:
+		if i, ok := e.tethers[x]; ok {
+			return snapshotRef{Kind: refTether, Index: i}
+		}
+		// A disposed tether is as good as no tether.
+		return snapshotRef{}
+
+	case *magmaDummyAttacker:
+		for i, lava := range e.world.lavaPuddles {
+			if &lava.attacker == x {
+				return snapshotRef{Kind: refLava, Index: i}
+			}
+		}
+	}
+
+	e.fail("unexpected %T reference", x)
+	return snapshotRef{}
+}
+
+func (e *worldSnapshotEncoder) stub(x any, stub snapshotStub) snapshotRef {
+	i, ok := e.stubs[x]
+	if !ok {
+		i = len(e.snapshot.Stubs)
+		e.stubs[x] = i
+		e.snapshot.Stubs = append(e.snapshot.Stubs, stub)
+	}
+	return snapshotRef{Kind: refStub, Index: i}
+}
+
+func (e *worldSnapshotEncoder) agentIndex(a *colonyAgentNode) int {
+	if i, ok := e.agents[a]; ok {
+		return i
+	}
+	return -1
+}
+
+func (e *worldSnapshotEncoder) creepIndex(creep *creepNode) int {
+	if i, ok := e.creeps[creep]; ok {
+		return i
+	}
+	return -1
+}
+
+func (e *worldSnapshotEncoder) essenceIndex(essence *essenceSourceNode) int {
+	i, ok := e.essences[essence]
+	if !ok {
+		e.fail("unexpected artifact")
+	}
+	return i
+}
+
+func (e *worldSnapshotEncoder) playerIndex(p player) int {
+	i := xslices.Index(e.world.players, p)
+	if i == -1 {
+		e.fail("unexpected player")
+	}
+	return i
+}
+
+func (e *worldSnapshotEncoder) agentStats(stats *gamedata.AgentStats) int {
+	return e.tableIndex(xslices.Index(e.tables.agents, stats), stats)
+}
+
+func (e *worldSnapshotEncoder) creepStats(stats *gamedata.CreepStats) int {
+	return e.tableIndex(xslices.Index(e.tables.creeps, stats), stats)
+}
+
+func (e *worldSnapshotEncoder) weaponStats(stats *gamedata.WeaponStats) int {
+	return e.tableIndex(xslices.Index(e.tables.weapons, stats), stats)
+}
+
+func (e *worldSnapshotEncoder) essenceStats(stats *essenceSourceStats) int {
+	return e.tableIndex(xslices.Index(e.tables.essences, stats), stats)
+}
+
+func (e *worldSnapshotEncoder) constructionStats(stats *constructionStats) int {
+	return e.tableIndex(xslices.Index(e.tables.constructions, stats), stats)
+}
+
+func (e *worldSnapshotEncoder) tableIndex(i int, stats any) int {
+	if i == -1 {
+		e.fail("unexpected %T value", stats)
+	}
+	return i
+}
+
+func encodeGridPath(p pathing.GridPath) []byte {
+	if p == (pathing.GridPath{}) {
+		return nil
+	}
+	data, _ := p.MarshalBinary()
+	return data
+}
+
+func decodeGridPath(data []byte) pathing.GridPath {
+	var p pathing.GridPath
+	if len(data) != 0 {
+		if err := p.UnmarshalBinary(data); err != nil {
+			return pathing.GridPath{}
+		}
+	}
+	return p
+}
@@ -115,6 +115,9 @@ type worldState struct {
 
 	result battleResults
 
+	matchEvents *matchEventLog
+	telemetry   *matchTelemetry
+
 	simulation   bool
 	seedKind     gamedata.SeedKind
 	config       *gamedata.LevelConfig
@@ -434,6 +437,7 @@ func (w *worldState) NewColonyCoreNode(config colonyConfig) *colonyCoreNode {
 	n.EventDestroyed.Connect(nil, func(x *colonyCoreNode) {
 		w.allColonies = xslices.Remove(w.allColonies, x)
 		playerState.colonies = xslices.Remove(playerState.colonies, x)
+		w.matchEvents.OnColonyDestroyed(x)
 		w.EventCheckDefeatState.Emit(gsignal.Void{})
 	})
 	w.allColonies = append(w.allColonies, n)
@@ -508,11 +512,15 @@ func (w *worldState) NewCreepNode(pos gmath.Vec, stats *gamedata.CreepStats) *cr
 		}
 		w.result.CreepFragScore += x.fragScore
 		switch x.stats.Kind {
-		case gamedata.CreepWisp, gamedata.CreepCrawlerBase:
+		case gamedata.CreepWisp:
+			// Not counted as a creep kill.
+		case gamedata.CreepCrawlerBase:
 			// Not counted as a creep kill.
+			w.matchEvents.OnCreepBaseDestroyed(x)
 		case gamedata.CreepBase:
 			// TODO: not used anywhere?
 			w.result.CreepBasesDestroyed++
+			w.matchEvents.OnCreepBaseDestroyed(x)
 		case gamedata.CreepWispLair:
 			w.wispLair = nil
 		case gamedata.CreepFortress:
@@ -523,6 +531,7 @@ func (w *worldState) NewCreepNode(pos gmath.Vec, stats *gamedata.CreepStats) *cr
 			}
 			w.centurionRallyPointPtr = nil
 			w.boss = nil
+			w.matchEvents.OnBossDefeated(x)
 			w.EventCheckDefeatState.Emit(gsignal.Void{})
 		case gamedata.CreepCenturion:
 			w.centurions = xslices.Remove(w.centurions, x)
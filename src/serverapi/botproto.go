@@ -0,0 +1,120 @@
+package serverapi
+
+// The external bot protocol.
+//
+// An external bot controls one of the players over a line-oriented
+// JSON connection (stdin/stdout or a socket).
+// Every decision tick the game sends a BotObservation line
+// and then waits for exactly one BotAction line in response.
+// The game is paused while it waits, so the bot may take its time
+// (unless the runner sets a response time limit, like the tournament does).
+// A malformed response or an unknown action kind is a protocol error
+// that aborts the game.
+//
+// When the game is over, the final BotObservation with Done=true
+// and the game results is sent; no response is expected.
+
+// BotProtocolVersion is incremented on every incompatible protocol change.
+const BotProtocolVersion = 1
+
+type BotObservation struct {
+	Version int `json:"version"`
+
+	Tick     int  `json:"tick"`
+	PlayerID int  `json:"player_id"`
+	Done     bool `json:"done,omitempty"`
+
+	// LastAction is "ok" or "rejected" for the previous non-wait action.
+	LastAction string `json:"last_action,omitempty"`
+
+	// Resources is the amount of the stashed resources;
+	// the colony resources are reported per colony.
+	Resources float64 `json:"resources"`
+
+	// CardsReady reports whether the cards can be played right now.
+	// Otherwise the card actions are rejected.
+	CardsReady bool      `json:"cards_ready"`
+	Cards      []BotCard `json:"cards"`
+
+	Colonies []BotColony `json:"colonies"`
+
+	// Creeps are the creeps inside the vision radius of any colony.
+	Creeps []BotCreep `json:"creeps"`
+
+	Stats BotStats `json:"stats"`
+
+	// Results are only set in the final observation.
+	Results *GameResults `json:"results,omitempty"`
+}
+
+type BotCard struct {
+	Index int `json:"index"`
+
+	// Special is a special action name, like "BuildColony" or "BuyCrawlers".
+	// It's empty for the colony priority cards.
+	Special string `json:"special,omitempty"`
+
+	// Effects map the colony priority names to the priority changes.
+	Effects map[string]float64 `json:"effects,omitempty"`
+
+	// Direction is an attack direction for the creeps player cards.
+	Direction int `json:"direction,omitempty"`
+
+	Cost float64 `json:"cost,omitempty"`
+}
+
+type BotColony struct {
+	Index    int        `json:"index"`
+	Pos      [2]float64 `json:"pos"`
+	Selected bool       `json:"selected,omitempty"`
+
+	// Mode is one of "normal", "takeoff", "relocating", "landing" or "teleporting".
+	// Only the colonies in normal mode can execute the actions.
+	Mode string `json:"mode"`
+
+	Health       float64 `json:"health"`
+	MaxHealth    float64 `json:"max_health"`
+	Resources    float64 `json:"resources"`
+	PatrolRadius float64 `json:"patrol_radius"`
+	Drones       int     `json:"drones"`
+
+	Priorities map[string]float64 `json:"priorities"`
+}
+
+type BotCreep struct {
+	Kind   string     `json:"kind"`
+	Pos    [2]float64 `json:"pos"`
+	Health float64    `json:"health"`
+	Flying bool       `json:"flying,omitempty"`
+}
+
+// BotStats are the cumulative player stats since the game start.
+type BotStats struct {
+	CreepsKilled      int     `json:"creeps_killed"`
+	DamageDealt       float64 `json:"damage_dealt"`
+	DamageTaken       float64 `json:"damage_taken"`
+	ResourcesGathered float64 `json:"resources_gathered"`
+}
+
+type BotActionKind string
+
+const (
+	BotActionWait BotActionKind = "wait"
+	BotActionCard BotActionKind = "card"
+	BotActionMove BotActionKind = "move"
+)
+
+type BotAction struct {
+	// Kind is an action kind; an empty kind is treated as a wait.
+	Kind BotActionKind `json:"kind"`
+
+	// Colony is an index of the colony that should execute the action.
+	Colony int `json:"colony,omitempty"`
+
+	// Card is a card index for the BotActionCard:
+	// 0-3 are the ordinary cards and 4 is the special card.
+	Card int `json:"card,omitempty"`
+
+	// Pos is a destination for the BotActionMove.
+	Pos [2]float64 `json:"pos"`
+}
@@ -0,0 +1,36 @@
+package serverapi
+
+import (
+	"crypto/sha1"
+	"strconv"
+)
+
+// ReplayChecksum returns a replay identity hash.
+// Two replays with the same checksum are considered to be the same match.
+//
+// The result is a raw (binary) sha1 sum.
+func ReplayChecksum(replay *GameReplay) string {
+	buf := make([]byte, 0, 256)
+
+	buf = strconv.AppendInt(buf, replay.Config.Seed, 10)
+	buf = append(buf, '/')
+	buf = append(buf, replay.Config.RawGameMode...)
+	buf = append(buf, '/')
+	for _, drone := range replay.Config.Tier2Recipes {
+		buf = append(buf, drone...)
+		buf = append(buf, '$')
+	}
+	buf = append(buf, replay.Config.TurretDesign...)
+	buf = append(buf, '$')
+	buf = strconv.AppendInt(buf, int64(len(replay.Actions)), 10)
+	buf = append(buf, '@')
+	buf = strconv.AppendInt(buf, int64(replay.Results.Score), 10)
+	buf = append(buf, ':')
+	buf = strconv.AppendInt(buf, int64(replay.Results.Ticks), 10)
+	buf = append(buf, ':')
+	buf = strconv.AppendInt(buf, int64(replay.Results.Time), 10)
+	buf = append(buf, ';')
+
+	checksum := sha1.Sum(buf)
+	return string(checksum[:])
+}
@@ -0,0 +1,64 @@
+package serverapi
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+)
+
+// MaxHashcashDifficulty is the highest difficulty the clients agree to solve.
+// Every extra bit doubles the expected solving time.
+const MaxHashcashDifficulty = 26
+
+// ScoreChallengeResp is a proof-of-work challenge for the score submission.
+//
+// The client needs to find a counter value that makes the
+// HashcashSum result to have at least Difficulty leading zero bits.
+// The nonce is bound to the replay checksum, so the solution
+// can't be reused for other replays.
+//
+// BinaryReplays tells the client that the server accepts
+// the binary-encoded replays (see ReplayBinaryContentType).
+type ScoreChallengeResp struct {
+	Nonce      string `json:"nonce"`
+	Difficulty int    `json:"difficulty"`
+
+	BinaryReplays bool `json:"binary_replays,omitempty"`
+}
+
+// HashcashSum computes a stamp hash for the given replay checksum (see ReplayChecksum),
+// the server-issued nonce and the client-selected counter.
+func HashcashSum(checksum, nonce string, counter uint64) [sha256.Size]byte {
+	buf := make([]byte, 0, len(checksum)+len(nonce)+8)
+	buf = append(buf, checksum...)
+	buf = append(buf, nonce...)
+	buf = binary.LittleEndian.AppendUint64(buf, counter)
+	return sha256.Sum256(buf)
+}
+
+// CheckHashcash reports whether the counter is a valid solution for the challenge.
+func CheckHashcash(checksum, nonce string, counter uint64, difficulty int) bool {
+	sum := HashcashSum(checksum, nonce, counter)
+	return hashcashZeroBits(sum[:]) >= difficulty
+}
+
+// SolveHashcash finds the counter value that satisfies the challenge.
+// It's a brute force search, the difficulty should be sane.
+func SolveHashcash(checksum, nonce string, difficulty int) uint64 {
+	counter := uint64(0)
+	for !CheckHashcash(checksum, nonce, counter, difficulty) {
+		counter++
+	}
+	return counter
+}
+
+func hashcashZeroBits(sum []byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
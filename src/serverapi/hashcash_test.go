@@ -0,0 +1,54 @@
+package serverapi
+
+import "testing"
+
+func TestHashcash(t *testing.T) {
+	replay := &GameReplay{}
+	replay.Config.Seed = 1234
+	replay.Config.RawGameMode = "classic"
+	replay.Results.Score = 500
+	checksum := ReplayChecksum(replay)
+
+	tests := []struct {
+		nonce      string
+		difficulty int
+	}{
+		{"", 0},
+		{"x", 1},
+		{"16.1700000000.abc", 8},
+		{"16.1700000000.abd", 12},
+	}
+
+	for _, test := range tests {
+		counter := SolveHashcash(checksum, test.nonce, test.difficulty)
+		if !CheckHashcash(checksum, test.nonce, counter, test.difficulty) {
+			t.Fatalf("nonce=%q difficulty=%d: solution %d is rejected", test.nonce, test.difficulty, counter)
+		}
+		if test.difficulty == 0 {
+			continue
+		}
+		otherChecksum := checksum[:len(checksum)-1] + "?"
+		if CheckHashcash(otherChecksum, test.nonce, counter, 32) {
+			t.Fatalf("nonce=%q: solution %d is accepted for another checksum", test.nonce, counter)
+		}
+	}
+}
+
+func TestHashcashZeroBits(t *testing.T) {
+	tests := []struct {
+		sum  []byte
+		want int
+	}{
+		{[]byte{0xff}, 0},
+		{[]byte{0x01}, 7},
+		{[]byte{0x00, 0x80}, 8},
+		{[]byte{0x00, 0x00, 0x10}, 19},
+		{[]byte{0x00, 0x00}, 16},
+	}
+
+	for _, test := range tests {
+		if have := hashcashZeroBits(test.sum); have != test.want {
+			t.Fatalf("zeroBits(%v): have %d, want %d", test.sum, have, test.want)
+		}
+	}
+}
@@ -1,3 +1,6 @@
 package serverapi
 
 const MaxNameLength = 20
+
+// MaxRequestBodySize is the server request body size limit.
+const MaxRequestBodySize = 128 * 1024
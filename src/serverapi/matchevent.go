@@ -0,0 +1,74 @@
+package serverapi
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// MaxMatchEvents is the max number of events per replay.
+// The events after this limit are not recorded.
+const MaxMatchEvents = 4000
+
+type MatchEventKind string
+
+const (
+	// EventColonyBuilt: a new colony construction was finished.
+	EventColonyBuilt MatchEventKind = "colony_built"
+
+	// EventColonyDestroyed: a colony was destroyed.
+	EventColonyDestroyed MatchEventKind = "colony_destroyed"
+
+	// EventTier3Drone: a tier 3 drone was created by a merge.
+	// The Name is a drone kind.
+	EventTier3Drone MatchEventKind = "tier3_drone"
+
+	// EventCreepBaseDestroyed: a creep base was destroyed.
+	// The Name is a creep kind.
+	EventCreepBaseDestroyed MatchEventKind = "creep_base_destroyed"
+
+	// EventBossStage: the boss health dropped below the next quarter.
+	// The Value is a stage number: 1 for 75%, 2 for 50% and 3 for 25%.
+	// The boss defeat is reported as a stage 4.
+	EventBossStage MatchEventKind = "boss_stage"
+
+	// EventArenaWave: the arena wave has started.
+	// The Value is a wave number.
+	EventArenaWave MatchEventKind = "arena_wave"
+
+	// EventResourcesGathered: the resources gathered during the last minute.
+	// The Value is a rounded resources amount.
+	EventResourcesGathered MatchEventKind = "resources_gathered"
+
+	// EventAchievement: the achievement conditions were met.
+	// The Name is an achievement name.
+	// These events are added at the end of the game.
+	EventAchievement MatchEventKind = "achievement"
+)
+
+// MatchEvent is a notable game event.
+//
+// The events are not used to execute a replay,
+// they're only recorded for the post-match analysis.
+type MatchEvent struct {
+	Tick int            `json:"tick"`
+	Kind MatchEventKind `json:"kind"`
+
+	// Player is an event owner player ID; it's -1 for the
+	// events that are not bound to any player.
+	Player int `json:"player"`
+
+	Value int        `json:"value,omitempty"`
+	Name  string     `json:"name,omitempty"`
+	Pos   [2]float64 `json:"pos"`
+}
+
+// WriteMatchEventsJSONL writes the events as JSON lines: one event per line.
+func WriteMatchEventsJSONL(w io.Writer, events []MatchEvent) error {
+	enc := json.NewEncoder(w)
+	for i := range events {
+		if err := enc.Encode(&events[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package serverapi
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ReplayBinaryContentType is a content type of the binary-encoded replays.
+//
+// The client sends it as a Content-Type header when uploading the replay
+// and as an Accept header when it can decode the binary replay downloads.
+// JSON is used when any side doesn't know about this encoding.
+const ReplayBinaryContentType = "application/x-roboden-replay"
+
+// ReplayBinaryFileExt is a file extension for the binary-encoded replays.
+const ReplayBinaryFileExt = ".rdrp"
+
+// ReplayBinaryVersion is the current binary replay format version.
+// It should be incremented on every format change;
+// the decoder should keep the support for the older versions.
+const ReplayBinaryVersion = 1
+
+// replayPosScale is a quantization step for the action positions.
+// Most positions are multiples of this step (the cursor positions are
+// usually integers), so they can be encoded as small varints.
+// Other positions are encoded as is.
+const replayPosScale = 16
+
+const replayMagic = "RDRP"
+
+var (
+	ErrBadReplayEncoding       = errors.New("malformed binary replay")
+	ErrUnsupportedReplayFormat = errors.New("unsupported binary replay version")
+)
+
+// The binary replay format:
+//
+//	magic        "RDRP"
+//	version      uvarint
+//	meta         uvarint length + JSON replay without the fields below
+//	checkpoints  list of varint
+//	state hashes list of {tick delta varint, 5 x uint32}
+//	actions      list of players, every player is a list of actions
+//
+// Every list starts with a uvarint len+1 prefix; 0 stands for a nil list,
+// so the JSON->binary->JSON conversion is lossless.
+//
+// An action is encoded as:
+//
+//	tick delta   varint (relative to the previous action tick)
+//	header       uvarint kind<<1 | rawPos
+//	colony       varint
+//	pos          2 x varint (quantized, rawPos=0) or 2 x float64 (rawPos=1)
+
+// IsBinaryReplay reports whether data looks like a binary-encoded replay.
+func IsBinaryReplay(data []byte) bool {
+	return len(data) >= len(replayMagic) && string(data[:len(replayMagic)]) == replayMagic
+}
+
+// UnmarshalReplay decodes the replay in either JSON or binary format.
+func UnmarshalReplay(data []byte, replay *GameReplay) error {
+	if IsBinaryReplay(data) {
+		return UnmarshalReplayBinary(data, replay)
+	}
+	return json.Unmarshal(data, replay)
+}
+
+// MarshalReplayBinary encodes the replay using the current binary format version.
+func MarshalReplayBinary(replay *GameReplay) ([]byte, error) {
+	meta := *replay
+	meta.Actions = nil
+	meta.Debug.Checkpoints = nil
+	meta.Debug.StateHashes = nil
+	metaData, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	numActions := 0
+	for _, actions := range replay.Actions {
+		numActions += len(actions)
+	}
+	buf := make([]byte, 0, 32+len(metaData)+4*len(replay.Debug.Checkpoints)+24*len(replay.Debug.StateHashes)+8*numActions)
+
+	buf = append(buf, replayMagic...)
+	buf = binary.AppendUvarint(buf, ReplayBinaryVersion)
+	buf = binary.AppendUvarint(buf, uint64(len(metaData)))
+	buf = append(buf, metaData...)
+
+	buf = appendListLen(buf, len(replay.Debug.Checkpoints), replay.Debug.Checkpoints == nil)
+	for _, c := range replay.Debug.Checkpoints {
+		buf = binary.AppendVarint(buf, int64(c))
+	}
+
+	buf = appendListLen(buf, len(replay.Debug.StateHashes), replay.Debug.StateHashes == nil)
+	prevTick := 0
+	for _, h := range replay.Debug.StateHashes {
+		buf = binary.AppendVarint(buf, int64(h.Tick-prevTick))
+		prevTick = h.Tick
+		buf = binary.LittleEndian.AppendUint32(buf, h.Colonies)
+		buf = binary.LittleEndian.AppendUint32(buf, h.Agents)
+		buf = binary.LittleEndian.AppendUint32(buf, h.Creeps)
+		buf = binary.LittleEndian.AppendUint32(buf, h.Resources)
+		buf = binary.LittleEndian.AppendUint32(buf, h.Projectiles)
+	}
+
+	buf = appendListLen(buf, len(replay.Actions), replay.Actions == nil)
+	for _, actions := range replay.Actions {
+		buf = appendListLen(buf, len(actions), actions == nil)
+		prevTick := 0
+		for _, a := range actions {
+			if a.Kind < 0 {
+				return nil, fmt.Errorf("can't encode action kind %d", a.Kind)
+			}
+			buf = binary.AppendVarint(buf, int64(a.Tick-prevTick))
+			prevTick = a.Tick
+			x, xok := quantizeReplayPos(a.Pos[0])
+			y, yok := quantizeReplayPos(a.Pos[1])
+			if xok && yok {
+				buf = binary.AppendUvarint(buf, uint64(a.Kind)<<1)
+				buf = binary.AppendVarint(buf, int64(a.SelectedColony))
+				buf = binary.AppendVarint(buf, x)
+				buf = binary.AppendVarint(buf, y)
+			} else {
+				buf = binary.AppendUvarint(buf, uint64(a.Kind)<<1|1)
+				buf = binary.AppendVarint(buf, int64(a.SelectedColony))
+				buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(a.Pos[0]))
+				buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(a.Pos[1]))
+			}
+		}
+	}
+
+	return buf, nil
+}
+
+// UnmarshalReplayBinary decodes the binary-encoded replay.
+// All known format versions are supported.
+func UnmarshalReplayBinary(data []byte, replay *GameReplay) error {
+	if !IsBinaryReplay(data) {
+		return ErrBadReplayEncoding
+	}
+	r := replayDecoder{data: data[len(replayMagic):]}
+
+	version := r.uvarint()
+	if r.err == nil && version != ReplayBinaryVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedReplayFormat, version)
+	}
+
+	metaData := r.bytes(r.uvarint())
+	if r.err != nil {
+		return r.err
+	}
+	var result GameReplay
+	if err := json.Unmarshal(metaData, &result); err != nil {
+		return fmt.Errorf("%w: meta: %v", ErrBadReplayEncoding, err)
+	}
+
+	if n, ok := r.listLen(1); ok {
+		result.Debug.Checkpoints = make([]int, n)
+		for i := range result.Debug.Checkpoints {
+			result.Debug.Checkpoints[i] = int(r.varint())
+		}
+	}
+
+	if n, ok := r.listLen(1 + 5*4); ok {
+		result.Debug.StateHashes = make([]StateHash, n)
+		prevTick := 0
+		for i := range result.Debug.StateHashes {
+			h := &result.Debug.StateHashes[i]
+			h.Tick = prevTick + int(r.varint())
+			prevTick = h.Tick
+			h.Colonies = r.uint32()
+			h.Agents = r.uint32()
+			h.Creeps = r.uint32()
+			h.Resources = r.uint32()
+			h.Projectiles = r.uint32()
+		}
+	}
+
+	if n, ok := r.listLen(1); ok {
+		result.Actions = make([][]PlayerAction, n)
+		for i := range result.Actions {
+			numActions, ok := r.listLen(4)
+			if !ok {
+				continue
+			}
+			actions := make([]PlayerAction, numActions)
+			prevTick := 0
+			for j := range actions {
+				a := &actions[j]
+				a.Tick = prevTick + int(r.varint())
+				prevTick = a.Tick
+				header := r.uvarint()
+				a.Kind = PlayerActionKind(header >> 1)
+				a.SelectedColony = int(r.varint())
+				if header&1 == 0 {
+					a.Pos[0] = float64(r.varint()) / replayPosScale
+					a.Pos[1] = float64(r.varint()) / replayPosScale
+				} else {
+					a.Pos[0] = math.Float64frombits(r.uint64())
+					a.Pos[1] = math.Float64frombits(r.uint64())
+				}
+			}
+			result.Actions[i] = actions
+		}
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.data) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrBadReplayEncoding, len(r.data))
+	}
+	*replay = result
+	return nil
+}
+
+func quantizeReplayPos(v float64) (int64, bool) {
+	q := v * replayPosScale
+	if q != math.Trunc(q) || math.Abs(q) > 1<<52 || math.Signbit(v) && v == 0 {
+		return 0, false
+	}
+	return int64(q), true
+}
+
+func appendListLen(buf []byte, n int, isNil bool) []byte {
+	if isNil {
+		return binary.AppendUvarint(buf, 0)
+	}
+	return binary.AppendUvarint(buf, uint64(n)+1)
+}
+
+// replayDecoder reads the binary replay data.
+// After the first error all reads return zero values,
+// so the error can be checked once at the end.
+type replayDecoder struct {
+	data []byte
+	err  error
+}
+
+func (r *replayDecoder) fail(what string) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: can't read %s", ErrBadReplayEncoding, what)
+	}
+	r.data = nil
+}
+
+// listLen reads the list length prefix.
+// It returns false for nil lists and after the errors.
+// minElemSize protects from the huge allocations with the malformed length values.
+func (r *replayDecoder) listLen(minElemSize int) (int, bool) {
+	n := r.uvarint()
+	if r.err != nil || n == 0 {
+		return 0, false
+	}
+	n--
+	if n > uint64(len(r.data)/minElemSize) {
+		r.fail("list")
+		return 0, false
+	}
+	return int(n), true
+}
+
+func (r *replayDecoder) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail("uvarint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *replayDecoder) varint() int64 {
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.fail("varint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *replayDecoder) uint32() uint32 {
+	if len(r.data) < 4 {
+		r.fail("uint32")
+		return 0
+	}
+	v := binary.LittleEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return v
+}
+
+func (r *replayDecoder) uint64() uint64 {
+	if len(r.data) < 8 {
+		r.fail("uint64")
+		return 0
+	}
+	v := binary.LittleEndian.Uint64(r.data)
+	r.data = r.data[8:]
+	return v
+}
+
+func (r *replayDecoder) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(len(r.data)) {
+		r.fail("bytes")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
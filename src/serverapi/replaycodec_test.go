@@ -0,0 +1,180 @@
+package serverapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func testReplay() *GameReplay {
+	replay := &GameReplay{
+		GameVersion:      16,
+		GameCommit:       "abc",
+		Platform:         "Steam",
+		LevelGenChecksum: 1942,
+		Results:          GameResults{Time: 500, Ticks: 30000, Score: 1000, Victory: true},
+	}
+	replay.Config.Seed = -4839284
+	replay.Config.RawGameMode = "inf_arena"
+	replay.Config.Tier2Recipes = []string{"a", "b"}
+	replay.Debug.Checkpoints = []int{10, -5, 0, 1 << 40}
+	replay.Debug.StateHashInterval = 60
+	replay.Debug.StateHashes = []StateHash{
+		{Tick: 60, Colonies: 1, Agents: math.MaxUint32},
+		{Tick: 120, Creeps: 3, Resources: 4, Projectiles: 5},
+	}
+	replay.Events = []MatchEvent{
+		{Tick: 3600, Kind: EventResourcesGathered, Player: 0, Value: 120},
+		{Tick: 4000, Kind: EventCreepBaseDestroyed, Player: -1, Name: "Base", Pos: [2]float64{10, 20}},
+	}
+	replay.Actions = [][]PlayerAction{
+		{
+			{Tick: 0, Pos: [2]float64{100, 200.5}, Kind: ActionCard1, SelectedColony: 0},
+			{Tick: 10, Pos: [2]float64{1.0 / 3, -7}, Kind: ActionMove, SelectedColony: -1},
+			{Tick: 10, Pos: [2]float64{math.Copysign(0, -1), 0}, Kind: ActionCard5, SelectedColony: 2},
+			{Tick: 5, Pos: [2]float64{1e300, -0.0625}, Kind: ActionUnknown, SelectedColony: 100},
+		},
+		{},
+		nil,
+	}
+	return replay
+}
+
+func TestReplayBinaryRoundTrip(t *testing.T) {
+	replays := []*GameReplay{
+		{},
+		testReplay(),
+	}
+
+	for i, replay := range replays {
+		jsonData, err := json.Marshal(replay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		binaryData, err := MarshalReplayBinary(replay)
+		if err != nil {
+			t.Fatalf("replay%d: encode: %v", i, err)
+		}
+		if !IsBinaryReplay(binaryData) || IsBinaryReplay(jsonData) {
+			t.Fatalf("replay%d: format detection failed", i)
+		}
+		var decoded GameReplay
+		if err := UnmarshalReplay(binaryData, &decoded); err != nil {
+			t.Fatalf("replay%d: decode: %v", i, err)
+		}
+		decodedJSON, err := json.Marshal(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(jsonData, decodedJSON) {
+			t.Fatalf("replay%d: round trip mismatch:\nhave: %s\nwant: %s", i, decodedJSON, jsonData)
+		}
+	}
+}
+
+func TestReplayBinarySize(t *testing.T) {
+	replay := testReplay()
+	actions := make([]PlayerAction, 5000)
+	for i := range actions {
+		actions[i] = PlayerAction{
+			Tick:           i * 37,
+			Pos:            [2]float64{float64(100 + i%1500), float64(200 + i%700)},
+			Kind:           PlayerActionKind(1 + i%6),
+			SelectedColony: i % 4,
+		}
+	}
+	replay.Actions = [][]PlayerAction{actions}
+
+	jsonData, err := json.Marshal(replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryData, err := MarshalReplayBinary(replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(binaryData)*5 > len(jsonData) {
+		t.Fatalf("binary replay is too big: %d bytes vs %d JSON bytes", len(binaryData), len(jsonData))
+	}
+}
+
+func TestReplaySubmissionSize(t *testing.T) {
+	// A maximum-length run: every list has as many elements as the server accepts.
+	replay := testReplay()
+	actions := make([]PlayerAction, 6000)
+	for i := range actions {
+		actions[i] = PlayerAction{
+			Tick:           i * 97,
+			Pos:            [2]float64{float64(i%3000) + 0.5, float64(i%2000) + 0.25},
+			Kind:           PlayerActionKind(1 + i%6),
+			SelectedColony: i % 4,
+		}
+	}
+	replay.Actions = [][]PlayerAction{actions}
+	replay.Debug.Checkpoints = make([]int, 48)
+	for i := range replay.Debug.Checkpoints {
+		replay.Debug.Checkpoints[i] = i * 7919
+	}
+	replay.Debug.StateHashes = make([]StateHash, MaxStateHashes)
+	for i := range replay.Debug.StateHashes {
+		replay.Debug.StateHashes[i] = StateHash{Tick: i * 60, Colonies: math.MaxUint32, Agents: math.MaxUint32}
+	}
+	replay.Events = make([]MatchEvent, MaxMatchEvents)
+	for i := range replay.Events {
+		replay.Events[i] = MatchEvent{
+			Tick:   i * 60,
+			Kind:   EventCreepBaseDestroyed,
+			Player: -1,
+			Name:   "CreepCrawlerBase",
+			Pos:    [2]float64{float64(i) + 0.5, float64(i) + 0.25},
+		}
+	}
+
+	submission := SubmissionReplay(replay)
+	if len(submission.Events) != 0 {
+		t.Fatal("the submission replay has match events")
+	}
+	if len(replay.Events) != MaxMatchEvents {
+		t.Fatal("the original replay events were modified")
+	}
+	data, err := MarshalReplayBinary(&submission)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > MaxRequestBodySize {
+		t.Fatalf("the submission is too big: %d bytes, the limit is %d", len(data), MaxRequestBodySize)
+	}
+}
+
+func TestReplayBinaryMalformed(t *testing.T) {
+	data, err := MarshalReplayBinary(testReplay())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every truncated prefix should be rejected without panics.
+	for n := 0; n < len(data); n++ {
+		var replay GameReplay
+		if err := UnmarshalReplayBinary(data[:n], &replay); err == nil {
+			t.Fatalf("truncated to %d bytes: no error", n)
+		}
+	}
+
+	var replay GameReplay
+	if err := UnmarshalReplayBinary(append(data[:len(data):len(data)], 0), &replay); !errors.Is(err, ErrBadReplayEncoding) {
+		t.Fatalf("trailing bytes: unexpected error: %v", err)
+	}
+
+	badVersion := append([]byte(replayMagic), 99)
+	if err := UnmarshalReplayBinary(badVersion, &replay); !errors.Is(err, ErrUnsupportedReplayFormat) {
+		t.Fatalf("bad version: unexpected error: %v", err)
+	}
+
+	// A huge list length should not cause a huge allocation.
+	hugeList := append([]byte(replayMagic), 1, 2, '{', '}', 0xff, 0xff, 0xff, 0xff, 0x0f)
+	if err := UnmarshalReplayBinary(hugeList, &replay); !errors.Is(err, ErrBadReplayEncoding) {
+		t.Fatalf("huge list: unexpected error: %v", err)
+	}
+}
@@ -8,6 +8,7 @@ type LeaderboardEntry struct {
 	PlayerName string `json:"player_name"`
 	Platform   string `json:"platform"`
 	Drones     string `json:"drones"`
+	HasReplay  bool   `json:"has_replay"`
 }
 
 type GameReplay struct {
@@ -27,6 +28,20 @@ type GameReplay struct {
 	Debug ReplayDebugInfo `json:"debug"`
 
 	Actions [][]PlayerAction `json:"actions"`
+
+	// Events are recorded for the post-match analysis.
+	// They're not used during the replay execution.
+	Events []MatchEvent `json:"events,omitempty"`
+}
+
+// SubmissionReplay returns a copy of the replay that is sent to the server.
+//
+// The match events are only kept in the local replays: the server doesn't need them
+// and they could make the request exceed the MaxRequestBodySize limit.
+func SubmissionReplay(replay *GameReplay) GameReplay {
+	result := *replay
+	result.Events = nil
+	return result
 }
 
 type ReplayDebugInfo struct {
@@ -39,6 +54,15 @@ type ReplayDebugInfo struct {
 	GOOS   string `json:"goos"`
 
 	Checkpoints []int `json:"checkpoints"`
+
+	// StateHashInterval is a number of ticks between the state hashes.
+	// Zero means that there are no state hashes.
+	StateHashInterval int         `json:"state_hash_interval,omitempty"`
+	StateHashes       []StateHash `json:"state_hashes,omitempty"`
+
+	// Resumed is set for the games that were saved and then continued.
+	// Such games are not accepted by the leaderboard.
+	Resumed bool `json:"resumed,omitempty"`
 }
 
 type GameResults struct {
@@ -85,6 +109,9 @@ type ReplayLevelConfig struct {
 	PlayersMode   int `json:"players_mode"`
 	InterfaceMode int `json:"ui_mode"`
 
+	// BotProfile is a computer players profile index (see gamedata.BotProfiles).
+	BotProfile int `json:"bot_profile"`
+
 	Relicts           bool `json:"relicts"`
 	FogOfWar          bool `json:"fog_of_war"`
 	SuperCreeps       bool `json:"super_creps"`
@@ -131,8 +158,37 @@ type LeaderboardResp struct {
 	NumSeasons int                `json:"num_seasons"`
 	NumPlayers int                `json:"num_players"`
 	Entries    []LeaderboardEntry `json:"entries"`
+
+	// ETag is not sent by the server in the body;
+	// the client stores the response header value here.
+	ETag string `json:"etag,omitempty"`
 }
 
+type PlayerProfileResp struct {
+	PlayerName string               `json:"player_name"`
+	NumSeasons int                  `json:"num_seasons"`
+	Entries    []PlayerProfileEntry `json:"entries"`
+}
+
+// PlayerProfileEntry is the player's best result in the given season and mode.
+type PlayerProfileEntry struct {
+	Season int    `json:"season"`
+	Mode   string `json:"mode"`
+	LeaderboardEntry
+}
+
+// BoardPageResp is a get-board response when pagination or filters are used.
+// Without these params, get-board returns the entire board as a plain entries array.
+type BoardPageResp struct {
+	// NumEntries is the total number of entries that matched the filters.
+	NumEntries int                `json:"num_entries"`
+	Offset     int                `json:"offset"`
+	Entries    []LeaderboardEntry `json:"entries"`
+}
+
+// MaxBoardPageSize is the get-board limit param upper bound.
+const MaxBoardPageSize = 100
+
 type SavePlayerScoreResp struct {
 	Queued           bool `json:"queued"`
 	CurrentHighscore int  `json:"current_highscore"`
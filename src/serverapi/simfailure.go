@@ -0,0 +1,46 @@
+package serverapi
+
+// Simulation failure kinds.
+// These values are stored in the server database, never change them.
+const (
+	FailureIllegalAction    = "illegal_action"
+	FailureBadCheckpoint    = "bad_checkpoint"
+	FailureLevelGenMismatch = "levelgen_mismatch"
+	FailureTimeout          = "timeout"
+	FailureBadReplay        = "bad_replay"
+	FailureCrash            = "crash"
+)
+
+// SimulationFailure is a machine-readable replay verification error.
+// runsim prints it to the stdout instead of the GameResults
+// when the replay can't be executed.
+type SimulationFailure struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+
+	// Tick is a simulation tick where the failure happened.
+	Tick int `json:"tick"`
+
+	// Action is only set for the FailureIllegalAction.
+	Action *FailedAction `json:"action,omitempty"`
+
+	// Checkpoint is only set for the FailureBadCheckpoint.
+	Checkpoint *FailedCheckpoint `json:"checkpoint,omitempty"`
+}
+
+type FailedAction struct {
+	PlayerID int `json:"player_id"`
+
+	// Index is the action index inside the player actions list.
+	Index int `json:"index"`
+
+	Action PlayerAction `json:"action"`
+}
+
+type FailedCheckpoint struct {
+	// Index is the checkpoint index inside the ReplayDebugInfo.Checkpoints.
+	Index int `json:"index"`
+
+	Expected int `json:"expected"`
+	Actual   int `json:"actual"`
+}
@@ -0,0 +1,42 @@
+package serverapi
+
+// MaxStateHashes is the max number of state hashes per replay.
+// When a game runs longer than that, the interval is doubled
+// and every other hash is discarded.
+const MaxStateHashes = 256
+
+// StateHash is a deterministic simulation state digest at the given tick.
+// Every game subsystem is hashed separately, so it's possible to tell
+// where the simulation diverged.
+type StateHash struct {
+	Tick int `json:"tick"`
+
+	Colonies    uint32 `json:"colonies"`
+	Agents      uint32 `json:"agents"`
+	Creeps      uint32 `json:"creeps"`
+	Resources   uint32 `json:"resources"`
+	Projectiles uint32 `json:"projectiles"`
+}
+
+// Diff returns the first subsystem name that has a different hash.
+// An empty string is returned for the equal hashes.
+// It also returns the mismatching hash values.
+func (h *StateHash) Diff(other *StateHash) (subsystem string, x, y uint32) {
+	pairs := [...]struct {
+		name string
+		x    uint32
+		y    uint32
+	}{
+		{"colonies", h.Colonies, other.Colonies},
+		{"agents", h.Agents, other.Agents},
+		{"creeps", h.Creeps, other.Creeps},
+		{"resources", h.Resources, other.Resources},
+		{"projectiles", h.Projectiles, other.Projectiles},
+	}
+	for _, p := range pairs {
+		if p.x != p.y {
+			return p.name, p.x, p.y
+		}
+	}
+	return "", 0, 0
+}
@@ -0,0 +1,79 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/quasilyte/roboden-game/gamedata"
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+const matchSaveKey = "match_save.json"
+
+// MatchSaveVersion is incremented every time the MatchSave format changes.
+// The saves with a different version can't be loaded.
+const MatchSaveVersion = 2
+
+// MatchSave is a mid-match save.
+//
+// The World is a serialized world snapshot created by the staging controller:
+// colonies, drones with their modes, creeps, projectiles, resources,
+// constructions, the game mode managers and the RNG state.
+// The static level parts (walls, forests, teleporters) are not saved,
+// they're generated from the Config when the game is resumed.
+//
+// The resumed world is not bit-identical to the saved one:
+// the objects update order and the purely visual state
+// (effects, fog of war history, weather) are not preserved.
+// This is why the resumed games are not eligible for the leaderboard.
+type MatchSave struct {
+	Version     int
+	GameVersion int
+
+	Date time.Time
+
+	Config serverapi.ReplayLevelConfig
+	World  json.RawMessage
+
+	// These fields are only used to describe the save in the menus.
+	Time     int
+	Colonies int
+	Drones   int
+}
+
+func (state *State) HasMatchSave() bool {
+	_, err := state.LoadMatchSave()
+	return err == nil
+}
+
+// LoadMatchSave returns the current mid-match save.
+// The saves made by the other game versions are rejected,
+// as the snapshot refers to the game data by the indices.
+func (state *State) LoadMatchSave() (MatchSave, error) {
+	var s MatchSave
+	if !state.CheckGameItem(matchSaveKey) {
+		return s, errors.New("there is no saved game")
+	}
+	if err := state.LoadGameItem(matchSaveKey, &s); err != nil {
+		return s, err
+	}
+	if s.Version != MatchSaveVersion || s.GameVersion != gamedata.BuildNumber {
+		return s, errors.New("the saved game is incompatible with this game version")
+	}
+	return s, nil
+}
+
+// SaveMatch replaces the current mid-match save.
+// There is only one save slot.
+func (state *State) SaveMatch(s MatchSave) {
+	s.Version = MatchSaveVersion
+	s.GameVersion = gamedata.BuildNumber
+	state.SaveGameItem(matchSaveKey, s)
+}
+
+func (state *State) DeleteMatchSave() {
+	if state.CheckGameItem(matchSaveKey) {
+		state.deleteGameItem(matchSaveKey)
+	}
+}
@@ -0,0 +1,279 @@
+package session
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/quasilyte/roboden-game/serverapi"
+)
+
+const replayIndexKey = "replay_index.json"
+
+// wasmReplayLibraryLimit is the max number of non-favourite replays
+// for the web builds: the localStorage quota is quite small there.
+// The other platforms have no library size limit.
+const wasmReplayLibraryLimit = 30
+
+// ReplayIndex is a saved replays library metadata.
+//
+// The replays themselves are stored as separate items (see LibraryReplayKey),
+// so the replay menu can list and sort them without loading every replay.
+type ReplayIndex struct {
+	NextID  int
+	Entries []ReplayIndexEntry
+}
+
+type ReplayIndexEntry struct {
+	ID int
+
+	// Checksum is a hex-encoded serverapi.ReplayChecksum;
+	// it's used to avoid the duplicated replays.
+	Checksum string
+
+	Date      time.Time
+	ResultTag string
+
+	GameVersion int
+	Mode        string
+	PlayersMode int
+	Seed        int64
+	Score       int
+	Victory     bool
+	Time        int
+	Drones      []string
+	Environment int
+
+	// Favourite replays are never evicted from the library.
+	Favourite bool
+}
+
+func (state *State) LibraryReplayKey(id int) string {
+	return fmt.Sprintf("library_replay_%d.json", id)
+}
+
+// LoadReplayIndex returns the replay library index.
+// The replays from the old fixed save slots are moved to the library
+// when the index is loaded for the first time.
+func (state *State) LoadReplayIndex() *ReplayIndex {
+	if state.replayIndex != nil {
+		return state.replayIndex
+	}
+
+	index := &ReplayIndex{NextID: 1}
+	state.replayIndex = index
+	if state.CheckGameItem(replayIndexKey) {
+		if err := state.LoadGameItem(replayIndexKey, index); err != nil {
+			state.Logf("load replay index: %v", err)
+		}
+		return index
+	}
+
+	// There used to be 9 replay slots: saved_replay_1.json...saved_replay_9.json.
+	const numLegacySlots = 9
+	for i := 1; i <= numLegacySlots; i++ {
+		k := state.ReplayDataKey(i)
+		if !state.CheckGameItem(k) {
+			continue
+		}
+		var r SavedReplay
+		if err := state.LoadGameItem(k, &r); err != nil {
+			state.Logf("migrate replay slot %d: %v", i, err)
+			continue
+		}
+		state.AddLibraryReplay(r)
+		state.deleteGameItem(k)
+	}
+	state.SaveGameItem(replayIndexKey, index)
+	return index
+}
+
+// AddLibraryReplay saves the replay to the library.
+// It returns false if this replay is already there.
+func (state *State) AddLibraryReplay(r SavedReplay) (ReplayIndexEntry, bool) {
+	index := state.LoadReplayIndex()
+
+	checksum := hex.EncodeToString([]byte(serverapi.ReplayChecksum(&r.Replay)))
+	for _, e := range index.Entries {
+		if e.Checksum == checksum {
+			return e, false
+		}
+	}
+
+	e := ReplayIndexEntry{
+		ID:          index.NextID,
+		Checksum:    checksum,
+		Date:        r.Date,
+		ResultTag:   r.ResultTag,
+		GameVersion: r.Replay.GameVersion,
+		Mode:        r.Replay.Config.RawGameMode,
+		PlayersMode: r.Replay.Config.PlayersMode,
+		Seed:        r.Replay.Config.Seed,
+		Score:       r.Replay.Results.Score,
+		Victory:     r.Replay.Results.Victory,
+		Time:        r.Replay.Results.Time,
+		Drones:      r.Replay.Config.Tier2Recipes,
+		Environment: r.Replay.Config.Environment,
+	}
+	index.NextID++
+	index.Entries = append(index.Entries, e)
+	state.SaveGameItem(state.LibraryReplayKey(e.ID), r)
+	state.evictLibraryReplays()
+	state.SaveGameItem(replayIndexKey, index)
+	return e, true
+}
+
+func (state *State) LoadLibraryReplay(id int) (SavedReplay, error) {
+	var r SavedReplay
+	k := state.LibraryReplayKey(id)
+	if !state.CheckGameItem(k) {
+		return r, errors.New("replay data is missing")
+	}
+	err := state.LoadGameItem(k, &r)
+	return r, err
+}
+
+func (state *State) DeleteLibraryReplay(id int) {
+	index := state.LoadReplayIndex()
+	for i, e := range index.Entries {
+		if e.ID != id {
+			continue
+		}
+		index.Entries = append(index.Entries[:i], index.Entries[i+1:]...)
+		state.deleteGameItem(state.LibraryReplayKey(id))
+		state.SaveGameItem(replayIndexKey, index)
+		return
+	}
+}
+
+func (state *State) SetLibraryReplayFavourite(id int, favourite bool) {
+	index := state.LoadReplayIndex()
+	for i := range index.Entries {
+		if index.Entries[i].ID == id {
+			index.Entries[i].Favourite = favourite
+			state.SaveGameItem(replayIndexKey, index)
+			return
+		}
+	}
+}
+
+// ExportLibraryReplay writes the replay into the ReplayExchangeDir.
+// The binary replay encoding is used, so the files are rather small.
+// The match events are also written as a separate JSON lines file.
+// It returns the created replay file path.
+func (state *State) ExportLibraryReplay(id int) (string, error) {
+	if state.ReplayExchangeDir == "" {
+		return "", errors.New("replay export is not supported on this platform")
+	}
+	r, err := state.LoadLibraryReplay(id)
+	if err != nil {
+		return "", err
+	}
+	data, err := serverapi.MarshalReplayBinary(&r.Replay)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(state.ReplayExchangeDir, 0o755); err != nil {
+		return "", err
+	}
+	filename := fmt.Sprintf("%s_%d_%s%s",
+		r.Replay.Config.RawGameMode, r.Replay.Config.Seed, r.Date.Format("20060102_150405"), serverapi.ReplayBinaryFileExt)
+	filePath := filepath.Join(state.ReplayExchangeDir, filename)
+	if err := os.WriteFile(filePath, data, 0o644); err != nil {
+		return "", err
+	}
+	if len(r.Replay.Events) != 0 {
+		var events bytes.Buffer
+		if err := serverapi.WriteMatchEventsJSONL(&events, r.Replay.Events); err != nil {
+			return "", err
+		}
+		eventsPath := strings.TrimSuffix(filePath, serverapi.ReplayBinaryFileExt) + ".events.jsonl"
+		if err := os.WriteFile(eventsPath, events.Bytes(), 0o644); err != nil {
+			return "", err
+		}
+	}
+	return filePath, nil
+}
+
+// ImportReplays adds all replay files from the ReplayExchangeDir to the library.
+// Both JSON and binary replays are accepted; the files are not removed.
+// It returns the number of new library replays.
+func (state *State) ImportReplays() (int, error) {
+	if state.ReplayExchangeDir == "" {
+		return 0, errors.New("replay import is not supported on this platform")
+	}
+	files, err := os.ReadDir(state.ReplayExchangeDir)
+	if err != nil {
+		return 0, err
+	}
+	numImported := 0
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if !strings.HasSuffix(f.Name(), ".json") && !strings.HasSuffix(f.Name(), serverapi.ReplayBinaryFileExt) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(state.ReplayExchangeDir, f.Name()))
+		if err != nil {
+			return numImported, err
+		}
+		var r SavedReplay
+		if err := serverapi.UnmarshalReplay(data, &r.Replay); err != nil {
+			state.Logf("import %q replay: %v", f.Name(), err)
+			continue
+		}
+		r.Date = time.Now()
+		if info, err := f.Info(); err == nil {
+			r.Date = info.ModTime()
+		}
+		r.ResultTag = "menu.results.defeat"
+		if r.Replay.Results.Victory {
+			r.ResultTag = "menu.results.victory"
+		}
+		if _, added := state.AddLibraryReplay(r); added {
+			numImported++
+		}
+	}
+	return numImported, nil
+}
+
+func (state *State) evictLibraryReplays() {
+	if runtime.GOARCH != "wasm" {
+		return
+	}
+	index := state.replayIndex
+	for {
+		numEvictable := 0
+		oldest := -1
+		for i, e := range index.Entries {
+			if e.Favourite {
+				continue
+			}
+			numEvictable++
+			if oldest == -1 || e.Date.Before(index.Entries[oldest].Date) {
+				oldest = i
+			}
+		}
+		if numEvictable <= wasmReplayLibraryLimit {
+			return
+		}
+		state.deleteGameItem(state.LibraryReplayKey(index.Entries[oldest].ID))
+		index.Entries = append(index.Entries[:oldest], index.Entries[oldest+1:]...)
+	}
+}
+
+func (state *State) deleteGameItem(key string) {
+	if state.GameData == nil {
+		return
+	}
+	if err := state.GameData.DeleteItem(key); err != nil {
+		state.Logf("delete %q game item: %v", key, err)
+	}
+}
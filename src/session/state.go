@@ -67,11 +67,21 @@ type State struct {
 
 	GameData *gdata.Manager
 
+	// ReplayExchangeDir is where the replays are exported to and imported from.
+	// It's empty on platforms without a user-accessible filesystem.
+	ReplayExchangeDir string
+
+	replayIndex *ReplayIndex
+
 	SentHighscores bool
 
 	GameCommitHash string
 
 	StdoutLogs []string
+
+	// LogWriter receives the Logf output.
+	// The stdout is used if it's nil.
+	LogWriter io.Writer
 }
 
 func (state *State) CheckGameItem(key string) bool {
@@ -116,7 +126,11 @@ func (state *State) Logf(format string, args ...any) {
 		s = fmt.Sprintf(format, args...)
 	}
 
-	fmt.Println(s)
+	if state.LogWriter != nil {
+		fmt.Fprintln(state.LogWriter, s)
+	} else {
+		fmt.Println(s)
+	}
 
 	if len(state.StdoutLogs) >= 100 {
 		state.StdoutLogs = state.StdoutLogs[:0]
@@ -397,30 +411,6 @@ func (state *State) CacheGlyphs() {
 	text.CacheGlyphs(state.Resources.Font3, alphabet)
 }
 
-func (state *State) FindNextReplayIndex() int {
-	var minDate time.Time
-	minIndex := 0
-	for i := 1; i < 10; i++ {
-		k := state.ReplayDataKey(i)
-		if !state.CheckGameItem(k) {
-			return i
-		}
-		var r SavedReplay
-		err := state.LoadGameItem(k, &r)
-		if err != nil {
-			return i
-		}
-		if minIndex == 0 || r.Date.Before(minDate) {
-			minDate = r.Date
-			minIndex = i
-		}
-	}
-	if minIndex != 0 {
-		return minIndex
-	}
-	return 1
-}
-
 func (state *State) ReplayDataKey(i int) string {
 	return fmt.Sprintf("saved_replay_%d.json", i)
 }
@@ -0,0 +1,150 @@
+package sqliteutil
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrSchemaTooNew is returned by Migrate if the database was migrated
+// by a newer program version. Using it could corrupt the data.
+var ErrSchemaTooNew = errors.New("database schema is newer than the program supports")
+
+// Migration is a single database schema change.
+//
+// Migrations are never removed or reordered once they're released:
+// the database only stores the number of the applied migrations.
+type Migration struct {
+	Name string
+
+	// Apply is executed inside a transaction.
+	// It should handle the databases that were created
+	// before the migrations were introduced.
+	Apply func(tx *sql.Tx) error
+}
+
+// Exec creates a migration that executes the given query.
+func Exec(name, query string) Migration {
+	return Migration{
+		Name: name,
+		Apply: func(tx *sql.Tx) error {
+			_, err := tx.Exec(query)
+			return err
+		},
+	}
+}
+
+// Migrate applies the migrations that were not applied to this database yet.
+// The schema version is stored in the schema_version table;
+// version N means that the first N migrations were applied.
+//
+// Every migration runs in its own transaction along with the version update,
+// so a failed migration can be retried after the fix.
+//
+// It returns the number of applied migrations.
+func Migrate(conn *sql.DB, migrations []Migration) (int, error) {
+	_, err := conn.Exec("CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)")
+	if err != nil {
+		return 0, fmt.Errorf("create schema_version table: %w", err)
+	}
+
+	version, err := SchemaVersion(conn)
+	if err != nil {
+		return 0, err
+	}
+	if version > len(migrations) {
+		return 0, fmt.Errorf("%w (version %d, expected %d at most)", ErrSchemaTooNew, version, len(migrations))
+	}
+
+	numApplied := 0
+	for i := version; i < len(migrations); i++ {
+		m := migrations[i]
+		err := withTransaction(conn, func(tx *sql.Tx) error {
+			if err := m.Apply(tx); err != nil {
+				return err
+			}
+			return setSchemaVersion(tx, i+1)
+		})
+		if err != nil {
+			return numApplied, fmt.Errorf("migration %d (%s): %w", i+1, m.Name, err)
+		}
+		numApplied++
+	}
+
+	return numApplied, nil
+}
+
+// SchemaVersion returns the number of migrations applied to the database.
+func SchemaVersion(conn *sql.DB) (int, error) {
+	exists, err := TableExists(conn, "schema_version")
+	if err != nil || !exists {
+		return 0, err
+	}
+	var version int
+	err = conn.QueryRow("SELECT version FROM schema_version").Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
+
+func setSchemaVersion(tx *sql.Tx, version int) error {
+	if _, err := tx.Exec("DELETE FROM schema_version"); err != nil {
+		return err
+	}
+	_, err := tx.Exec("INSERT INTO schema_version (version) VALUES (?)", version)
+	return err
+}
+
+func withTransaction(conn *sql.DB, f func(tx *sql.Tx) error) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("rollback error (%v) after %w", rollbackErr, err)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// TableExists reports whether the database has a table with the given name.
+// The q argument is either *sql.DB or *sql.Tx.
+func TableExists(q queryer, name string) (bool, error) {
+	var count int
+	err := q.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	return count != 0, err
+}
+
+// TableColumns returns a set of the table column names.
+// The set is empty if there is no such table.
+// The q argument is either *sql.DB or *sql.Tx.
+func TableColumns(q queryer, table string) (map[string]bool, error) {
+	rows, err := q.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var cid int
+		var name string
+		var typeName string
+		var notNull bool
+		var defaultValue sql.NullString
+		var pk int
+		if err := rows.Scan(&cid, &name, &typeName, &notNull, &defaultValue, &pk); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
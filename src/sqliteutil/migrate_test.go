@@ -0,0 +1,118 @@
+package sqliteutil
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestMigrate(t *testing.T) {
+	conn, err := Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	migrations := []Migration{
+		Exec("create", "CREATE TABLE IF NOT EXISTS items (name TEXT NOT NULL)"),
+		Exec("insert", "INSERT INTO items (name) VALUES ('first')"),
+	}
+
+	runMigrate := func(migrations []Migration, wantApplied, wantVersion int) {
+		t.Helper()
+		numApplied, err := Migrate(conn, migrations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if numApplied != wantApplied {
+			t.Fatalf("applied %d migrations, want %d", numApplied, wantApplied)
+		}
+		version, err := SchemaVersion(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != wantVersion {
+			t.Fatalf("schema version is %d, want %d", version, wantVersion)
+		}
+	}
+
+	runMigrate(migrations, 2, 2)
+	// Nothing to do for the second run.
+	runMigrate(migrations, 0, 2)
+
+	migrations = append(migrations, Migration{
+		Name: "column",
+		Apply: func(tx *sql.Tx) error {
+			_, err := tx.Exec("ALTER TABLE items ADD COLUMN n INTEGER NOT NULL DEFAULT 0")
+			return err
+		},
+	})
+	runMigrate(migrations, 1, 3)
+
+	columns, err := TableColumns(conn, "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !columns["name"] || !columns["n"] {
+		t.Fatalf("unexpected items columns: %v", columns)
+	}
+	var numItems int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM items").Scan(&numItems); err != nil {
+		t.Fatal(err)
+	}
+	if numItems != 1 {
+		t.Fatalf("found %d items, want 1", numItems)
+	}
+
+	// An older binary must not touch this database.
+	if _, err := Migrate(conn, migrations[:2]); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestMigrateFailure(t *testing.T) {
+	conn, err := Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	migrations := []Migration{
+		Exec("create", "CREATE TABLE items (name TEXT NOT NULL)"),
+		{
+			Name: "broken",
+			Apply: func(tx *sql.Tx) error {
+				if _, err := tx.Exec("INSERT INTO items (name) VALUES ('x')"); err != nil {
+					return err
+				}
+				return errors.New("oops")
+			},
+		},
+	}
+
+	numApplied, err := Migrate(conn, migrations)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if numApplied != 1 {
+		t.Fatalf("applied %d migrations, want 1", numApplied)
+	}
+	version, err := SchemaVersion(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 {
+		t.Fatalf("schema version is %d, want 1", version)
+	}
+	// The failed migration changes should be rolled back.
+	var numItems int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM items").Scan(&numItems); err != nil {
+		t.Fatal(err)
+	}
+	if numItems != 0 {
+		t.Fatalf("found %d items, want 0", numItems)
+	}
+}